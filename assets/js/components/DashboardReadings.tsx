@@ -1,6 +1,6 @@
 import React, {useState} from 'react';
 import {useMutation, useQueryClient} from '@tanstack/react-query';
-import {Check, AlertTriangle, Calendar, BookOpen} from 'lucide-react';
+import {Check, AlertTriangle, Calendar, BookOpen, SkipForward} from 'lucide-react';
 import {getCsrfToken} from '../hooks/useCsrf';
 import {toast} from './Toaster';
 
@@ -8,7 +8,7 @@ interface Reading {
   id: number;
   date: string;
   content: string;
-  status: 'pending' | 'completed';
+  status: 'pending' | 'completed' | 'skipped';
   isOverdue: boolean;
 }
 
@@ -27,28 +27,45 @@ interface DashboardReadingsProps {
   planGroups: PlanGroup[];
 }
 
+type ReadingAction =
+  | {readingId: number; kind: 'complete'}
+  | {readingId: number; kind: 'skip'; reason: string};
+
 export const DashboardReadings: React.FC<DashboardReadingsProps> = ({
   planGroups: initialPlanGroups,
 }) => {
   const [planGroups, setPlanGroups] = useState(initialPlanGroups);
+  const [skipping, setSkipping] = useState<number | null>(null);
+  const [skipReason, setSkipReason] = useState('');
   const queryClient = useQueryClient();
 
   const mutation = useMutation({
-    mutationFn: async (readingId: number) => {
-      const response = await fetch(`/reading/${readingId}/complete`, {
-        method: 'POST',
-        headers: {
-          'X-CSRF-Token': getCsrfToken(),
+    mutationFn: async (action: ReadingAction) => {
+      const body = new URLSearchParams({return_to: '/dashboard'});
+      if (action.kind === 'skip') {
+        body.set('reason', action.reason);
+      }
+      const response = await fetch(
+        `/reading/${action.readingId}/${action.kind}`,
+        {
+          method: 'POST',
+          headers: {
+            'X-CSRF-Token': getCsrfToken(),
+          },
+          body,
         },
-      });
+      );
 
       if (!response.ok) {
-        throw new Error('Failed to complete reading');
+        throw new Error(
+          (await response.text()) || `Failed to ${action.kind} reading`,
+        );
       }
 
-      return readingId;
+      return action;
     },
-    onSuccess: (readingId: number) => {
+    onSuccess: (action: ReadingAction) => {
+      const {readingId} = action;
       // Remove the reading from its plan group
       setPlanGroups(prev =>
         prev
@@ -61,15 +78,19 @@ export const DashboardReadings: React.FC<DashboardReadingsProps> = ({
           }))
           .filter(group => group.readings.length > 0),
       );
-      toast.success('Reading completed!');
+      setSkipping(null);
+      setSkipReason('');
+      toast.success(
+        action.kind === 'skip' ? 'Reading skipped' : 'Reading completed!',
+      );
 
       // Trigger HTMX to refresh stats
       document.body.dispatchEvent(new Event('stats-updated'));
 
       void queryClient.invalidateQueries({queryKey: ['notifications']});
     },
-    onError: () => {
-      toast.error('Failed to complete reading');
+    onError: (error: Error) => {
+      toast.error(error.message);
     },
   });
 
@@ -127,7 +148,8 @@ export const DashboardReadings: React.FC<DashboardReadingsProps> = ({
             <div className="divide-y divide-base-300">
               {group.readings.map(reading => {
                 const isLoading =
-                  mutation.isPending && mutation.variables === reading.id;
+                  mutation.isPending &&
+                  mutation.variables?.readingId === reading.id;
 
                 return (
                   <div key={reading.id} className="p-6">
@@ -147,23 +169,83 @@ export const DashboardReadings: React.FC<DashboardReadingsProps> = ({
                             <Calendar className="h-4 w-4" />
                             <span>{reading.date}</span>
                           </div>
-                          <button
-                            type="button"
-                            onClick={() => mutation.mutate(reading.id)}
-                            disabled={isLoading}
-                            className="btn btn-primary btn-sm gap-2"
-                            aria-label={`Mark ${group.plan.title} reading as complete`}
-                          >
-                            {isLoading ? (
-                              <span className="loading loading-spinner loading-xs" />
-                            ) : (
-                              <>
-                                <Check className="h-5 w-5" aria-hidden="true" />
-                                Complete
-                              </>
-                            )}
-                          </button>
+                          <div className="flex items-center gap-2">
+                            <button
+                              type="button"
+                              onClick={() =>
+                                setSkipping(
+                                  skipping === reading.id ? null : reading.id,
+                                )
+                              }
+                              disabled={isLoading}
+                              className="btn btn-ghost btn-sm gap-2"
+                              aria-expanded={skipping === reading.id}
+                              aria-label={`Skip ${group.plan.title} reading`}
+                            >
+                              <SkipForward
+                                className="h-4 w-4"
+                                aria-hidden="true"
+                              />
+                              Skip
+                            </button>
+                            <button
+                              type="button"
+                              onClick={() =>
+                                mutation.mutate({
+                                  readingId: reading.id,
+                                  kind: 'complete',
+                                })
+                              }
+                              disabled={isLoading}
+                              className="btn btn-primary btn-sm gap-2"
+                              aria-label={`Mark ${group.plan.title} reading as complete`}
+                            >
+                              {isLoading ? (
+                                <span className="loading loading-spinner loading-xs" />
+                              ) : (
+                                <>
+                                  <Check
+                                    className="h-5 w-5"
+                                    aria-hidden="true"
+                                  />
+                                  Complete
+                                </>
+                              )}
+                            </button>
+                          </div>
                         </div>
+                        {skipping === reading.id && (
+                          <form
+                            className="flex items-center gap-2 mt-4"
+                            onSubmit={event => {
+                              event.preventDefault();
+                              mutation.mutate({
+                                readingId: reading.id,
+                                kind: 'skip',
+                                reason: skipReason,
+                              });
+                            }}
+                          >
+                            <input
+                              type="text"
+                              value={skipReason}
+                              onChange={event =>
+                                setSkipReason(event.target.value)
+                              }
+                              maxLength={500}
+                              placeholder="Reason (optional)"
+                              aria-label="Skip reason"
+                              className="input input-bordered input-sm flex-1"
+                            />
+                            <button
+                              type="submit"
+                              disabled={isLoading}
+                              className="btn btn-outline btn-sm"
+                            >
+                              Skip reading
+                            </button>
+                          </form>
+                        )}
                       </div>
                     </div>
                   </div>
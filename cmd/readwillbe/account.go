@@ -6,13 +6,18 @@ import (
 	"net/mail"
 	"regexp"
 	"strings"
+	"time"
 
+	"github.com/labstack/echo-contrib/v5/session"
 	"github.com/labstack/echo/v5"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 
+	"readwillbe/internal/cache"
+	"readwillbe/internal/clock"
 	mw "readwillbe/internal/middleware"
 	"readwillbe/internal/model"
+	"readwillbe/internal/repository"
 	emailservice "readwillbe/internal/service/email"
 	"readwillbe/internal/views"
 )
@@ -24,18 +29,67 @@ func isValidEmail(email string) bool {
 	return err == nil
 }
 
-func accountHandler(cfg model.Config, _ *gorm.DB) echo.HandlerFunc {
+// isValidTimezone reports whether name is a loadable IANA timezone. "Local"
+// is rejected since it would silently track the server's zone.
+func isValidTimezone(name string) bool {
+	if name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+func accountHandler(cfg model.Config, db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
 	return func(c *echo.Context) error {
 		user, ok := mw.GetSessionUser(c)
 		if !ok {
 			return c.Redirect(http.StatusFound, "/auth/sign-in")
 		}
 
-		return render(c, 200, views.Account(cfg, &user))
+		props := views.AccountProps{
+			PasswordChanged:   c.QueryParam("password") == "changed",
+			VerificationSent:  c.QueryParam("verification") == "sent",
+			TwoFactorDisabled: c.QueryParam("two_factor") == "disabled",
+			SessionsRevoked:   c.QueryParam("sessions") == "revoked",
+		}
+		return renderAccount(c, cfg, db, clk, user, 200, props)
 	}
 }
 
-func updateSettings(db *gorm.DB) echo.HandlerFunc {
+// renderAccount renders the account page for user, filling in the parts of
+// props that come from the database.
+func renderAccount(c *echo.Context, cfg model.Config, db *gorm.DB, clk clock.Clock, user model.User, status int, props views.AccountProps) error {
+	tx := db.WithContext(c.Request().Context())
+	if user.TwoFactorEnabled() {
+		left, err := repository.CountRecoveryCodes(tx, user.ID)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to load account")
+		}
+		props.RecoveryCodesLeft = left
+	}
+	if cfg.PasskeysEnabled() {
+		passkeys, err := repository.ListPasskeys(tx, user.ID)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to load account")
+		}
+		props.Passkeys = passkeys
+	}
+	if !cfg.ProxyAuthEnabled() {
+		sessions, err := repository.ListSessions(tx, user.ID, clk.Now())
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to load account")
+		}
+		props.Sessions = sessions
+		if sess, err := session.Get(mw.SessionKey, c); err == nil && sess.ID != "" {
+			if current, err := repository.GetSession(tx, sess.ID, clk.Now()); err == nil {
+				props.CurrentSessionID = current.ID
+			}
+		}
+	}
+	return render(c, status, views.Account(cfg, &user, props))
+}
+
+func updateSettings(cfg model.Config, db *gorm.DB, clk clock.Clock, userCache *cache.UserCache, sender emailservice.Service) echo.HandlerFunc {
 	return func(c *echo.Context) error {
 		user, ok := mw.GetSessionUser(c)
 		if !ok {
@@ -49,8 +103,14 @@ func updateSettings(db *gorm.DB) echo.HandlerFunc {
 			return c.String(http.StatusBadRequest, fmt.Sprintf("Invalid time format: %s (expected HH:MM)", notificationTime))
 		}
 
+		timezone := strings.TrimSpace(c.FormValue("timezone"))
+		if timezone != "" && !isValidTimezone(timezone) {
+			return c.String(http.StatusBadRequest, fmt.Sprintf("Unknown timezone: %s", timezone))
+		}
+
 		user.NotificationsEnabled = notificationsEnabled
 		user.NotificationTime = notificationTime
+		user.Timezone = timezone
 
 		// Email notification settings
 		user.EmailNotificationsEnabled = c.FormValue("email_notifications_enabled") == "on"
@@ -58,11 +118,24 @@ func updateSettings(db *gorm.DB) echo.HandlerFunc {
 		if notificationEmail != "" && !isValidEmail(notificationEmail) {
 			return c.String(http.StatusBadRequest, "Invalid email address")
 		}
+		user.NotificationEmailVerified = notificationEmailVerified(user, notificationEmail)
+		needsVerification := notificationEmail != "" && !user.NotificationEmailVerified &&
+			!strings.EqualFold(notificationEmail, user.NotificationEmail)
 		user.NotificationEmail = notificationEmail
 
-		if err := db.WithContext(c.Request().Context()).Save(&user).Error; err != nil {
+		tx := db.WithContext(c.Request().Context())
+		if err := tx.Save(&user).Error; err != nil {
 			return c.String(http.StatusInternalServerError, "Failed to update settings")
 		}
+		userCache.Invalidate(user.ID)
+
+		if needsVerification && sender != nil {
+			if err := sendEmailVerification(cfg, tx, clk, sender, user, notificationEmail); err != nil {
+				logrus.Errorf("Failed to issue email verification for user %d: %v", user.ID, err)
+			} else {
+				return c.Redirect(http.StatusFound, "/account?verification=sent")
+			}
+		}
 
 		return c.Redirect(http.StatusFound, "/account")
 	}
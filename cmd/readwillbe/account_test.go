@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"readwillbe/internal/cache"
+	"readwillbe/internal/clock"
+	mw "readwillbe/internal/middleware"
+	"readwillbe/internal/model"
+
+	"github.com/labstack/echo/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateSettings_Timezone(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "tz@example.com", "password123")
+	userCache := cache.NewUserCache(time.Minute, time.Minute)
+
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/account/settings", strings.NewReader(form.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set(mw.UserKey, *user)
+
+		require.NoError(t, updateSettings(model.Config{}, db, clock.System, userCache, nil)(c))
+		return rec
+	}
+
+	t.Run("valid timezone is saved and cache invalidated", func(t *testing.T) {
+		userCache.Set(*user)
+
+		rec := post(url.Values{"timezone": {"America/Chicago"}, "notification_time": {"07:30"}})
+		assert.Equal(t, http.StatusFound, rec.Code)
+
+		var updated model.User
+		require.NoError(t, db.First(&updated, user.ID).Error)
+		assert.Equal(t, "America/Chicago", updated.Timezone)
+		assert.Equal(t, "America/Chicago", updated.Location().String())
+
+		_, found := userCache.Get(user.ID)
+		assert.False(t, found, "settings change should evict the cached user")
+	})
+
+	t.Run("unknown timezone is rejected", func(t *testing.T) {
+		rec := post(url.Values{"timezone": {"Mars/Olympus_Mons"}})
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("blank timezone falls back to server default", func(t *testing.T) {
+		rec := post(url.Values{"timezone": {""}})
+		assert.Equal(t, http.StatusFound, rec.Code)
+
+		var updated model.User
+		require.NoError(t, db.First(&updated, user.ID).Error)
+		assert.Empty(t, updated.Timezone)
+		assert.Equal(t, time.Local, updated.Location())
+	})
+}
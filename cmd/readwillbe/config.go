@@ -24,14 +24,20 @@ var configCmd = &cobra.Command{
 		fmt.Println()
 		fmt.Printf("  port:         %s\n", viper.GetString("port"))
 		fmt.Printf("  log_level:    %s\n", viper.GetString("log_level"))
+		fmt.Printf("  db_driver:    %s\n", viper.GetString("db_driver"))
 		fmt.Printf("  db_path:      %s\n", viper.GetString("db_path"))
 		fmt.Printf("  allow_signup: %t\n", viper.GetBool("allow_signup"))
 		fmt.Printf("  seed_db:      %t\n", viper.GetBool("seed_db"))
+		fmt.Printf("  auto_migrate: %t\n", viper.GetBool("auto_migrate"))
 
 		if viper.IsSet("tz") {
 			fmt.Printf("  tz:           %s\n", viper.GetString("tz"))
 		}
 
+		if viper.GetString("db_dsn") != "" {
+			fmt.Printf("  db_dsn:       [REDACTED]\n")
+		}
+
 		if viper.IsSet("cookie_secret") {
 			fmt.Printf("  cookie_secret: [REDACTED]\n")
 		} else {
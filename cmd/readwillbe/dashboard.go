@@ -1,55 +1,71 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
 	"sort"
+	"time"
 
 	"github.com/labstack/echo/v5"
 	"gorm.io/gorm"
 
+	"readwillbe/internal/clock"
 	mw "readwillbe/internal/middleware"
 	"readwillbe/internal/model"
 	"readwillbe/internal/repository"
+	"readwillbe/internal/service/stats"
 	"readwillbe/internal/views"
 	"readwillbe/internal/views/partials"
 )
 
-func dashboardHandler(cfg model.Config, db *gorm.DB) echo.HandlerFunc {
+func dashboardHandler(cfg model.Config, db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
 	return func(c *echo.Context) error {
 		user, ok := mw.GetSessionUser(c)
 		if !ok {
 			return c.Redirect(http.StatusFound, "/auth/sign-in")
 		}
 
+		now := clock.NowIn(clk, user.Location())
+
 		// Fetch only relevant readings (active today or overdue, excluding future)
 		// using optimized SQL query
-		readings, err := repository.GetDashboardReadings(db.WithContext(c.Request().Context()), user.ID)
+		readings, err := repository.GetDashboardReadings(db.WithContext(c.Request().Context()), user.ID, now)
 		if err != nil {
 			return c.String(http.StatusInternalServerError, "Failed to load dashboard data")
 		}
 
 		// Filter to active/overdue readings and group by plan
-		planGroups := groupReadingsByPlan(readings)
+		planGroups := groupReadingsByPlan(readings, now)
 
-		weeklyCount, err := repository.GetWeeklyCompletedReadingsCount(db.WithContext(c.Request().Context()), user.ID)
+		counts, err := loadReadingStats(db.WithContext(c.Request().Context()), user.ID, now)
 		if err != nil {
-			return c.String(http.StatusInternalServerError, "Failed to load weekly stats")
+			return c.String(http.StatusInternalServerError, "Failed to load stats")
+		}
+
+		completed, err := repository.GetCompletedTodayReadings(db.WithContext(c.Request().Context()), user.ID, now)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to load dashboard data")
 		}
 
-		monthlyCount, err := repository.GetMonthlyCompletedReadingsCount(db.WithContext(c.Request().Context()), user.ID)
+		report, err := stats.ForUser(db.WithContext(c.Request().Context()), user.ID, now)
 		if err != nil {
-			return c.String(http.StatusInternalServerError, "Failed to load monthly stats")
+			return c.String(http.StatusInternalServerError, "Failed to load stats")
 		}
 
-		return render(c, 200, views.Dashboard(cfg, &user, planGroups, weeklyCount, monthlyCount))
+		projections, err := stats.ProjectionsForUser(db.WithContext(c.Request().Context()), user.ID, now)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to load stats")
+		}
+
+		return render(c, 200, views.Dashboard(cfg, &user, now, planGroups, completed, counts, report, projections))
 	}
 }
 
-func groupReadingsByPlan(readings []model.Reading) []model.PlanGroup {
+func groupReadingsByPlan(readings []model.Reading, now time.Time) []model.PlanGroup {
 	// Filter to only active today or overdue readings
 	var activeReadings []model.Reading
 	for _, r := range readings {
-		if r.IsActiveToday() || r.IsOverdue() {
+		if r.IsActiveToday(now) || r.IsOverdue(now) {
 			activeReadings = append(activeReadings, r)
 		}
 	}
@@ -86,23 +102,144 @@ func groupReadingsByPlan(readings []model.Reading) []model.PlanGroup {
 }
 
 // dashboardStatsPartial returns just the stats section for HTMX updates
-func dashboardStatsPartial(db *gorm.DB) echo.HandlerFunc {
+func dashboardStatsPartial(db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.NoContent(http.StatusUnauthorized)
+		}
+
+		now := clock.NowIn(clk, user.Location())
+
+		counts, err := loadReadingStats(db.WithContext(c.Request().Context()), user.ID, now)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to load stats")
+		}
+
+		return render(c, http.StatusOK, partials.DashboardStats(counts))
+	}
+}
+
+func apiStats(db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		}
+
+		report, err := stats.ForUser(db.WithContext(c.Request().Context()), user.ID, clock.NowIn(clk, user.Location()))
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to load stats"})
+		}
+
+		return c.JSON(http.StatusOK, report)
+	}
+}
+
+func apiProjections(db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		}
+
+		projections, err := stats.ProjectionsForUser(db.WithContext(c.Request().Context()), user.ID, clock.NowIn(clk, user.Location()))
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to load projections"})
+		}
+
+		return c.JSON(http.StatusOK, map[string][]stats.Projection{"plans": projections})
+	}
+}
+
+// readHeatmapRange parses the optional from and to dates in the query
+// string, defaulting to the year ending today.
+func readHeatmapRange(c *echo.Context, today time.Time) (time.Time, time.Time, error) {
+	to := today
+	if value := c.QueryParam("to"); value != "" {
+		t, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("to date must be in YYYY-MM-DD format")
+		}
+		to = t
+	}
+	from := to.AddDate(-1, 0, 1)
+	if value := c.QueryParam("from"); value != "" {
+		t, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("from date must be in YYYY-MM-DD format")
+		}
+		from = t
+	}
+
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from date must not be after to date")
+	}
+	if to.Sub(from) >= stats.MaxHeatmapDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("date range must be at most %d days", stats.MaxHeatmapDays)
+	}
+	return from, to, nil
+}
+
+// heatmapPartial renders the past year's activity heatmap for the dashboard.
+func heatmapPartial(db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
 	return func(c *echo.Context) error {
 		user, ok := mw.GetSessionUser(c)
 		if !ok {
 			return c.NoContent(http.StatusUnauthorized)
 		}
 
-		weeklyCount, err := repository.GetWeeklyCompletedReadingsCount(db.WithContext(c.Request().Context()), user.ID)
+		now := clock.NowIn(clk, user.Location())
+		from, to, err := readHeatmapRange(c, now)
 		if err != nil {
-			return c.String(http.StatusInternalServerError, "Failed to load weekly stats")
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		days, err := stats.Heatmap(db.WithContext(c.Request().Context()), user.ID, from, to, user.Location())
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to load activity")
+		}
+
+		return render(c, http.StatusOK, partials.Heatmap(days))
+	}
+}
+
+func apiHeatmap(db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
 		}
 
-		monthlyCount, err := repository.GetMonthlyCompletedReadingsCount(db.WithContext(c.Request().Context()), user.ID)
+		from, to, err := readHeatmapRange(c, clock.NowIn(clk, user.Location()))
 		if err != nil {
-			return c.String(http.StatusInternalServerError, "Failed to load monthly stats")
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 		}
 
-		return render(c, http.StatusOK, partials.DashboardStats(weeklyCount, monthlyCount))
+		days, err := stats.Heatmap(db.WithContext(c.Request().Context()), user.ID, from, to, user.Location())
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to load activity"})
+		}
+
+		return c.JSON(http.StatusOK, map[string][]stats.HeatmapDay{"days": days})
+	}
+}
+
+// loadReadingStats counts the user's completed and skipped readings for the
+// week and month containing now.
+func loadReadingStats(tx *gorm.DB, userID uint, now time.Time) (partials.ReadingStats, error) {
+	var counts partials.ReadingStats
+	var err error
+	if counts.WeeklyCompleted, err = repository.GetWeeklyCompletedReadingsCount(tx, userID, now); err != nil {
+		return counts, err
+	}
+	if counts.MonthlyCompleted, err = repository.GetMonthlyCompletedReadingsCount(tx, userID, now); err != nil {
+		return counts, err
+	}
+	if counts.WeeklySkipped, err = repository.GetWeeklySkippedReadingsCount(tx, userID, now); err != nil {
+		return counts, err
 	}
+	counts.MonthlySkipped, err = repository.GetMonthlySkippedReadingsCount(tx, userID, now)
+	return counts, err
 }
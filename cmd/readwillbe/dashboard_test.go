@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"readwillbe/internal/clock"
+	"readwillbe/internal/model"
+	"readwillbe/internal/service/stats"
+
+	"github.com/labstack/echo/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDashboardStats(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "stats@example.com", "password123")
+	plan := createTestPlan(t, db, user, "Streak Plan")
+	now := time.Date(2026, time.March, 10, 9, 0, 0, 0, time.UTC)
+	clk := clock.Fixed(now)
+
+	for i := 0; i < 3; i++ {
+		date := now.AddDate(0, 0, -i)
+		r := createTestReading(t, db, plan, "Reading", time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC))
+		completed := date.AddDate(0, 0, i) // the older two were finished late
+		r.Status, r.CompletedAt = model.StatusCompleted, &completed
+		require.NoError(t, db.Save(r).Error)
+	}
+
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			c.Set(UserKey, *user)
+			return next(c)
+		}
+	})
+	e.GET("/dashboard", dashboardHandler(model.Config{}, db, clk))
+	e.GET("/api/stats", apiStats(db, clk))
+	e.GET("/api/heatmap", apiHeatmap(db, clk))
+	e.GET("/partials/heatmap", heatmapPartial(db, clk))
+	e.GET("/api/projections", apiProjections(db, clk))
+
+	t.Run("dashboard", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/dashboard", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, 200, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Current streak")
+		assert.Contains(t, rec.Body.String(), "33%")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/stats", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		require.Equal(t, 200, rec.Code)
+
+		var report stats.Report
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.Equal(t, 3, report.Overall.Completed)
+		assert.Equal(t, 1, report.Overall.OnTime)
+		assert.Equal(t, 2, report.Overall.Late)
+		assert.InDelta(t, 1.5, report.Overall.AverageDelayDays, 0.001)
+		assert.Equal(t, 1, report.Overall.CurrentStreak, "everything was completed today")
+		require.Len(t, report.Plans, 1)
+		assert.Equal(t, plan.ID, report.Plans[0].PlanID)
+	})
+
+	t.Run("heatmap", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/heatmap?from=2026-03-09&to=2026-03-10", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		require.Equal(t, 200, rec.Code)
+
+		var body struct {
+			Days []stats.HeatmapDay `json:"days"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		require.Len(t, body.Days, 2)
+		assert.Equal(t, 0, body.Days[0].Count)
+		assert.Equal(t, 3, body.Days[1].Count)
+
+		req = httptest.NewRequest("GET", "/partials/heatmap", nil)
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, 200, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Mar 10, 2026: 3 readings (3 daily)")
+
+		for _, target := range []string{
+			"/api/heatmap?from=2026-03-10&to=2026-03-01",
+			"/api/heatmap?from=2020-01-01&to=2026-03-01",
+			"/api/heatmap?to=tomorrow",
+		} {
+			req = httptest.NewRequest("GET", target, nil)
+			rec = httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, 400, rec.Code, target)
+		}
+	})
+
+	t.Run("projections", func(t *testing.T) {
+		unread := createTestPlan(t, db, user, "Unread Plan")
+		createTestReading(t, db, unread, "Reading", time.Date(2026, time.March, 20, 0, 0, 0, 0, time.UTC))
+
+		req := httptest.NewRequest("GET", "/api/projections", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		require.Equal(t, 200, rec.Code)
+
+		var body struct {
+			Plans []stats.Projection `json:"plans"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		require.Len(t, body.Plans, 1, "the finished plan is left out")
+		assert.Equal(t, unread.ID, body.Plans[0].PlanID)
+		assert.Equal(t, 1, body.Plans[0].Remaining)
+		assert.Equal(t, stats.ProjectionUnknown, body.Plans[0].Status)
+	})
+}
@@ -1,16 +1,108 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	mw "readwillbe/internal/middleware"
 	"readwillbe/internal/model"
+	"readwillbe/internal/repository"
 	"readwillbe/internal/views"
 
 	"github.com/labstack/echo/v5"
 	"gorm.io/gorm"
 )
 
+// readHistoryFilter parses the history filters in the query string: plan,
+// date_type, and a from/to completion date range taken as calendar days in
+// the user's timezone.
+func readHistoryFilter(c *echo.Context, user model.User) (repository.HistoryFilter, error) {
+	var filter repository.HistoryFilter
+
+	if value := c.QueryParam("plan"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return filter, fmt.Errorf("invalid plan")
+		}
+		filter.PlanID = uint(id)
+	}
+
+	filter.DateType = model.DateType(c.QueryParam("date_type"))
+	switch filter.DateType {
+	case "", model.DateTypeDay, model.DateTypeWeek, model.DateTypeMonth:
+	default:
+		return filter, fmt.Errorf("unknown date type %q", filter.DateType)
+	}
+
+	loc := user.Location()
+	from, err := readHistoryDate(c, "from", loc)
+	if err != nil {
+		return filter, err
+	}
+	to, err := readHistoryDate(c, "to", loc)
+	if err != nil {
+		return filter, err
+	}
+	if from != nil && to != nil && to.Before(*from) {
+		return filter, fmt.Errorf("from date must not be after to date")
+	}
+	filter.From = from
+	if to != nil {
+		until := to.AddDate(0, 0, 1)
+		filter.Until = &until
+	}
+
+	return filter, nil
+}
+
+// readHistoryDate parses an optional YYYY-MM-DD query parameter as the start
+// of that day in loc.
+func readHistoryDate(c *echo.Context, param string, loc *time.Location) (*time.Time, error) {
+	value := c.QueryParam(param)
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return nil, fmt.Errorf("%s date must be in YYYY-MM-DD format", param)
+	}
+	return &date, nil
+}
+
+// readHistoryCursor parses the optional after cursor in the query string.
+func readHistoryCursor(c *echo.Context) (*repository.HistoryCursor, error) {
+	value := c.QueryParam("after")
+	if value == "" {
+		return nil, nil
+	}
+	cursor, err := repository.ParseHistoryCursor(value)
+	if err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// historyPageURL links to the history page for the current filters, starting
+// after cursor, or at the newest reading if cursor is nil.
+func historyPageURL(c *echo.Context, cursor *repository.HistoryCursor) string {
+	query := url.Values{}
+	for _, key := range []string{"plan", "date_type", "from", "to"} {
+		if value := c.QueryParam(key); value != "" {
+			query.Set(key, value)
+		}
+	}
+	if cursor != nil {
+		query.Set("after", cursor.String())
+	}
+	if len(query) == 0 {
+		return "/history"
+	}
+	return "/history?" + query.Encode()
+}
+
 func historyHandler(cfg model.Config, db *gorm.DB) echo.HandlerFunc {
 	return func(c *echo.Context) error {
 		user, ok := mw.GetSessionUser(c)
@@ -18,16 +110,103 @@ func historyHandler(cfg model.Config, db *gorm.DB) echo.HandlerFunc {
 			return c.Redirect(http.StatusFound, "/auth/sign-in")
 		}
 
-		var readings []model.Reading
 		tx := db.WithContext(c.Request().Context())
-		tx.Preload("Plan").
-			Where("plan_id IN (?) AND status = ?",
-				tx.Table("plans").Select("id").Where("user_id = ?", user.ID),
-				model.StatusCompleted,
-			).
-			Order("completed_at DESC").
-			Find(&readings)
-
-		return render(c, 200, views.History(cfg, &user, readings))
+		plans, err := repository.GetPlanTitles(tx, user.ID)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to load plans")
+		}
+
+		props := views.HistoryProps{Plans: plans, Query: c.QueryParams()}
+		filter, err := readHistoryFilter(c, user)
+		if err != nil {
+			props.Err = err
+			return render(c, 400, views.History(cfg, &user, props))
+		}
+		after, err := readHistoryCursor(c)
+		if err != nil {
+			props.Err = err
+			return render(c, 400, views.History(cfg, &user, props))
+		}
+
+		page, err := repository.GetHistory(tx, user.ID, filter, after, repository.HistoryPageSize)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to load history")
+		}
+		props.Completed, props.Skipped, err = repository.CountHistory(tx, user.ID, filter)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to load history")
+		}
+
+		props.Readings = page.Readings
+		props.Filtered = filter != (repository.HistoryFilter{})
+		if after != nil {
+			props.NewestURL = historyPageURL(c, nil)
+		}
+		if page.Next != nil {
+			props.OlderURL = historyPageURL(c, page.Next)
+		}
+
+		return render(c, 200, views.History(cfg, &user, props))
+	}
+}
+
+type apiHistoryReading struct {
+	apiReading
+	Status      string     `json:"status"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	SkippedAt   *time.Time `json:"skippedAt,omitempty"`
+	SkipReason  string     `json:"skipReason,omitempty"`
+	Journal     string     `json:"journal,omitempty"`
+}
+
+type apiHistoryPage struct {
+	Readings []apiHistoryReading `json:"readings"`
+	Next     string              `json:"next,omitempty"`
+}
+
+func apiHistory(db *gorm.DB) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		}
+
+		filter, err := readHistoryFilter(c, user)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		after, err := readHistoryCursor(c)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		page, err := repository.GetHistory(db.WithContext(c.Request().Context()), user.ID, filter, after, repository.HistoryPageSize)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to load history"})
+		}
+
+		result := apiHistoryPage{Readings: make([]apiHistoryReading, len(page.Readings))}
+		for i, r := range page.Readings {
+			result.Readings[i] = apiHistoryReading{
+				apiReading: apiReading{
+					ID:      r.ID,
+					Date:    r.FormattedDate(),
+					Content: r.Content,
+					Plan:    &apiPlan{ID: r.Plan.ID, Title: r.Plan.Title},
+				},
+				Status:      string(r.Status),
+				CompletedAt: r.CompletedAt,
+				SkippedAt:   r.SkippedAt,
+				SkipReason:  r.SkipReason,
+			}
+			if r.Journal != nil {
+				result.Readings[i].Journal = r.Journal.Body
+			}
+		}
+		if page.Next != nil {
+			result.Next = page.Next.String()
+		}
+
+		return c.JSON(http.StatusOK, result)
 	}
 }
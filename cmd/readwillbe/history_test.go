@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"readwillbe/internal/model"
+	"readwillbe/internal/repository"
+
+	"github.com/labstack/echo/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "history@example.com", "password123")
+	plan := createTestPlan(t, db, user, "History Plan")
+
+	base := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < repository.HistoryPageSize+2; i++ {
+		r := createTestReading(t, db, plan, "Reading", base.AddDate(0, 0, i))
+		at := base.AddDate(0, 0, i)
+		r.Status, r.CompletedAt = model.StatusCompleted, &at
+		require.NoError(t, db.Save(r).Error)
+	}
+
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			c.Set(UserKey, *user)
+			return next(c)
+		}
+	})
+	e.GET("/history", historyHandler(model.Config{}, db))
+	e.GET("/api/history", apiHistory(db))
+
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("page", func(t *testing.T) {
+		rec := get("/history")
+		assert.Equal(t, 200, rec.Code)
+		assert.Contains(t, rec.Body.String(), "27 completed, 0 skipped")
+		assert.Contains(t, rec.Body.String(), "/history?after=")
+	})
+
+	t.Run("json pages follow the cursor", func(t *testing.T) {
+		var first apiHistoryPage
+		rec := get("/api/history")
+		require.Equal(t, 200, rec.Code)
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &first))
+		assert.Len(t, first.Readings, repository.HistoryPageSize)
+		require.NotEmpty(t, first.Next)
+
+		var second apiHistoryPage
+		rec = get("/api/history?after=" + first.Next)
+		require.Equal(t, 200, rec.Code)
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &second))
+		assert.Len(t, second.Readings, 2)
+		assert.Empty(t, second.Next)
+		assert.Equal(t, "History Plan", second.Readings[0].Plan.Title)
+	})
+
+	t.Run("completion date range", func(t *testing.T) {
+		var page apiHistoryPage
+		rec := get("/api/history?from=2026-03-02&to=2026-03-03&date_type=day")
+		require.Equal(t, 200, rec.Code)
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+		assert.Len(t, page.Readings, 2, "the to date is inclusive")
+	})
+
+	t.Run("invalid filters", func(t *testing.T) {
+		for _, target := range []string{
+			"/history?date_type=year",
+			"/history?plan=x",
+			"/history?from=2026-03-05&to=2026-03-01",
+			"/history?after=garbage",
+		} {
+			assert.Equal(t, 400, get(target).Code, target)
+		}
+		assert.Equal(t, 400, get("/api/history?after=garbage").Code)
+	})
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+
+	"readwillbe/internal/database"
+	"readwillbe/internal/model"
+)
+
+var (
+	jobsListStatus string
+	jobsListLimit  int
+)
+
+var jobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "Inspect background jobs",
+	Long:  `List and inspect the persisted background jobs, such as CSV imports, and their retry state.`,
+}
+
+var jobsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List recent jobs",
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		db, err := openDatabase()
+		if err != nil {
+			return err
+		}
+
+		query := db.Order("id DESC").Limit(jobsListLimit)
+		if jobsListStatus != "" {
+			query = query.Where("status = ?", jobsListStatus)
+		}
+
+		var list []model.Job
+		if err := query.Find(&list).Error; err != nil {
+			return errors.Wrap(err, "listing jobs")
+		}
+
+		if len(list) == 0 {
+			fmt.Println("No jobs")
+			return nil
+		}
+		for _, j := range list {
+			fmt.Printf("  %6d  %-12s %-10s plan=%-6d attempts=%d/%d  %s\n",
+				j.ID, j.Type, j.Status, j.PlanID, j.Attempts, j.MaxAttempts, j.CreatedAt.Format("2006-01-02 15:04:05"))
+		}
+		return nil
+	},
+}
+
+var jobsShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show one job in detail",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		id, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			return errors.Errorf("invalid job ID %q", args[0])
+		}
+
+		db, err := openDatabase()
+		if err != nil {
+			return err
+		}
+
+		var j model.Job
+		if err := db.First(&j, id).Error; err != nil {
+			return errors.Wrapf(err, "loading job %d", id)
+		}
+
+		fmt.Printf("Job %d (%s)\n", j.ID, j.Type)
+		fmt.Printf("  Status:    %s\n", j.Status)
+		fmt.Printf("  User:      %d\n", j.UserID)
+		fmt.Printf("  Plan:      %d\n", j.PlanID)
+		fmt.Printf("  Attempts:  %d of %d\n", j.Attempts, j.MaxAttempts)
+		fmt.Printf("  Created:   %s\n", j.CreatedAt.Format("2006-01-02 15:04:05"))
+		if j.IsFinished() && j.FinishedAt != nil {
+			fmt.Printf("  Finished:  %s\n", j.FinishedAt.Format("2006-01-02 15:04:05"))
+		} else {
+			fmt.Printf("  Run after: %s\n", j.RunAfter.Format("2006-01-02 15:04:05"))
+		}
+		if j.LockedBy != "" {
+			fmt.Printf("  Worker:    %s\n", j.LockedBy)
+		}
+		if j.LastError != "" {
+			fmt.Printf("  Error:     %s\n", j.LastError)
+		}
+		return nil
+	},
+}
+
+func openDatabase() (*gorm.DB, error) {
+	driver, dsn, err := model.DatabaseFromViper()
+	if err != nil {
+		return nil, err
+	}
+	return database.Open(driver, dsn)
+}
+
+func init() {
+	jobsListCmd.Flags().StringVar(&jobsListStatus, "status", "", "only show jobs in this status (pending, running, succeeded, failed)")
+	jobsListCmd.Flags().IntVar(&jobsListLimit, "limit", 50, "maximum number of jobs to show")
+
+	jobsCmd.AddCommand(jobsListCmd, jobsShowCmd)
+	rootCmd.AddCommand(jobsCmd)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v5"
+	"gorm.io/gorm"
+
+	"readwillbe/internal/clock"
+	mw "readwillbe/internal/middleware"
+	"readwillbe/internal/model"
+	"readwillbe/internal/repository"
+	"readwillbe/internal/views"
+)
+
+// journalReturnPaths are the pages a journal form may send the user back to.
+var journalReturnPaths = map[string]bool{
+	"/dashboard": true,
+	"/history":   true,
+	"/journal":   true,
+}
+
+func journalReturnTo(c *echo.Context) string {
+	if to := c.FormValue("return_to"); journalReturnPaths[to] {
+		return to
+	}
+	return "/journal"
+}
+
+func loadUserReading(c *echo.Context, db *gorm.DB, user model.User) (model.Reading, error) {
+	var reading model.Reading
+	err := db.WithContext(c.Request().Context()).
+		Preload("Plan").
+		Joins("JOIN plans ON plans.id = readings.plan_id").
+		Where("readings.id = ? AND plans.user_id = ?", c.Param("id"), user.ID).
+		First(&reading).Error
+	return reading, err
+}
+
+func journalForm(cfg model.Config, db *gorm.DB) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.Redirect(http.StatusFound, "/auth/sign-in")
+		}
+
+		if _, err := strconv.ParseUint(c.Param("id"), 10, 32); err != nil {
+			return c.String(http.StatusBadRequest, "Invalid reading ID")
+		}
+		reading, err := loadUserReading(c, db, user)
+		if err != nil {
+			return c.String(http.StatusNotFound, "Reading not found")
+		}
+
+		entry, err := repository.GetJournalEntry(db.WithContext(c.Request().Context()), user.ID, reading.ID)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to load journal entry")
+		}
+
+		return render(c, 200, views.JournalEdit(cfg, &user, reading, entry, journalReturnTo(c), nil))
+	}
+}
+
+func saveJournal(cfg model.Config, db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.Redirect(http.StatusFound, "/auth/sign-in")
+		}
+
+		if _, err := strconv.ParseUint(c.Param("id"), 10, 32); err != nil {
+			return c.String(http.StatusBadRequest, "Invalid reading ID")
+		}
+		reading, err := loadUserReading(c, db, user)
+		if err != nil {
+			return c.String(http.StatusNotFound, "Reading not found")
+		}
+
+		body := c.FormValue("body")
+		returnTo := journalReturnTo(c)
+		if len(body) > model.MaxJournalLength {
+			entry := model.JournalEntry{ReadingID: reading.ID, Body: body}
+			return render(c, 422, views.JournalEdit(cfg, &user, reading, entry, returnTo,
+				fmt.Errorf("journal entry must be less than %d characters", model.MaxJournalLength)))
+		}
+
+		if _, err := repository.SaveJournalEntry(db.WithContext(c.Request().Context()), user.ID, reading.ID, body, clk.Now()); err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to save journal entry")
+		}
+
+		return c.Redirect(http.StatusFound, returnTo)
+	}
+}
+
+// MaxJournalResults caps how many entries the journal page lists.
+const MaxJournalResults = 100
+
+func journalList(cfg model.Config, db *gorm.DB) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.Redirect(http.StatusFound, "/auth/sign-in")
+		}
+
+		query := c.QueryParam("q")
+		entries, err := repository.SearchJournal(db.WithContext(c.Request().Context()), user.ID, query, MaxJournalResults)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to load journal")
+		}
+
+		return render(c, 200, views.Journal(cfg, &user, query, entries))
+	}
+}
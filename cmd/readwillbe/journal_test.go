@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"readwillbe/internal/clock"
+	"readwillbe/internal/model"
+
+	"github.com/labstack/echo/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournal(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "journal@example.com", "password123")
+	plan := createTestPlan(t, db, user, "Journal Plan")
+	reading := createTestReading(t, db, plan, "Romans 8", time.Now())
+	other := createTestUser(t, db, "other@example.com", "password123")
+	otherPlan := createTestPlan(t, db, other, "Other Plan")
+	foreign := createTestReading(t, db, otherPlan, "Romans 9", time.Now())
+	clk := clock.Fixed(time.Date(2026, time.May, 1, 8, 0, 0, 0, time.UTC))
+
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			c.Set(UserKey, *user)
+			return next(c)
+		}
+	})
+	e.GET("/reading/:id/journal", journalForm(model.Config{}, db))
+	e.POST("/reading/:id/journal", saveJournal(model.Config{}, db, clk))
+	e.GET("/journal", journalList(model.Config{}, db))
+
+	post := func(id uint, body, returnTo string) *httptest.ResponseRecorder {
+		form := url.Values{}
+		form.Set("body", body)
+		form.Set("return_to", returnTo)
+		req := httptest.NewRequest("POST", fmt.Sprintf("/reading/%d/journal", id), strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("save and return", func(t *testing.T) {
+		rec := post(reading.ID, "No **condemnation**", "/history")
+		assert.Equal(t, 302, rec.Code)
+		assert.Equal(t, "/history", rec.Header().Get("Location"))
+
+		var entry model.JournalEntry
+		require.NoError(t, db.Where("reading_id = ?", reading.ID).First(&entry).Error)
+		assert.Equal(t, "No **condemnation**", entry.Body)
+		assert.Equal(t, user.ID, entry.UserID)
+	})
+
+	t.Run("unknown return path falls back to journal", func(t *testing.T) {
+		rec := post(reading.ID, "No condemnation", "https://example.com")
+		assert.Equal(t, 302, rec.Code)
+		assert.Equal(t, "/journal", rec.Header().Get("Location"))
+	})
+
+	t.Run("too long", func(t *testing.T) {
+		rec := post(reading.ID, strings.Repeat("a", model.MaxJournalLength+1), "/history")
+		assert.Equal(t, 422, rec.Code)
+		assert.Contains(t, rec.Body.String(), "journal entry must be less than")
+	})
+
+	t.Run("other user's reading", func(t *testing.T) {
+		rec := post(foreign.ID, "Not mine", "/history")
+		assert.Equal(t, 404, rec.Code)
+
+		req := httptest.NewRequest("GET", fmt.Sprintf("/reading/%d/journal", foreign.ID), nil)
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, 404, rec.Code)
+	})
+
+	t.Run("form shows entry", func(t *testing.T) {
+		req := httptest.NewRequest("GET", fmt.Sprintf("/reading/%d/journal?return_to=/dashboard", reading.ID), nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, 200, rec.Code)
+		assert.Contains(t, rec.Body.String(), "No condemnation")
+		assert.Contains(t, rec.Body.String(), `value="/dashboard"`)
+	})
+
+	t.Run("search", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/journal?q=CONDEMN", nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, 200, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Romans 8")
+
+		req = httptest.NewRequest("GET", "/journal?q=grace", nil)
+		rec = httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, 200, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "Romans 8")
+	})
+}
@@ -3,12 +3,23 @@ package main
 import (
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo-contrib/v5/session"
 	"github.com/labstack/echo/v5"
 	"github.com/labstack/echo/v5/middleware"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"readwillbe/internal/cache"
+	"readwillbe/internal/clock"
+	mw "readwillbe/internal/middleware"
+	"readwillbe/internal/model"
+	"readwillbe/internal/repository"
 )
 
 func TestGzipConfiguration(t *testing.T) {
@@ -54,3 +65,118 @@ func TestGzipConfiguration(t *testing.T) {
 		assert.True(t, rec.Body.Len() < 1500)
 	})
 }
+
+func TestProxyAuth(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "proxied@example.com", "correctpassword123")
+	cfg := model.Config{
+		ProxyAuthHeader:     "Remote-Email",
+		ProxyAuthNameHeader: "Remote-Name",
+		TrustedProxies:      []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("fd00::/8")},
+	}
+
+	newServer := func(cfg model.Config) *echo.Echo {
+		e := echo.New()
+		e.Use(session.Middleware(sessions.NewCookieStore([]byte("0123456789abcdef0123456789abcdef"))))
+		e.Use(mw.UserMiddleware(db, cache.NewUserCache(time.Minute, time.Minute), cfg, clock.Fixed(time.Date(2026, time.March, 1, 9, 0, 0, 0, time.UTC))))
+		e.GET("/whoami", func(c *echo.Context) error {
+			if user, ok := mw.GetSessionUser(c); ok {
+				return c.String(http.StatusOK, user.Email)
+			}
+			return c.String(http.StatusOK, "anonymous")
+		})
+		e.GET("/auth/sign-in", signIn(cfg))
+		return e
+	}
+	request := func(e *echo.Echo, remoteAddr string, header http.Header, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.RemoteAddr = remoteAddr
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	as := func(email string) http.Header {
+		return http.Header{"Remote-Email": {email}}
+	}
+
+	e := newServer(cfg)
+
+	t.Run("trusts the header from a trusted proxy", func(t *testing.T) {
+		assert.Equal(t, user.Email, request(e, "10.1.2.3:4567", as(user.Email)).Body.String())
+		assert.Equal(t, user.Email, request(e, "[fd00::1]:4567", as(user.Email)).Body.String())
+		assert.Equal(t, user.Email, request(e, "[::ffff:10.0.0.2]:4567", as(user.Email)).Body.String())
+	})
+
+	t.Run("ignores the header from anywhere else", func(t *testing.T) {
+		assert.Equal(t, "anonymous", request(e, "192.0.2.1:4567", as(user.Email)).Body.String())
+
+		spoofed := as(user.Email)
+		spoofed.Set("X-Forwarded-For", "10.1.2.3")
+		assert.Equal(t, "anonymous", request(e, "192.0.2.1:4567", spoofed).Body.String())
+	})
+
+	t.Run("the header decides over the session", func(t *testing.T) {
+		other := createTestUser(t, db, "other-proxied@example.com", "correctpassword123")
+		rec := request(e, "10.1.2.3:4567", as(user.Email))
+		cookies := rec.Result().Cookies()
+		require.NotEmpty(t, cookies)
+
+		assert.Equal(t, other.Email, request(e, "10.1.2.3:4567", as(other.Email), cookies...).Body.String())
+		assert.Equal(t, "anonymous", request(e, "10.1.2.3:4567", nil, cookies...).Body.String(), "the session alone signs no one in")
+		assert.Equal(t, "anonymous", request(e, "192.0.2.1:4567", nil, cookies...).Body.String())
+	})
+
+	t.Run("clears the session user when the header is missing", func(t *testing.T) {
+		cookies := request(e, "10.1.2.3:4567", as(user.Email)).Result().Cookies()
+		require.NotEmpty(t, cookies)
+
+		rec := request(e, "10.1.2.3:4567", nil, cookies...)
+		cleared := rec.Result().Cookies()
+		require.NotEmpty(t, cleared, "the session is saved without its user")
+
+		proxyless := cfg
+		proxyless.ProxyAuthHeader = ""
+		assert.Equal(t, "anonymous", request(newServer(proxyless), "10.1.2.3:4567", nil, cleared...).Body.String())
+	})
+
+	t.Run("offers no sign-in form", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/auth/sign-in", nil)
+		req.RemoteAddr = "10.1.2.3:4567"
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("skips the sign-in form", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/auth/sign-in", nil)
+		req.RemoteAddr = "10.1.2.3:4567"
+		req.Header.Set("Remote-Email", user.Email)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusFound, rec.Code)
+		assert.Equal(t, "/", rec.Header().Get("Location"))
+	})
+
+	t.Run("creates unknown users only if sign-up is allowed", func(t *testing.T) {
+		header := as("new-proxied@example.com")
+		header.Set("Remote-Name", "New Reader")
+
+		assert.Equal(t, "anonymous", request(e, "10.1.2.3:4567", header).Body.String())
+		assert.False(t, repository.UserExists("new-proxied@example.com", db))
+
+		signup := cfg
+		signup.AllowSignup = true
+		assert.Equal(t, "new-proxied@example.com", request(newServer(signup), "10.1.2.3:4567", header).Body.String())
+		created, err := repository.GetUserByEmail(db, "new-proxied@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "New Reader", created.Name)
+		assert.True(t, created.EmailVerified)
+		assert.Empty(t, created.Password)
+	})
+}
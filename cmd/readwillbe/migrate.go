@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"readwillbe/internal/migrate"
+)
+
+var migrateDownSteps int
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage database schema migrations",
+	Long:  `Apply, roll back, or inspect the versioned schema migrations for the configured database.`,
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply all pending migrations",
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		m, err := openMigrator()
+		if err != nil {
+			return err
+		}
+
+		ran, err := m.Up()
+		for _, mig := range ran {
+			fmt.Printf("Applied %d_%s\n", mig.Version, mig.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(ran) == 0 {
+			fmt.Println("Schema is up to date")
+		}
+		return nil
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Roll back the most recent migrations",
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		if migrateDownSteps < 1 {
+			return errors.New("--steps must be at least 1")
+		}
+
+		m, err := openMigrator()
+		if err != nil {
+			return err
+		}
+
+		ran, err := m.Down(migrateDownSteps)
+		for _, mig := range ran {
+			fmt.Printf("Reverted %d_%s\n", mig.Version, mig.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(ran) == 0 {
+			fmt.Println("No migrations to revert")
+		}
+		return nil
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show which migrations have been applied",
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		m, err := openMigrator()
+		if err != nil {
+			return err
+		}
+
+		statuses, err := m.Status()
+		if err != nil {
+			return err
+		}
+
+		current, err := m.Current()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Schema version: %d (binary expects %d)\n", current, m.Latest())
+		fmt.Println()
+		for _, s := range statuses {
+			applied := "pending"
+			if s.Applied {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("  %4d  %-30s %s\n", s.Version, s.Name, applied)
+		}
+		return nil
+	},
+}
+
+func openMigrator() (*migrate.Migrator, error) {
+	db, err := openDatabase()
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(db), nil
+}
+
+func init() {
+	migrateDownCmd.Flags().IntVar(&migrateDownSteps, "steps", 1, "number of migrations to roll back")
+
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateStatusCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
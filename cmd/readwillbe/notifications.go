@@ -6,19 +6,20 @@ import (
 	"github.com/labstack/echo/v5"
 	"gorm.io/gorm"
 
+	"readwillbe/internal/clock"
 	mw "readwillbe/internal/middleware"
 	"readwillbe/internal/repository"
 	"readwillbe/internal/views"
 )
 
-func notificationCount(db *gorm.DB) echo.HandlerFunc {
+func notificationCount(db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
 	return func(c *echo.Context) error {
 		user, ok := mw.GetSessionUser(c)
 		if !ok {
 			return c.NoContent(http.StatusUnauthorized)
 		}
 
-		count, err := repository.GetActiveReadingsCount(db.WithContext(c.Request().Context()), user.ID)
+		count, err := repository.GetActiveReadingsCount(db.WithContext(c.Request().Context()), user.ID, clock.NowIn(clk, user.Location()))
 		if err != nil {
 			// Log error but return 0 count
 			return render(c, 200, views.NotificationBell(0))
@@ -28,14 +29,14 @@ func notificationCount(db *gorm.DB) echo.HandlerFunc {
 	}
 }
 
-func notificationDropdown(db *gorm.DB) echo.HandlerFunc {
+func notificationDropdown(db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
 	return func(c *echo.Context) error {
 		user, ok := mw.GetSessionUser(c)
 		if !ok {
 			return c.NoContent(http.StatusUnauthorized)
 		}
 
-		readings, err := repository.GetActiveReadings(db.WithContext(c.Request().Context()), user.ID, 5)
+		readings, err := repository.GetActiveReadings(db.WithContext(c.Request().Context()), user.ID, clock.NowIn(clk, user.Location()), 5)
 		if err != nil {
 			return c.NoContent(http.StatusInternalServerError)
 		}
@@ -46,14 +47,14 @@ func notificationDropdown(db *gorm.DB) echo.HandlerFunc {
 
 // JSON API handlers for React components
 
-func apiNotificationCount(db *gorm.DB) echo.HandlerFunc {
+func apiNotificationCount(db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
 	return func(c *echo.Context) error {
 		user, ok := mw.GetSessionUser(c)
 		if !ok {
 			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
 		}
 
-		count, err := repository.GetActiveReadingsCount(db.WithContext(c.Request().Context()), user.ID)
+		count, err := repository.GetActiveReadingsCount(db.WithContext(c.Request().Context()), user.ID, clock.NowIn(clk, user.Location()))
 		if err != nil {
 			return c.JSON(http.StatusOK, map[string]int{"count": 0})
 		}
@@ -74,14 +75,14 @@ type apiPlan struct {
 	Title string `json:"title"`
 }
 
-func apiNotificationReadings(db *gorm.DB) echo.HandlerFunc {
+func apiNotificationReadings(db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
 	return func(c *echo.Context) error {
 		user, ok := mw.GetSessionUser(c)
 		if !ok {
 			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
 		}
 
-		readings, err := repository.GetActiveReadings(db.WithContext(c.Request().Context()), user.ID, 10)
+		readings, err := repository.GetActiveReadings(db.WithContext(c.Request().Context()), user.ID, clock.NowIn(clk, user.Location()), 10)
 		if err != nil {
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to fetch readings"})
 		}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"time"
+
+	"github.com/labstack/echo-contrib/v5/session"
+	"github.com/labstack/echo/v5"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+
+	"readwillbe/internal/clock"
+	mw "readwillbe/internal/middleware"
+	"readwillbe/internal/model"
+	"readwillbe/internal/repository"
+	"readwillbe/internal/service/sso"
+	"readwillbe/internal/views"
+)
+
+// OIDCTimeout is how long a user has to sign in at the identity provider.
+const OIDCTimeout = 10 * time.Minute
+
+var errNoOIDCAccount = errors.New("there is no account for that email address")
+
+// oidcSignIn sends the browser to the identity provider, remembering what
+// the callback needs to check its answer.
+func oidcSignIn(cfg model.Config, clk clock.Clock, provider *sso.Provider) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		sess, err := session.Get(mw.OIDCSessionKey, c)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to start sign-in")
+		}
+		state, nonce, verifier := rand.Text(), rand.Text(), oauth2.GenerateVerifier()
+		sess.Options = mw.GetOIDCSessionOptions(cfg)
+		sess.Values[mw.OIDCStateKey] = state
+		sess.Values[mw.OIDCNonceKey] = nonce
+		sess.Values[mw.OIDCVerifierKey] = verifier
+		sess.Values[mw.OIDCStartedAtKey] = clk.Now().Unix()
+		if err := sess.Save(c.Request(), c.Response()); err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to start sign-in")
+		}
+
+		return c.Redirect(http.StatusFound, provider.AuthCodeURL(state, nonce, verifier))
+	}
+}
+
+// oidcCallback signs in the user the identity provider vouches for, creating
+// their account first if cfg allows it.
+func oidcCallback(cfg model.Config, db *gorm.DB, clk clock.Clock, provider *sso.Provider) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		csrf, _ := c.Get("csrf").(string)
+		fail := func(status int, err error) error {
+			return render(c, status, views.SignInPage(cfg, csrf, err))
+		}
+
+		sess, err := session.Get(mw.OIDCSessionKey, c)
+		if err != nil {
+			return fail(http.StatusBadRequest, errors.New("sign-in expired, please try again"))
+		}
+		state, _ := sess.Values[mw.OIDCStateKey].(string)
+		nonce, _ := sess.Values[mw.OIDCNonceKey].(string)
+		verifier, _ := sess.Values[mw.OIDCVerifierKey].(string)
+		startedAt, _ := sess.Values[mw.OIDCStartedAtKey].(int64)
+		sess.Options = mw.GetOIDCSessionOptions(cfg)
+		sess.Options.MaxAge = -1
+		if err := sess.Save(c.Request(), c.Response()); err != nil {
+			return fail(http.StatusInternalServerError, errors.Wrap(err, "Internal server error"))
+		}
+
+		if state == "" || clk.Now().Sub(time.Unix(startedAt, 0)) > OIDCTimeout ||
+			subtle.ConstantTimeCompare([]byte(state), []byte(c.QueryParam("state"))) != 1 {
+			return fail(http.StatusBadRequest, errors.New("sign-in expired, please try again"))
+		}
+		if reason := c.QueryParam("error"); reason != "" {
+			logrus.Infof("Identity provider refused sign-in: %s", reason)
+			return fail(http.StatusUnauthorized, fmt.Errorf("%s did not sign you in", cfg.OIDCProviderName))
+		}
+
+		identity, err := provider.Exchange(c.Request().Context(), c.QueryParam("code"), nonce, verifier)
+		if err != nil {
+			logrus.Warnf("Rejected OpenID Connect sign-in: %v", err)
+			if errors.Is(err, sso.ErrNoEmail) || errors.Is(err, sso.ErrEmailNotVerified) {
+				return fail(http.StatusForbidden, err)
+			}
+			return fail(http.StatusUnauthorized, fmt.Errorf("sign-in with %s failed", cfg.OIDCProviderName))
+		}
+
+		user, err := oidcUser(db.WithContext(c.Request().Context()), cfg, clk, identity)
+		if errors.Is(err, errNoOIDCAccount) {
+			return fail(http.StatusForbidden, err)
+		}
+		if err != nil {
+			logrus.Errorf("Failed to find or create user for %s: %v", identity.Email, err)
+			return fail(http.StatusInternalServerError, errors.New("internal server error"))
+		}
+
+		target := "/"
+		if user.TwoFactorEnabled() {
+			err = startTwoFactor(c, cfg, user.ID, clk.Now())
+			target = "/auth/two-factor"
+		} else {
+			err = signInSession(c, cfg, user.ID)
+		}
+		if err != nil {
+			return fail(http.StatusInternalServerError, errors.Wrap(err, "Internal server error"))
+		}
+
+		return render(c, http.StatusOK, views.SignInRedirect(target))
+	}
+}
+
+// oidcUser returns the user with the identity's email address. If there is
+// none and both cfg.AllowSignup and cfg.OIDCCreateUsers are set, it creates
+// one without a password, so they can only sign in through the provider.
+func oidcUser(tx *gorm.DB, cfg model.Config, clk clock.Clock, identity sso.Identity) (model.User, error) {
+	address, err := mail.ParseAddress(identity.Email)
+	if err != nil || len(address.Address) > MaxEmailLength {
+		return model.User{}, errors.Errorf("invalid email address %q", identity.Email)
+	}
+
+	user, err := repository.GetUserByEmail(tx, address.Address)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.User{}, err
+	}
+	if !cfg.AllowSignup || !cfg.OIDCCreateUsers {
+		return model.User{}, errNoOIDCAccount
+	}
+
+	name := []rune(identity.Name)
+	if len(name) == 0 {
+		name = []rune(address.Address)
+	}
+	if len(name) > MaxNameLength {
+		name = name[:MaxNameLength]
+	}
+	user = model.User{
+		Name:          string(name),
+		Email:         address.Address,
+		EmailVerified: true,
+		CreatedAt:     clk.Now(),
+	}
+	if err := repository.CreateUser(tx, &user); err != nil {
+		return model.User{}, errors.Wrap(err, "creating user")
+	}
+	logrus.Infof("Created user %d on first sign-in through %s", user.ID, cfg.OIDCProviderName)
+
+	return user, nil
+}
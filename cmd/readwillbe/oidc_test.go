@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"readwillbe/internal/clock"
+	mw "readwillbe/internal/middleware"
+	"readwillbe/internal/model"
+	"readwillbe/internal/repository"
+	"readwillbe/internal/service/sso"
+	"readwillbe/internal/service/sso/ssotest"
+)
+
+func newTestOIDC(t *testing.T, cfg model.Config) (*ssotest.Provider, model.Config, *sso.Provider) {
+	t.Helper()
+	idp := ssotest.NewProvider(t)
+	cfg.OIDCIssuer = idp.URL
+	cfg.OIDCClientID = ssotest.ClientID
+	cfg.OIDCClientSecret = ssotest.ClientSecret
+	cfg.OIDCRedirectURL = "https://readwillbe.example/auth/oidc/callback"
+	cfg.OIDCProviderName = "Example SSO"
+	provider, err := sso.New(context.Background(), cfg)
+	require.NoError(t, err)
+	return idp, cfg, provider
+}
+
+// oidcFlow starts a sign-in with start, lets the identity provider answer as
+// the user it has signed in, and returns the response of callback.
+func oidcFlow(t *testing.T, client *sessionClient, idp *ssotest.Provider, start, callback echo.HandlerFunc) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := client.get(t, start, "/auth/oidc", nil)
+	require.Equal(t, http.StatusFound, rec.Code)
+	redirect := idp.Authorize(t, rec.Header().Get("Location"))
+	require.Equal(t, "/auth/oidc/callback", redirect.Path)
+	return client.get(t, callback, redirect.RequestURI(), nil)
+}
+
+func TestOIDCSignIn(t *testing.T) {
+	db := setupTestDB(t)
+	clk := clock.Fixed(time.Date(2026, time.May, 1, 8, 0, 0, 0, time.UTC))
+	idp, cfg, provider := newTestOIDC(t, model.Config{})
+	user := createTestUser(t, db, "sso@example.com", "correctpassword123")
+
+	flow := func(t *testing.T, client *sessionClient, finishedAt time.Time) *httptest.ResponseRecorder {
+		t.Helper()
+		return oidcFlow(t, client, idp, oidcSignIn(cfg, clk, provider), oidcCallback(cfg, db, clock.Fixed(finishedAt), provider))
+	}
+
+	t.Run("sign-in page offers the provider", func(t *testing.T) {
+		rec := newSessionClient().get(t, signIn(cfg), "/auth/sign-in", nil)
+		assert.Contains(t, rec.Body.String(), `href="/auth/oidc"`)
+		assert.Contains(t, rec.Body.String(), "Sign In with Example SSO")
+	})
+
+	t.Run("signs in the user with the email address", func(t *testing.T) {
+		client := newSessionClient()
+		idp.SignIn(map[string]any{"email": user.Email, "email_verified": true})
+
+		rec := flow(t, client, clk.Now())
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `content="0;url=/"`)
+		assert.Equal(t, user.ID, client.values(t)[mw.SessionUserIDKey])
+	})
+
+	t.Run("the callback cannot be replayed", func(t *testing.T) {
+		client := newSessionClient()
+		idp.SignIn(map[string]any{"email": user.Email})
+		rec := client.get(t, oidcSignIn(cfg, clk, provider), "/auth/oidc", nil)
+		callback := idp.Authorize(t, rec.Header().Get("Location"))
+
+		rec = client.get(t, oidcCallback(cfg, db, clk, provider), callback.RequestURI(), nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+		rec = client.get(t, oidcCallback(cfg, db, clk, provider), callback.RequestURI(), nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "sign-in expired")
+	})
+
+	t.Run("the state must match", func(t *testing.T) {
+		client := newSessionClient()
+		idp.SignIn(map[string]any{"email": user.Email})
+		rec := client.get(t, oidcSignIn(cfg, clk, provider), "/auth/oidc", nil)
+		callback := idp.Authorize(t, rec.Header().Get("Location"))
+		q := callback.Query()
+		q.Set("state", "forged")
+		callback.RawQuery = q.Encode()
+
+		rec = client.get(t, oidcCallback(cfg, db, clk, provider), callback.RequestURI(), nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Nil(t, client.values(t)[mw.SessionUserIDKey])
+	})
+
+	t.Run("sign-in expires", func(t *testing.T) {
+		client := newSessionClient()
+		idp.SignIn(map[string]any{"email": user.Email})
+
+		rec := flow(t, client, clk.Now().Add(OIDCTimeout+time.Second))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Nil(t, client.values(t)[mw.SessionUserIDKey])
+	})
+
+	t.Run("the provider can refuse", func(t *testing.T) {
+		client := newSessionClient()
+		rec := client.get(t, oidcSignIn(cfg, clk, provider), "/auth/oidc", nil)
+		authURL, err := url.Parse(rec.Header().Get("Location"))
+		require.NoError(t, err)
+		state := authURL.Query().Get("state")
+
+		rec = client.get(t, oidcCallback(cfg, db, clk, provider), "/auth/oidc/callback?error=access_denied&state="+state, nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Example SSO did not sign you in")
+	})
+
+	t.Run("an unverified email is refused", func(t *testing.T) {
+		client := newSessionClient()
+		idp.SignIn(map[string]any{"email": user.Email, "email_verified": false})
+
+		rec := flow(t, client, clk.Now())
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Nil(t, client.values(t)[mw.SessionUserIDKey])
+	})
+
+	t.Run("two-factor users still need their code", func(t *testing.T) {
+		other := createTestUser(t, db, "sso-totp@example.com", "correctpassword123")
+		enableTestTwoFactor(t, db, other, clk.Now())
+		client := newSessionClient()
+		idp.SignIn(map[string]any{"email": other.Email})
+
+		rec := flow(t, client, clk.Now())
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `content="0;url=/auth/two-factor"`)
+		values := client.values(t)
+		assert.Nil(t, values[mw.SessionUserIDKey])
+		assert.Equal(t, other.ID, values[mw.SessionPendingUserIDKey])
+	})
+}
+
+func TestOIDCAccountCreation(t *testing.T) {
+	db := setupTestDB(t)
+	clk := clock.Fixed(time.Date(2026, time.May, 1, 8, 0, 0, 0, time.UTC))
+	idp, cfg, provider := newTestOIDC(t, model.Config{})
+
+	run := func(t *testing.T, cfg model.Config) (*sessionClient, *httptest.ResponseRecorder) {
+		t.Helper()
+		client := newSessionClient()
+		return client, oidcFlow(t, client, idp, oidcSignIn(cfg, clk, provider), oidcCallback(cfg, db, clk, provider))
+	}
+
+	idp.SignIn(map[string]any{"email": "new@example.com", "name": "New Reader"})
+
+	signupOnly, createOnly := cfg, cfg
+	signupOnly.AllowSignup = true
+	createOnly.OIDCCreateUsers = true
+	for name, c := range map[string]model.Config{
+		"not without account creation": signupOnly,
+		"not without sign-up":          createOnly,
+	} {
+		t.Run(name, func(t *testing.T) {
+			client, rec := run(t, c)
+			assert.Equal(t, http.StatusForbidden, rec.Code)
+			assert.Contains(t, rec.Body.String(), "no account for that email address")
+			assert.Nil(t, client.values(t)[mw.SessionUserIDKey])
+			assert.False(t, repository.UserExists("new@example.com", db))
+		})
+	}
+
+	t.Run("creates the account on first sign-in", func(t *testing.T) {
+		cfg.AllowSignup, cfg.OIDCCreateUsers = true, true
+		client, rec := run(t, cfg)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		created, err := repository.GetUserByEmail(db, "new@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "New Reader", created.Name)
+		assert.True(t, created.EmailVerified)
+		assert.Empty(t, created.Password, "the account has no password to guess")
+		assert.Equal(t, created.ID, client.values(t)[mw.SessionUserIDKey])
+
+		_, rec = run(t, cfg)
+		require.Equal(t, http.StatusOK, rec.Code)
+		var count int64
+		require.NoError(t, db.Model(&model.User{}).Where("email = ?", "new@example.com").Count(&count).Error)
+		assert.Equal(t, int64(1), count, "signing in again reuses the account")
+	})
+}
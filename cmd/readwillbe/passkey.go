@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/labstack/echo-contrib/v5/session"
+	"github.com/labstack/echo/v5"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"readwillbe/internal/clock"
+	mw "readwillbe/internal/middleware"
+	"readwillbe/internal/model"
+	"readwillbe/internal/repository"
+	"readwillbe/internal/service/passkey"
+)
+
+// MaxPasskeyNameLength caps the name a user gives a passkey.
+const MaxPasskeyNameLength = 64
+
+var errPasskeyExpired = errors.New("passkey request expired, please try again")
+
+// hasPasskeys reports whether the user has registered any passkeys, treating
+// a failed lookup as none.
+func hasPasskeys(tx *gorm.DB, userID uint) bool {
+	n, err := repository.CountPasskeys(tx, userID)
+	if err != nil {
+		logrus.Errorf("Failed to count passkeys for user %d: %v", userID, err)
+	}
+	return n > 0
+}
+
+// passkeyUser loads the user with the given ID and their passkeys.
+func passkeyUser(tx *gorm.DB, userID uint) (passkey.User, error) {
+	user, err := repository.GetUserByID(tx, userID)
+	if err != nil {
+		return passkey.User{}, err
+	}
+	user.Passkeys, err = repository.ListPasskeys(tx, userID)
+	return passkey.User{User: user}, err
+}
+
+// storeCeremony keeps a WebAuthn ceremony's session data on the session
+// under key until the browser responds.
+func storeCeremony(c *echo.Context, cfg model.Config, key string, data *webauthn.SessionData) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	sess, err := session.Get(mw.SessionKey, c)
+	if err != nil {
+		return err
+	}
+	sess.Options = mw.GetSecureSessionOptions(cfg)
+	sess.Values[key] = string(encoded)
+	return sess.Save(c.Request(), c.Response())
+}
+
+// takeCeremony removes and returns the session data stored under key, so
+// that each challenge can be answered only once.
+func takeCeremony(c *echo.Context, key string) (webauthn.SessionData, error) {
+	var data webauthn.SessionData
+	sess, err := session.Get(mw.SessionKey, c)
+	if err != nil {
+		return data, err
+	}
+	encoded, ok := sess.Values[key].(string)
+	if !ok {
+		return data, errPasskeyExpired
+	}
+	delete(sess.Values, key)
+	if err := sess.Save(c.Request(), c.Response()); err != nil {
+		return data, err
+	}
+	return data, json.Unmarshal([]byte(encoded), &data)
+}
+
+func passkeyError(c *echo.Context, status int, message string) error {
+	return c.JSON(status, map[string]string{"error": message})
+}
+
+// recordPasskeyUse saves the counter and flags from a passkey sign-in,
+// rejecting it if the counter suggests the authenticator has been cloned.
+func recordPasskeyUse(tx *gorm.DB, clk clock.Clock, credential *webauthn.Credential) error {
+	if credential.Authenticator.CloneWarning {
+		return errors.New("passkey signature counter went backwards")
+	}
+	return repository.RecordPasskeyUse(tx, credential.ID, credential.Authenticator.SignCount,
+		uint8(credential.Flags.ProtocolValue()), clk.Now())
+}
+
+func passkeyRegistrationOptions(cfg model.Config, db *gorm.DB, wa *webauthn.WebAuthn) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		sessionUser, ok := mw.GetSessionUser(c)
+		if !ok {
+			return passkeyError(c, http.StatusUnauthorized, "unauthorized")
+		}
+
+		user, err := passkeyUser(db.WithContext(c.Request().Context()), sessionUser.ID)
+		if err != nil {
+			return passkeyError(c, http.StatusInternalServerError, "failed to load passkeys")
+		}
+
+		exclude := webauthn.Credentials(user.WebAuthnCredentials()).CredentialDescriptors()
+		options, data, err := wa.BeginRegistration(user,
+			webauthn.WithExclusions(exclude),
+			webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementPreferred),
+		)
+		if err != nil {
+			logrus.Errorf("Failed to begin passkey registration for user %d: %v", user.ID, err)
+			return passkeyError(c, http.StatusInternalServerError, "failed to start passkey registration")
+		}
+		if err := storeCeremony(c, cfg, mw.SessionPasskeyRegistrationKey, data); err != nil {
+			return passkeyError(c, http.StatusInternalServerError, "failed to start passkey registration")
+		}
+
+		return c.JSON(http.StatusOK, options)
+	}
+}
+
+// registerPasskey checks the browser's response to the registration options
+// and stores the new passkey under the name query parameter.
+func registerPasskey(cfg model.Config, db *gorm.DB, clk clock.Clock, wa *webauthn.WebAuthn) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		sessionUser, ok := mw.GetSessionUser(c)
+		if !ok {
+			return passkeyError(c, http.StatusUnauthorized, "unauthorized")
+		}
+
+		data, err := takeCeremony(c, mw.SessionPasskeyRegistrationKey)
+		if err != nil {
+			return passkeyError(c, http.StatusBadRequest, errPasskeyExpired.Error())
+		}
+		response, err := protocol.ParseCredentialCreationResponseBody(c.Request().Body)
+		if err != nil {
+			return passkeyError(c, http.StatusBadRequest, "invalid passkey response")
+		}
+
+		tx := db.WithContext(c.Request().Context())
+		user, err := passkeyUser(tx, sessionUser.ID)
+		if err != nil {
+			return passkeyError(c, http.StatusInternalServerError, "failed to load passkeys")
+		}
+		credential, err := wa.CreateCredential(user, data, response)
+		if err != nil {
+			logrus.Warnf("Rejected passkey registration for user %d: %v", user.ID, err)
+			return passkeyError(c, http.StatusBadRequest, "the passkey could not be verified")
+		}
+		for _, existing := range user.Passkeys {
+			if bytes.Equal(existing.CredentialID, credential.ID) {
+				return passkeyError(c, http.StatusBadRequest, "that passkey is already registered")
+			}
+		}
+
+		name := strings.TrimSpace(c.QueryParam("name"))
+		if name == "" {
+			name = "Passkey"
+		}
+		if utf8.RuneCountInString(name) > MaxPasskeyNameLength {
+			name = string([]rune(name)[:MaxPasskeyNameLength])
+		}
+
+		p := passkey.FromCredential(user.ID, name, credential, clk.Now())
+		if err := repository.CreatePasskey(tx, &p); err != nil {
+			return passkeyError(c, http.StatusInternalServerError, "failed to save passkey")
+		}
+
+		return c.JSON(http.StatusOK, map[string]string{"redirect": "/account#passkeys"})
+	}
+}
+
+func deletePasskey(db *gorm.DB) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.Redirect(http.StatusFound, "/auth/sign-in")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "Invalid passkey ID")
+		}
+
+		deleted, err := repository.DeletePasskey(db.WithContext(c.Request().Context()), user.ID, uint(id))
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to remove passkey")
+		}
+		if !deleted {
+			return c.String(http.StatusNotFound, "Passkey not found")
+		}
+
+		return c.Redirect(http.StatusFound, "/account#passkeys")
+	}
+}
+
+// passkeySignInOptions starts a passwordless sign-in. No user is named: the
+// browser offers whichever passkeys it holds for this site.
+func passkeySignInOptions(cfg model.Config, wa *webauthn.WebAuthn) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		options, data, err := wa.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+		if err != nil {
+			logrus.Errorf("Failed to begin passkey sign-in: %v", err)
+			return passkeyError(c, http.StatusInternalServerError, "failed to start passkey sign-in")
+		}
+		if err := storeCeremony(c, cfg, mw.SessionPasskeyLoginKey, data); err != nil {
+			return passkeyError(c, http.StatusInternalServerError, "failed to start passkey sign-in")
+		}
+		return c.JSON(http.StatusOK, options)
+	}
+}
+
+// signInWithPasskey signs in the owner of the passkey used. The passkey
+// stands in for both the password and any second factor, since using it
+// needs the device and its screen lock.
+func signInWithPasskey(cfg model.Config, db *gorm.DB, clk clock.Clock, wa *webauthn.WebAuthn) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		data, err := takeCeremony(c, mw.SessionPasskeyLoginKey)
+		if err != nil {
+			return passkeyError(c, http.StatusBadRequest, errPasskeyExpired.Error())
+		}
+		response, err := protocol.ParseCredentialRequestResponseBody(c.Request().Body)
+		if err != nil {
+			return passkeyError(c, http.StatusBadRequest, "invalid passkey response")
+		}
+
+		tx := db.WithContext(c.Request().Context())
+		owner, credential, err := wa.ValidatePasskeyLogin(func(_, userHandle []byte) (webauthn.User, error) {
+			userID, err := passkey.UserIDFromHandle(userHandle)
+			if err != nil {
+				return nil, err
+			}
+			return passkeyUser(tx, userID)
+		}, data, response)
+		if err == nil {
+			err = recordPasskeyUse(tx, clk, credential)
+		}
+		if err != nil {
+			logrus.Warnf("Rejected passkey sign-in: %v", err)
+			return passkeyError(c, http.StatusUnauthorized, "that passkey wasn't recognised")
+		}
+
+		if err := signInSession(c, cfg, owner.(passkey.User).ID); err != nil {
+			return passkeyError(c, http.StatusInternalServerError, "internal server error")
+		}
+		return c.JSON(http.StatusOK, map[string]string{"redirect": "/"})
+	}
+}
+
+// twoFactorPasskeyOptions offers the pending user's passkeys as their second
+// factor.
+func twoFactorPasskeyOptions(cfg model.Config, db *gorm.DB, clk clock.Clock, wa *webauthn.WebAuthn) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		userID, ok := pendingTwoFactorUser(c, clk.Now())
+		if !ok {
+			return passkeyError(c, http.StatusUnauthorized, "your sign-in timed out, please try again")
+		}
+
+		user, err := passkeyUser(db.WithContext(c.Request().Context()), userID)
+		if err != nil {
+			return passkeyError(c, http.StatusInternalServerError, "internal server error")
+		}
+		if len(user.Passkeys) == 0 {
+			return passkeyError(c, http.StatusBadRequest, "you have no passkeys")
+		}
+
+		options, data, err := wa.BeginLogin(user)
+		if err != nil {
+			logrus.Errorf("Failed to begin passkey second factor for user %d: %v", user.ID, err)
+			return passkeyError(c, http.StatusInternalServerError, "failed to start passkey sign-in")
+		}
+		if err := storeCeremony(c, cfg, mw.SessionPasskeyLoginKey, data); err != nil {
+			return passkeyError(c, http.StatusInternalServerError, "failed to start passkey sign-in")
+		}
+		return c.JSON(http.StatusOK, options)
+	}
+}
+
+// verifyTwoFactorPasskey completes a sign-in held by startTwoFactor with one
+// of the user's passkeys in place of a code.
+func verifyTwoFactorPasskey(cfg model.Config, db *gorm.DB, clk clock.Clock, wa *webauthn.WebAuthn) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		userID, ok := pendingTwoFactorUser(c, clk.Now())
+		if !ok {
+			return passkeyError(c, http.StatusUnauthorized, "your sign-in timed out, please try again")
+		}
+
+		data, err := takeCeremony(c, mw.SessionPasskeyLoginKey)
+		if err != nil {
+			return passkeyError(c, http.StatusBadRequest, errPasskeyExpired.Error())
+		}
+		response, err := protocol.ParseCredentialRequestResponseBody(c.Request().Body)
+		if err != nil {
+			return passkeyError(c, http.StatusBadRequest, "invalid passkey response")
+		}
+
+		tx := db.WithContext(c.Request().Context())
+		user, err := passkeyUser(tx, userID)
+		if err != nil {
+			return passkeyError(c, http.StatusInternalServerError, "internal server error")
+		}
+		credential, err := wa.ValidateLogin(user, data, response)
+		if err == nil {
+			err = recordPasskeyUse(tx, clk, credential)
+		}
+		if err != nil {
+			logrus.Warnf("Rejected passkey second factor for user %d: %v", user.ID, err)
+			locked, lockErr := failTwoFactorAttempt(c)
+			if lockErr != nil {
+				return passkeyError(c, http.StatusInternalServerError, "internal server error")
+			}
+			if locked {
+				return passkeyError(c, http.StatusUnauthorized, errTooManyAttempts.Error())
+			}
+			return passkeyError(c, http.StatusUnauthorized, "that passkey wasn't recognised")
+		}
+
+		if err := signInSession(c, cfg, user.ID); err != nil {
+			return passkeyError(c, http.StatusInternalServerError, "internal server error")
+		}
+		return c.JSON(http.StatusOK, map[string]string{"redirect": "/"})
+	}
+}
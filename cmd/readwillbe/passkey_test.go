@@ -0,0 +1,343 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"readwillbe/internal/clock"
+	mw "readwillbe/internal/middleware"
+	"readwillbe/internal/model"
+	"readwillbe/internal/service/passkey"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/labstack/echo/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// softAuthenticator is a software WebAuthn authenticator holding a single
+// P-256 passkey, answering options the way a browser and device would.
+type softAuthenticator struct {
+	rpID         string
+	origin       string
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T, cfg model.Config) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	credentialID := make([]byte, 16)
+	_, err = rand.Read(credentialID)
+	require.NoError(t, err)
+	return &softAuthenticator{rpID: cfg.Hostname, origin: "https://" + cfg.Hostname, key: key, credentialID: credentialID}
+}
+
+var b64url = base64.RawURLEncoding
+
+func (a *softAuthenticator) authenticatorData(flags protocol.AuthenticatorFlags, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	data := append(rpIDHash[:], byte(flags))
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+func (a *softAuthenticator) clientData(t *testing.T, ceremony protocol.CeremonyType, challenge string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{"type": ceremony, "challenge": challenge, "origin": a.origin})
+	require.NoError(t, err)
+	return data
+}
+
+// create answers registration options with a new credential.
+func (a *softAuthenticator) create(t *testing.T, options []byte) []byte {
+	t.Helper()
+	var creation struct {
+		PublicKey struct {
+			Challenge string `json:"challenge"`
+			User      struct {
+				ID string `json:"id"`
+			} `json:"user"`
+		} `json:"publicKey"`
+	}
+	require.NoError(t, json.Unmarshal(options, &creation))
+	handle, err := b64url.DecodeString(creation.PublicKey.User.ID)
+	require.NoError(t, err)
+	a.userHandle = handle
+
+	publicKey, err := a.key.PublicKey.ECDH()
+	require.NoError(t, err)
+	point := publicKey.Bytes()
+	coseKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: point[1:33],
+		YCoord: point[33:],
+	})
+	require.NoError(t, err)
+
+	attested := make([]byte, 16) // AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, coseKey...)
+	flags := protocol.FlagUserPresent | protocol.FlagUserVerified | protocol.FlagAttestedCredentialData
+	attestation, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authenticatorData(flags, attested),
+	})
+	require.NoError(t, err)
+
+	body, err := json.Marshal(map[string]any{
+		"id":    b64url.EncodeToString(a.credentialID),
+		"rawId": b64url.EncodeToString(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    b64url.EncodeToString(a.clientData(t, protocol.CreateCeremony, creation.PublicKey.Challenge)),
+			"attestationObject": b64url.EncodeToString(attestation),
+			"transports":        []string{"internal"},
+		},
+	})
+	require.NoError(t, err)
+	return body
+}
+
+// get answers sign-in options by signing the challenge.
+func (a *softAuthenticator) get(t *testing.T, options []byte) []byte {
+	t.Helper()
+	var assertion struct {
+		PublicKey struct {
+			Challenge string `json:"challenge"`
+		} `json:"publicKey"`
+	}
+	require.NoError(t, json.Unmarshal(options, &assertion))
+
+	a.signCount++
+	authData := a.authenticatorData(protocol.FlagUserPresent|protocol.FlagUserVerified, nil)
+	clientData := a.clientData(t, protocol.AssertCeremony, assertion.PublicKey.Challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(t, err)
+
+	body, err := json.Marshal(map[string]any{
+		"id":    b64url.EncodeToString(a.credentialID),
+		"rawId": b64url.EncodeToString(a.credentialID),
+		"type":  "public-key",
+		"response": map[string]any{
+			"clientDataJSON":    b64url.EncodeToString(clientData),
+			"authenticatorData": b64url.EncodeToString(authData),
+			"signature":         b64url.EncodeToString(signature),
+			"userHandle":        b64url.EncodeToString(a.userHandle),
+		},
+	})
+	require.NoError(t, err)
+	return body
+}
+
+func (s *sessionClient) postJSON(t *testing.T, handler echo.HandlerFunc, target string, body []byte, user *model.User) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	return s.do(t, handler, req, user)
+}
+
+// registerTestPasskey runs the registration ceremony for user with auth.
+func registerTestPasskey(t *testing.T, cfg model.Config, db *gorm.DB, clk clock.Clock, wa *webauthn.WebAuthn, user *model.User, auth *softAuthenticator, name string) {
+	t.Helper()
+	client := newSessionClient()
+	rec := client.postJSON(t, passkeyRegistrationOptions(cfg, db, wa), "/account/passkeys/options", nil, user)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	rec = client.postJSON(t, registerPasskey(cfg, db, clk, wa), "/account/passkeys?name="+url.QueryEscape(name), auth.create(t, rec.Body.Bytes()), user)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.JSONEq(t, `{"redirect": "/account#passkeys"}`, rec.Body.String())
+}
+
+func newTestWebAuthn(t *testing.T) (model.Config, *webauthn.WebAuthn) {
+	t.Helper()
+	cfg := model.Config{Hostname: "readwillbe.example"}
+	wa, err := passkey.New(cfg)
+	require.NoError(t, err)
+	return cfg, wa
+}
+
+func TestPasskeySignIn(t *testing.T) {
+	db := setupTestDB(t)
+	cfg, wa := newTestWebAuthn(t)
+	clk := clock.Fixed(time.Date(2026, time.May, 1, 8, 0, 0, 0, time.UTC))
+	user := createTestUser(t, db, "passkey@example.com", "correctpassword123")
+	auth := newSoftAuthenticator(t, cfg)
+
+	registerTestPasskey(t, cfg, db, clk, wa, user, auth, "  My laptop ")
+
+	var stored model.Passkey
+	require.NoError(t, db.First(&stored, "user_id = ?", user.ID).Error)
+	assert.Equal(t, "My laptop", stored.Name)
+	assert.Equal(t, auth.credentialID, stored.CredentialID)
+	assert.Equal(t, "internal", stored.Transports)
+
+	t.Run("registering the same passkey again is refused", func(t *testing.T) {
+		client := newSessionClient()
+		rec := client.postJSON(t, passkeyRegistrationOptions(cfg, db, wa), "/account/passkeys/options", nil, user)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), b64url.EncodeToString(auth.credentialID), "existing passkeys are excluded")
+
+		rec = client.postJSON(t, registerPasskey(cfg, db, clk, wa), "/account/passkeys", auth.create(t, rec.Body.Bytes()), user)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "already registered")
+	})
+
+	t.Run("account page", func(t *testing.T) {
+		rec := postForm(t, accountHandler(cfg, db, clk), "/account", nil, user)
+		assert.Equal(t, 200, rec.Code)
+		assert.Contains(t, rec.Body.String(), "My laptop")
+	})
+
+	t.Run("passwordless", func(t *testing.T) {
+		client := newSessionClient()
+		rec := client.postJSON(t, passkeySignInOptions(cfg, wa), "/auth/passkey/options", nil, nil)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		response := auth.get(t, rec.Body.Bytes())
+		rec = client.postJSON(t, signInWithPasskey(cfg, db, clk, wa), "/auth/passkey", response, nil)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.JSONEq(t, `{"redirect": "/"}`, rec.Body.String())
+		assert.Equal(t, user.ID, client.values(t)[mw.SessionUserIDKey])
+
+		var used model.Passkey
+		require.NoError(t, db.First(&used, stored.ID).Error)
+		assert.Equal(t, uint32(1), used.SignCount)
+		require.NotNil(t, used.LastUsedAt)
+
+		rec = client.postJSON(t, signInWithPasskey(cfg, db, clk, wa), "/auth/passkey", response, nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, "each challenge can be answered once")
+	})
+
+	t.Run("unknown passkey", func(t *testing.T) {
+		impostor := newSoftAuthenticator(t, cfg)
+		impostor.userHandle = auth.userHandle
+
+		client := newSessionClient()
+		rec := client.postJSON(t, passkeySignInOptions(cfg, wa), "/auth/passkey/options", nil, nil)
+		rec = client.postJSON(t, signInWithPasskey(cfg, db, clk, wa), "/auth/passkey", impostor.get(t, rec.Body.Bytes()), nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.NotContains(t, client.values(t), mw.SessionUserIDKey)
+	})
+
+	t.Run("cloned passkey", func(t *testing.T) {
+		clone := *auth
+		clone.signCount = 0
+
+		client := newSessionClient()
+		rec := client.postJSON(t, passkeySignInOptions(cfg, wa), "/auth/passkey/options", nil, nil)
+		rec = client.postJSON(t, signInWithPasskey(cfg, db, clk, wa), "/auth/passkey", clone.get(t, rec.Body.Bytes()), nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.NotContains(t, client.values(t), mw.SessionUserIDKey)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		other := createTestUser(t, db, "other@example.com", "correctpassword123")
+		rec := postFormWithParam(t, deletePasskey(db), stored.ID, other)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = postFormWithParam(t, deletePasskey(db), stored.ID, user)
+		assert.Equal(t, http.StatusFound, rec.Code)
+		assert.Equal(t, "/account#passkeys", rec.Header().Get("Location"))
+
+		var n int64
+		require.NoError(t, db.Model(&model.Passkey{}).Where("user_id = ?", user.ID).Count(&n).Error)
+		assert.Zero(t, n)
+	})
+}
+
+func postFormWithParam(t *testing.T, handler echo.HandlerFunc, id uint, user *model.User) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetPathValues(echo.PathValues{{Name: "id", Value: strconv.FormatUint(uint64(id), 10)}})
+	c.Set(mw.UserKey, *user)
+	require.NoError(t, handler(c))
+	return rec
+}
+
+func TestPasskeySecondFactor(t *testing.T) {
+	db := setupTestDB(t)
+	cfg, wa := newTestWebAuthn(t)
+	clk := clock.Fixed(time.Date(2026, time.May, 1, 8, 0, 0, 0, time.UTC))
+	user := createTestUser(t, db, "second@example.com", "correctpassword123")
+	enableTestTwoFactor(t, db, user, clk.Now())
+	auth := newSoftAuthenticator(t, cfg)
+	registerTestPasskey(t, cfg, db, clk, wa, user, auth, "Phone")
+
+	signInForm := url.Values{"email": {user.Email}, "password": {"correctpassword123"}}
+
+	t.Run("no pending sign-in", func(t *testing.T) {
+		client := newSessionClient()
+		rec := client.postJSON(t, twoFactorPasskeyOptions(cfg, db, clk, wa), "/auth/two-factor/passkey/options", nil, nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("offered on the two-factor page", func(t *testing.T) {
+		client := newSessionClient()
+		client.post(t, signInWithEmailAndPassword(db, cfg, clk), "/auth/sign-in", signInForm, nil)
+		rec := client.get(t, twoFactorForm(cfg, db, clk), "/auth/two-factor", nil)
+		assert.Equal(t, 200, rec.Code)
+		assert.Contains(t, rec.Body.String(), "verifyWithPasskey()")
+	})
+
+	t.Run("passkey after password", func(t *testing.T) {
+		client := newSessionClient()
+		rec := client.post(t, signInWithEmailAndPassword(db, cfg, clk), "/auth/sign-in", signInForm, nil)
+		require.Equal(t, "/auth/two-factor", rec.Header().Get("Location"))
+
+		rec = client.postJSON(t, twoFactorPasskeyOptions(cfg, db, clk, wa), "/auth/two-factor/passkey/options", nil, nil)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.Contains(t, rec.Body.String(), b64url.EncodeToString(auth.credentialID))
+
+		rec = client.postJSON(t, verifyTwoFactorPasskey(cfg, db, clk, wa), "/auth/two-factor/passkey", auth.get(t, rec.Body.Bytes()), nil)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		values := client.values(t)
+		assert.Equal(t, user.ID, values[mw.SessionUserIDKey])
+		assert.NotContains(t, values, mw.SessionPendingUserIDKey)
+	})
+
+	t.Run("another user's passkey", func(t *testing.T) {
+		other := createTestUser(t, db, "other-second@example.com", "correctpassword123")
+		otherAuth := newSoftAuthenticator(t, cfg)
+		registerTestPasskey(t, cfg, db, clk, wa, other, otherAuth, "Other")
+
+		client := newSessionClient()
+		client.post(t, signInWithEmailAndPassword(db, cfg, clk), "/auth/sign-in", signInForm, nil)
+		rec := client.postJSON(t, twoFactorPasskeyOptions(cfg, db, clk, wa), "/auth/two-factor/passkey/options", nil, nil)
+		rec = client.postJSON(t, verifyTwoFactorPasskey(cfg, db, clk, wa), "/auth/two-factor/passkey", otherAuth.get(t, rec.Body.Bytes()), nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		values := client.values(t)
+		assert.NotContains(t, values, mw.SessionUserIDKey)
+		assert.Equal(t, 1, values[mw.SessionPendingAttemptsKey], "a wrong passkey counts as a failed attempt")
+	})
+}
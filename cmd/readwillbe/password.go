@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo-contrib/v5/session"
+	"github.com/labstack/echo/v5"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"readwillbe/internal/cache"
+	"readwillbe/internal/clock"
+	mw "readwillbe/internal/middleware"
+	"readwillbe/internal/model"
+	"readwillbe/internal/repository"
+	emailservice "readwillbe/internal/service/email"
+	"readwillbe/internal/views"
+)
+
+// MaxPasswordResetsPerHour caps the reset emails sent for one account, on top
+// of the per-client rate limit on the route.
+const MaxPasswordResetsPerHour = 3
+
+// validatePassword checks a new password against the length limits.
+func validatePassword(password string) error {
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return fmt.Errorf("password must be between %d and %d characters", MinPasswordLength, MaxPasswordLength)
+	}
+	return nil
+}
+
+// readNewPassword validates the new_password and confirm_password form
+// fields and returns the bcrypt hash of the new password.
+func readNewPassword(c *echo.Context) (string, error) {
+	password := c.FormValue("new_password")
+	if err := validatePassword(password); err != nil {
+		return "", err
+	}
+	if password != c.FormValue("confirm_password") {
+		return "", fmt.Errorf("new passwords do not match")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), BcryptCost)
+	if err != nil {
+		return "", fmt.Errorf("internal server error")
+	}
+	return string(hash), nil
+}
+
+func changePassword(cfg model.Config, db *gorm.DB, clk clock.Clock, userCache *cache.UserCache) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.Redirect(http.StatusFound, "/auth/sign-in")
+		}
+
+		current := c.FormValue("current_password")
+		if user.Password == "" || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(current)) != nil {
+			err := fmt.Errorf("current password is incorrect")
+			return renderAccount(c, cfg, db, clk, user, 422, views.AccountProps{PasswordErr: err})
+		}
+
+		hash, err := readNewPassword(c)
+		if err != nil {
+			return renderAccount(c, cfg, db, clk, user, 422, views.AccountProps{PasswordErr: err})
+		}
+
+		tx := db.WithContext(c.Request().Context())
+		if err := repository.UpdatePassword(tx, user.ID, hash); err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to change password")
+		}
+		userCache.Invalidate(user.ID)
+		// Sign out every other browser, in case the old password leaked.
+		if sess, err := session.Get(mw.SessionKey, c); err == nil {
+			if _, err := repository.RevokeOtherSessions(tx, user.ID, sess.ID); err != nil {
+				logrus.Errorf("Failed to revoke other sessions of user %d: %v", user.ID, err)
+			}
+		}
+
+		return c.Redirect(http.StatusFound, "/account?password=changed#password")
+	}
+}
+
+func forgotPassword(cfg model.Config) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		csrf, _ := c.Get("csrf").(string)
+		return render(c, 200, views.ForgotPasswordPage(cfg, csrf, false, nil))
+	}
+}
+
+// requestPasswordReset emails a reset link to the account with the given
+// email. The response is the same whether or not the account exists, so the
+// form cannot be used to find out who has signed up.
+func requestPasswordReset(cfg model.Config, db *gorm.DB, clk clock.Clock, sender emailservice.Service) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		csrf, _ := c.Get("csrf").(string)
+
+		address, err := mail.ParseAddress(strings.TrimSpace(c.FormValue("email")))
+		if err != nil {
+			return render(c, 422, views.ForgotPasswordPage(cfg, csrf, false, fmt.Errorf("invalid email address")))
+		}
+
+		tx := db.WithContext(c.Request().Context())
+		user, err := repository.GetUserByEmail(tx, address.Address)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+		case err != nil:
+			logrus.Errorf("Failed to look up user for password reset: %v", err)
+		default:
+			if err := sendPasswordReset(cfg, tx, clk, sender, user); err != nil {
+				logrus.Errorf("Failed to issue password reset for user %d: %v", user.ID, err)
+			}
+		}
+
+		return render(c, 200, views.ForgotPasswordPage(cfg, csrf, true, nil))
+	}
+}
+
+// sendPasswordReset issues a reset token for user and emails it, unless the
+// account has already had MaxPasswordResetsPerHour of them.
+func sendPasswordReset(cfg model.Config, tx *gorm.DB, clk clock.Clock, sender emailservice.Service, user model.User) error {
+	now := clk.Now()
+	recent, err := repository.CountPasswordResets(tx, user.ID, now.Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	if recent >= MaxPasswordResetsPerHour {
+		logrus.Warnf("Not sending password reset for user %d: %d sent in the last hour", user.ID, recent)
+		return nil
+	}
+
+	token, err := repository.CreatePasswordReset(tx, user.ID, now)
+	if err != nil {
+		return err
+	}
+	resetURL := fmt.Sprintf("https://%s/auth/reset-password?token=%s", cfg.Hostname, url.QueryEscape(token))
+
+	// Send in the background so that how long the request takes does not
+	// give away whether the account exists.
+	go func() {
+		if err := sender.SendPasswordReset(user, resetURL); err != nil {
+			logrus.Errorf("Failed to send password reset email: %v", err)
+		}
+	}()
+	return nil
+}
+
+func resetPasswordForm(cfg model.Config, db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		csrf, _ := c.Get("csrf").(string)
+
+		token := c.QueryParam("token")
+		if _, err := repository.GetPasswordReset(db.WithContext(c.Request().Context()), token, clk.Now()); err != nil {
+			if !errors.Is(err, repository.ErrInvalidResetToken) {
+				return c.String(http.StatusInternalServerError, "Failed to check reset link")
+			}
+			return render(c, 400, views.ResetPasswordPage(cfg, csrf, "", false, err))
+		}
+
+		return render(c, 200, views.ResetPasswordPage(cfg, csrf, token, false, nil))
+	}
+}
+
+func resetPassword(cfg model.Config, db *gorm.DB, clk clock.Clock, userCache *cache.UserCache) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		csrf, _ := c.Get("csrf").(string)
+		token := c.FormValue("token")
+
+		hash, err := readNewPassword(c)
+		if err != nil {
+			return render(c, 422, views.ResetPasswordPage(cfg, csrf, token, false, err))
+		}
+
+		userID, err := repository.ResetPassword(db.WithContext(c.Request().Context()), token, hash, clk.Now())
+		if err != nil {
+			if !errors.Is(err, repository.ErrInvalidResetToken) {
+				return c.String(http.StatusInternalServerError, "Failed to reset password")
+			}
+			return render(c, 400, views.ResetPasswordPage(cfg, csrf, "", false, err))
+		}
+		userCache.Invalidate(userID)
+
+		return render(c, 200, views.ResetPasswordPage(cfg, csrf, "", true, nil))
+	}
+}
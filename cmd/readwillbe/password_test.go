@@ -0,0 +1,212 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"readwillbe/internal/cache"
+	"readwillbe/internal/clock"
+	mw "readwillbe/internal/middleware"
+	"readwillbe/internal/model"
+	"readwillbe/internal/repository"
+
+	"github.com/labstack/echo/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type sentEmail struct {
+	to  string
+	url string
+}
+
+// fakeMailer records the password reset and verification emails it is asked
+// to send.
+type fakeMailer struct {
+	resets        chan sentEmail
+	verifications chan sentEmail
+}
+
+func newFakeMailer() *fakeMailer {
+	return &fakeMailer{resets: make(chan sentEmail, 10), verifications: make(chan sentEmail, 10)}
+}
+
+func (f *fakeMailer) SendDailyDigest(model.User, []model.Reading, time.Time, string) error {
+	return nil
+}
+
+func (f *fakeMailer) SendTestEmail(string, string) error {
+	return nil
+}
+
+func (f *fakeMailer) SendPasswordReset(user model.User, resetURL string) error {
+	f.resets <- sentEmail{to: user.Email, url: resetURL}
+	return nil
+}
+
+func (f *fakeMailer) SendEmailVerification(_ model.User, to, verifyURL string) error {
+	f.verifications <- sentEmail{to: to, url: verifyURL}
+	return nil
+}
+
+// receiveEmail waits for the background send of an email on sent.
+func receiveEmail(t *testing.T, sent chan sentEmail) sentEmail {
+	t.Helper()
+	select {
+	case email := <-sent:
+		return email
+	case <-time.After(time.Second):
+		t.Fatal("no email was sent")
+		return sentEmail{}
+	}
+}
+
+// emailToken extracts the token query parameter from an emailed link.
+func emailToken(t *testing.T, link string) string {
+	t.Helper()
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	token := u.Query().Get("token")
+	require.NotEmpty(t, token)
+	return token
+}
+
+func postForm(t *testing.T, handler echo.HandlerFunc, target string, form url.Values, user *model.User) *httptest.ResponseRecorder {
+	t.Helper()
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	if user != nil {
+		c.Set(mw.UserKey, *user)
+	}
+	require.NoError(t, handler(c))
+	return rec
+}
+
+func TestChangePassword(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "change@example.com", "old-password-123")
+	userCache := cache.NewUserCache(time.Minute, time.Minute)
+	handler := changePassword(model.Config{}, db, clock.System, userCache)
+
+	for name, form := range map[string]url.Values{
+		"wrong current password": {"current_password": {"not-my-password"}, "new_password": {"new-password-456"}, "confirm_password": {"new-password-456"}},
+		"too short":              {"current_password": {"old-password-123"}, "new_password": {"short"}, "confirm_password": {"short"}},
+		"mismatch":               {"current_password": {"old-password-123"}, "new_password": {"new-password-456"}, "confirm_password": {"new-password-789"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := postForm(t, handler, "/account/password", form, user)
+			assert.Equal(t, 422, rec.Code)
+		})
+	}
+
+	t.Run("changed", func(t *testing.T) {
+		userCache.Set(*user)
+		form := url.Values{"current_password": {"old-password-123"}, "new_password": {"new-password-456"}, "confirm_password": {"new-password-456"}}
+		rec := postForm(t, handler, "/account/password", form, user)
+		assert.Equal(t, http.StatusFound, rec.Code)
+		assert.Equal(t, "/account?password=changed#password", rec.Header().Get("Location"))
+
+		var updated model.User
+		require.NoError(t, db.First(&updated, user.ID).Error)
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(updated.Password), []byte("new-password-456")))
+
+		_, found := userCache.Get(user.ID)
+		assert.False(t, found, "a password change should evict the cached user")
+	})
+}
+
+func TestPasswordReset(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "forgot@example.com", "old-password-123")
+	cfg := model.Config{Hostname: "readwillbe.example"}
+	clk := clock.Fixed(time.Date(2026, time.May, 1, 8, 0, 0, 0, time.UTC))
+	mailer := newFakeMailer()
+	request := requestPasswordReset(cfg, db, clk, mailer)
+
+	t.Run("unknown email looks the same", func(t *testing.T) {
+		rec := postForm(t, request, "/auth/forgot-password", url.Values{"email": {"nobody@example.com"}}, nil)
+		assert.Equal(t, 200, rec.Code)
+		assert.Contains(t, rec.Body.String(), "If an account exists")
+		assert.Empty(t, mailer.resets)
+	})
+
+	var sent sentEmail
+	t.Run("email sent", func(t *testing.T) {
+		rec := postForm(t, request, "/auth/forgot-password", url.Values{"email": {" forgot@example.com "}}, nil)
+		assert.Equal(t, 200, rec.Code)
+		assert.Contains(t, rec.Body.String(), "If an account exists")
+
+		sent = receiveEmail(t, mailer.resets)
+		assert.Equal(t, user.Email, sent.to)
+		assert.True(t, strings.HasPrefix(sent.url, "https://readwillbe.example/auth/reset-password?token="), sent.url)
+	})
+
+	token := emailToken(t, sent.url)
+
+	t.Run("form", func(t *testing.T) {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/auth/reset-password?token="+url.QueryEscape(token), nil)
+		rec := httptest.NewRecorder()
+		require.NoError(t, resetPasswordForm(cfg, db, clk)(e.NewContext(req, rec)))
+		assert.Equal(t, 200, rec.Code)
+		assert.Contains(t, rec.Body.String(), `name="token" value="`+token+`"`)
+
+		req = httptest.NewRequest(http.MethodGet, "/auth/reset-password?token=bogus", nil)
+		rec = httptest.NewRecorder()
+		require.NoError(t, resetPasswordForm(cfg, db, clk)(e.NewContext(req, rec)))
+		assert.Equal(t, 400, rec.Code)
+		assert.Contains(t, rec.Body.String(), "invalid or has expired")
+	})
+
+	userCache := cache.NewUserCache(time.Minute, time.Minute)
+	reset := resetPassword(cfg, db, clk, userCache)
+
+	t.Run("mismatch keeps the token", func(t *testing.T) {
+		form := url.Values{"token": {token}, "new_password": {"new-password-456"}, "confirm_password": {"other-password-789"}}
+		rec := postForm(t, reset, "/auth/reset-password", form, nil)
+		assert.Equal(t, 422, rec.Code)
+		assert.Contains(t, rec.Body.String(), "do not match")
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		userCache.Set(*user)
+		form := url.Values{"token": {token}, "new_password": {"new-password-456"}, "confirm_password": {"new-password-456"}}
+		rec := postForm(t, reset, "/auth/reset-password", form, nil)
+		assert.Equal(t, 200, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Your password has been reset")
+
+		var updated model.User
+		require.NoError(t, db.First(&updated, user.ID).Error)
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(updated.Password), []byte("new-password-456")))
+		_, found := userCache.Get(user.ID)
+		assert.False(t, found)
+
+		rec = postForm(t, reset, "/auth/reset-password", form, nil)
+		assert.Equal(t, 400, rec.Code, "the token is single use")
+	})
+
+	t.Run("per-account limit", func(t *testing.T) {
+		issued, err := repository.CountPasswordResets(db, user.ID, clk.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		for i := issued; i < MaxPasswordResetsPerHour+1; i++ {
+			rec := postForm(t, request, "/auth/forgot-password", url.Values{"email": {user.Email}}, nil)
+			assert.Equal(t, 200, rec.Code)
+		}
+		for i := issued; i < MaxPasswordResetsPerHour; i++ {
+			receiveEmail(t, mailer.resets)
+		}
+
+		issued, err = repository.CountPasswordResets(db, user.ID, clk.Now().Add(-time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, int64(MaxPasswordResetsPerHour), issued)
+		assert.Empty(t, mailer.resets)
+	})
+}
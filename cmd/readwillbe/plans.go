@@ -1,22 +1,26 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/labstack/echo-contrib/v5/session"
 	"github.com/labstack/echo/v5"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
-	"github.com/spf13/afero"
 	"gorm.io/gorm"
 
+	"readwillbe/internal/clock"
 	mw "readwillbe/internal/middleware"
 	"readwillbe/internal/model"
+	"readwillbe/internal/repository"
 	csvservice "readwillbe/internal/service/csv"
+	"readwillbe/internal/service/jobs"
 	"readwillbe/internal/views"
 )
 
@@ -26,7 +30,7 @@ const (
 	MaxContentLength = 2000
 )
 
-func plansListHandler(cfg model.Config, db *gorm.DB) echo.HandlerFunc {
+func plansListHandler(cfg model.Config, db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
 	return func(c *echo.Context) error {
 		user, ok := mw.GetSessionUser(c)
 		if !ok {
@@ -46,7 +50,8 @@ func plansListHandler(cfg model.Config, db *gorm.DB) echo.HandlerFunc {
 			}
 		}
 
-		return render(c, 200, views.PlansList(cfg, &user, inProgressPlans, completedPlans))
+		now := clock.NowIn(clk, user.Location())
+		return render(c, 200, views.PlansList(cfg, &user, now, inProgressPlans, completedPlans))
 	}
 }
 
@@ -318,7 +323,7 @@ func deleteDraft() echo.HandlerFunc {
 	}
 }
 
-func createManualPlan(cfg model.Config, db *gorm.DB) echo.HandlerFunc {
+func createManualPlan(cfg model.Config, db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
 	return func(c *echo.Context) error {
 		user, ok := mw.GetSessionUser(c)
 		if !ok {
@@ -357,24 +362,31 @@ func createManualPlan(cfg model.Config, db *gorm.DB) echo.HandlerFunc {
 			return render(c, 422, views.ManualPlanCreate(cfg, &user, title, draftReadings, fmt.Errorf("at least one reading is required")))
 		}
 
+		start, err := readStartDate(c, user, clk)
+		if err != nil {
+			return render(c, 422, views.ManualPlanCreate(cfg, &user, title, draftReadings, err))
+		}
+
 		readings := make([]model.Reading, 0, len(formReadings))
 		for _, mr := range formReadings {
-			parsedDate, dateType, parseErr := csvservice.ParseDate(mr.Date)
+			parsedDate, dateType, offset, parseErr := csvservice.ResolveDate(mr.Date, start)
 			if parseErr != nil {
 				return render(c, 422, views.ManualPlanCreate(cfg, &user, title, draftReadings, errors.Wrap(parseErr, fmt.Sprintf("invalid date: %s", mr.Date))))
 			}
 			readings = append(readings, model.Reading{
-				Date:     parsedDate,
-				DateType: dateType,
-				Content:  mr.Content,
-				Status:   model.StatusPending,
+				Date:        parsedDate,
+				DateType:    dateType,
+				StartOffset: offset,
+				Content:     mr.Content,
+				Status:      model.StatusPending,
 			})
 		}
 
 		plan := model.Plan{
-			Title:  title,
-			UserID: user.ID,
-			Status: "active",
+			Title:     title,
+			UserID:    user.ID,
+			Status:    "active",
+			StartDate: &start,
 		}
 
 		txErr := db.WithContext(c.Request().Context()).Transaction(func(tx *gorm.DB) error {
@@ -401,128 +413,146 @@ func createManualPlan(cfg model.Config, db *gorm.DB) echo.HandlerFunc {
 	}
 }
 
-func createPlan(fs afero.Fs, db *gorm.DB) echo.HandlerFunc {
+// readPlanUpload validates the plan title and returns the CSV content,
+// either from the "csv" file upload or, when confirming a preview, from the
+// "csv_data" field the preview page carries forward.
+func readPlanUpload(c *echo.Context) (string, []byte, error) {
+	title := c.FormValue("title")
+	if title == "" {
+		return "", nil, fmt.Errorf("plan title is required")
+	}
+	if len(title) > MaxTitleLength {
+		return "", nil, fmt.Errorf("plan title must be less than %d characters", MaxTitleLength)
+	}
+	if csvservice.IsFormulaInjection(title) {
+		return "", nil, fmt.Errorf("title cannot start with formula characters (=, +, -, @)")
+	}
+
+	if csvData := c.FormValue("csv_data"); csvData != "" {
+		if len(csvData) > MaxCSVFileSize {
+			return "", nil, fmt.Errorf("CSV file must be less than 10MB")
+		}
+		return title, []byte(csvData), nil
+	}
+
+	file, err := c.FormFile("csv")
+	if err != nil {
+		return "", nil, fmt.Errorf("CSV file is required")
+	}
+
+	if file.Size > MaxCSVFileSize {
+		return "", nil, fmt.Errorf("CSV file must be less than 10MB")
+	}
+
+	contentType := file.Header.Get("Content-Type")
+	validCSVTypes := map[string]bool{
+		"text/csv":                 true,
+		"application/csv":          true,
+		"text/plain":               true,
+		"application/vnd.ms-excel": true,
+		"application/octet-stream": true,
+		"":                         true, // Allow empty content-type from multipart forms
+	}
+	if !validCSVTypes[contentType] {
+		return "", nil, fmt.Errorf("invalid file type: must be a CSV file")
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return "", nil, errors.Wrap(err, "Failed to open file")
+	}
+	defer func() { _ = src.Close() }()
+
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "Failed to read CSV")
+	}
+	return title, data, nil
+}
+
+// readStartDate returns the day chosen in the "start_date" field, which
+// relative "Day n" and "Week n" readings are counted from. It defaults to
+// today in the user's timezone.
+func readStartDate(c *echo.Context, user model.User, clk clock.Clock) (time.Time, error) {
+	value := c.FormValue("start_date")
+	if value == "" {
+		now := clock.NowIn(clk, user.Location())
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+	start, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("start date must be in YYYY-MM-DD format")
+	}
+	return start, nil
+}
+
+// previewPlan parses an uploaded CSV without saving anything and shows every
+// row, with its resolved date or its error, so the user can confirm the
+// import or go back and fix the file.
+func previewPlan(cfg model.Config, clk clock.Clock) echo.HandlerFunc {
 	return func(c *echo.Context) error {
 		user, ok := mw.GetSessionUser(c)
 		if !ok {
 			return c.Redirect(http.StatusFound, "/auth/sign-in")
 		}
 
-		title := c.FormValue("title")
-		if title == "" {
-			return render(c, 422, views.CreatePlanFormError(fmt.Errorf("plan title is required")))
-		}
-		if len(title) > MaxTitleLength {
-			return render(c, 422, views.CreatePlanFormError(fmt.Errorf("plan title must be less than %d characters", MaxTitleLength)))
-		}
-		if csvservice.IsFormulaInjection(title) {
-			return render(c, 422, views.CreatePlanFormError(fmt.Errorf("title cannot start with formula characters (=, +, -, @)")))
+		title, data, err := readPlanUpload(c)
+		if err != nil {
+			return render(c, 422, views.CreatePlanForm(cfg, &user, err))
 		}
 
-		file, err := c.FormFile("csv")
+		start, err := readStartDate(c, user, clk)
 		if err != nil {
-			return render(c, 422, views.CreatePlanFormError(fmt.Errorf("CSV file is required")))
+			return render(c, 422, views.CreatePlanForm(cfg, &user, err))
 		}
 
-		if file.Size > MaxCSVFileSize {
-			return render(c, 422, views.CreatePlanFormError(fmt.Errorf("CSV file must be less than 10MB")))
+		preview, err := csvservice.PreviewCSV(bytes.NewReader(data), start)
+		if err != nil {
+			return render(c, 422, views.CreatePlanForm(cfg, &user, err))
 		}
 
-		contentType := file.Header.Get("Content-Type")
-		validCSVTypes := map[string]bool{
-			"text/csv":                 true,
-			"application/csv":          true,
-			"text/plain":               true,
-			"application/vnd.ms-excel": true,
-			"application/octet-stream": true,
-			"":                         true, // Allow empty content-type from multipart forms
-		}
-		if !validCSVTypes[contentType] {
-			return render(c, 422, views.CreatePlanFormError(fmt.Errorf("invalid file type: must be a CSV file")))
-		}
+		return render(c, 200, views.PlanImportPreview(cfg, &user, title, string(data), start, preview))
+	}
+}
 
-		src, err := file.Open()
-		if err != nil {
-			return render(c, 422, views.CreatePlanFormError(errors.Wrap(err, "Failed to open file")))
+func createPlan(db *gorm.DB, queue *jobs.Queue, clk clock.Clock) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.Redirect(http.StatusFound, "/auth/sign-in")
 		}
-		defer func() { _ = src.Close() }()
 
-		// Create a temp file to store the CSV
-		tempFile, err := afero.TempFile(fs, "", "plan-upload-*.csv")
+		title, data, err := readPlanUpload(c)
 		if err != nil {
-			return render(c, 422, views.CreatePlanFormError(errors.Wrap(err, "Failed to create temp file")))
+			return render(c, 422, views.CreatePlanFormError(err))
 		}
-		tempPath := tempFile.Name()
-		// We don't defer fs.Remove here because the goroutine needs it.
-		// The goroutine is responsible for cleanup.
 
-		if _, err := io.Copy(tempFile, src); err != nil {
-			_ = tempFile.Close()
-			_ = fs.Remove(tempPath)
-			return render(c, 422, views.CreatePlanFormError(errors.Wrap(err, "Failed to save CSV")))
+		start, err := readStartDate(c, user, clk)
+		if err != nil {
+			return render(c, 422, views.CreatePlanFormError(err))
 		}
-		_ = tempFile.Close()
 
-		// Create plan immediately in processing state
+		// Create the plan in processing state and queue the import in the
+		// same transaction, so a plan is never left without its job.
 		plan := model.Plan{
-			Title:  title,
-			UserID: user.ID,
-			Status: "processing",
-		}
-
-		if err := db.WithContext(c.Request().Context()).Create(&plan).Error; err != nil {
-			_ = fs.Remove(tempPath)
-			return render(c, 422, views.CreatePlanFormError(errors.Wrap(err, "Failed to create plan record")))
+			Title:     title,
+			UserID:    user.ID,
+			Status:    "processing",
+			StartDate: &start,
 		}
 
-		// Process CSV in background
-		go func(p model.Plan, filePath string, fileSys afero.Fs, d *gorm.DB) {
-			defer func() {
-				if r := recover(); r != nil {
-					p.Status = "failed"
-					p.ErrorMessage = fmt.Sprintf("Panic during processing: %v", r)
-					d.Save(&p)
-				}
-				_ = fileSys.Remove(filePath)
-			}()
-
-			f, err := fileSys.Open(filePath)
-			if err != nil {
-				p.Status = "failed"
-				p.ErrorMessage = fmt.Sprintf("Failed to open CSV file: %v", err)
-				d.Save(&p)
-				return
-			}
-			defer func() { _ = f.Close() }()
-
-			readings, err := csvservice.ParseCSV(f)
-			if err != nil {
-				p.Status = "failed"
-				p.ErrorMessage = fmt.Sprintf("Failed to parse CSV: %v", err)
-				d.Save(&p)
-				return
-			}
-
-			err = d.Transaction(func(tx *gorm.DB) error {
-				for i := range readings {
-					readings[i].PlanID = p.ID
-				}
-				if txErr := tx.Create(&readings).Error; txErr != nil {
-					return txErr
-				}
-				p.Status = "active"
-				if txErr := tx.Save(&p).Error; txErr != nil {
-					return txErr
-				}
-				return nil
-			})
-
-			if err != nil {
-				p.Status = "failed"
-				p.ErrorMessage = fmt.Sprintf("Failed to save readings: %v", err)
-				d.Save(&p)
+		ctx := c.Request().Context()
+		txErr := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&plan).Error; err != nil {
+				return errors.Wrap(err, "Failed to create plan record")
 			}
-		}(plan, tempPath, fs, db)
+			_, err := jobs.EnqueueCSVImport(tx, queue, plan, data)
+			return err
+		})
+		if txErr != nil {
+			return render(c, 422, views.CreatePlanFormError(txErr))
+		}
+		queue.Notify()
 
 		return c.Redirect(http.StatusFound, "/plans")
 	}
@@ -565,6 +595,204 @@ func renamePlan(db *gorm.DB) echo.HandlerFunc {
 	}
 }
 
+func pausePlan(db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.Redirect(http.StatusFound, "/auth/sign-in")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "Invalid plan ID")
+		}
+
+		var plan model.Plan
+		if err := db.WithContext(c.Request().Context()).First(&plan, "id = ? AND user_id = ?", id, user.ID).Error; err != nil {
+			return c.String(http.StatusNotFound, "Plan not found")
+		}
+		if plan.Status != "active" {
+			return c.String(http.StatusBadRequest, "Only active plans can be paused")
+		}
+		if plan.IsPaused() {
+			return c.String(http.StatusBadRequest, "Plan is already paused")
+		}
+
+		if err := repository.PausePlan(db.WithContext(c.Request().Context()), &plan, clock.NowIn(clk, user.Location())); err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to pause plan")
+		}
+
+		return c.Redirect(http.StatusFound, "/plans")
+	}
+}
+
+func resumePlan(db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.Redirect(http.StatusFound, "/auth/sign-in")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "Invalid plan ID")
+		}
+
+		var plan model.Plan
+		if err := db.WithContext(c.Request().Context()).First(&plan, "id = ? AND user_id = ?", id, user.ID).Error; err != nil {
+			return c.String(http.StatusNotFound, "Plan not found")
+		}
+		if !plan.IsPaused() {
+			return c.String(http.StatusBadRequest, "Plan is not paused")
+		}
+
+		if _, err := repository.ResumePlan(db.WithContext(c.Request().Context()), &plan, clock.NowIn(clk, user.Location())); err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to resume plan")
+		}
+
+		return c.Redirect(http.StatusFound, "/plans")
+	}
+}
+
+// MaxShiftDays bounds how far a single shift can move a plan's readings.
+const MaxShiftDays = 366
+
+// shiftPlan moves every pending reading of a plan by a number of days or
+// weeks, forwards or, with a negative amount, backwards.
+func shiftPlan(db *gorm.DB) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.Redirect(http.StatusFound, "/auth/sign-in")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "Invalid plan ID")
+		}
+
+		var plan model.Plan
+		if err := db.WithContext(c.Request().Context()).First(&plan, "id = ? AND user_id = ?", id, user.ID).Error; err != nil {
+			return c.String(http.StatusNotFound, "Plan not found")
+		}
+
+		amount, err := strconv.Atoi(c.FormValue("amount"))
+		if err != nil || amount == 0 {
+			return c.String(http.StatusBadRequest, "Amount must be a non-zero whole number")
+		}
+
+		days := amount
+		switch c.FormValue("unit") {
+		case "days":
+		case "weeks":
+			days = amount * 7
+		default:
+			return c.String(http.StatusBadRequest, "Unit must be days or weeks")
+		}
+		if days > MaxShiftDays || days < -MaxShiftDays {
+			return c.String(http.StatusBadRequest, fmt.Sprintf("Readings can be shifted by at most %d days", MaxShiftDays))
+		}
+
+		if _, err := repository.ShiftPendingReadings(db.WithContext(c.Request().Context()), plan.ID, days); err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to shift readings")
+		}
+
+		return c.Redirect(http.StatusFound, "/plans")
+	}
+}
+
+// readCatchUpOptions reads the catch-up window length and mode, defaulting to
+// spreading the backlog over a week.
+func readCatchUpOptions(c *echo.Context) (int, repository.CatchUpMode, error) {
+	days := 7
+	if value := c.FormValue("days"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > repository.MaxCatchUpDays {
+			return 0, "", fmt.Errorf("days must be a whole number from 1 to %d", repository.MaxCatchUpDays)
+		}
+		days = n
+	}
+
+	mode := repository.CatchUpMode(c.FormValue("mode"))
+	switch mode {
+	case "":
+		mode = repository.CatchUpSpread
+	case repository.CatchUpSpread, repository.CatchUpPushBack:
+	default:
+		return 0, "", fmt.Errorf("unknown catch-up mode %q", mode)
+	}
+	return days, mode, nil
+}
+
+// catchUpForm previews how a plan's overdue readings would be spread out,
+// for the options in the query string, before anything is changed.
+func catchUpForm(cfg model.Config, db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.Redirect(http.StatusFound, "/auth/sign-in")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "Invalid plan ID")
+		}
+
+		var plan model.Plan
+		if err := db.WithContext(c.Request().Context()).First(&plan, "id = ? AND user_id = ?", id, user.ID).Error; err != nil {
+			return c.String(http.StatusNotFound, "Plan not found")
+		}
+
+		days, mode, err := readCatchUpOptions(c)
+		if err != nil {
+			return render(c, 422, views.CatchUpPreview(cfg, &user, plan, repository.CatchUpPreview{Days: 7, Mode: repository.CatchUpSpread}, err))
+		}
+		if plan.IsPaused() {
+			return render(c, 422, views.CatchUpPreview(cfg, &user, plan, repository.CatchUpPreview{Days: days, Mode: mode}, fmt.Errorf("resume the plan before catching up")))
+		}
+
+		preview, err := repository.PreviewCatchUp(db.WithContext(c.Request().Context()), plan.ID, clock.NowIn(clk, user.Location()), days, mode)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to load readings")
+		}
+
+		return render(c, 200, views.CatchUpPreview(cfg, &user, plan, preview, nil))
+	}
+}
+
+func catchUpPlan(db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.Redirect(http.StatusFound, "/auth/sign-in")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "Invalid plan ID")
+		}
+
+		var plan model.Plan
+		if err := db.WithContext(c.Request().Context()).First(&plan, "id = ? AND user_id = ?", id, user.ID).Error; err != nil {
+			return c.String(http.StatusNotFound, "Plan not found")
+		}
+		if plan.IsPaused() {
+			return c.String(http.StatusBadRequest, "Resume the plan before catching up")
+		}
+
+		days, mode, err := readCatchUpOptions(c)
+		if err != nil {
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+
+		if _, err := repository.CatchUp(db.WithContext(c.Request().Context()), plan.ID, clock.NowIn(clk, user.Location()), days, mode); err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to reschedule readings")
+		}
+
+		return c.Redirect(http.StatusFound, "/plans")
+	}
+}
+
 func deletePlan(db *gorm.DB) echo.HandlerFunc {
 	return func(c *echo.Context) error {
 		user, ok := mw.GetSessionUser(c)
@@ -611,7 +839,7 @@ func editPlanForm(cfg model.Config, db *gorm.DB) echo.HandlerFunc {
 	}
 }
 
-func editPlan(cfg model.Config, db *gorm.DB) echo.HandlerFunc {
+func editPlan(cfg model.Config, db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
 	return func(c *echo.Context) error {
 		user, ok := mw.GetSessionUser(c)
 		if !ok {
@@ -648,6 +876,12 @@ func editPlan(cfg model.Config, db *gorm.DB) echo.HandlerFunc {
 			return render(c, 422, views.EditPlan(cfg, &user, plan, fmt.Errorf("invalid readings data")))
 		}
 
+		// Relative dates added while editing count from the plan's start, or
+		// from today for plans created before start dates existed.
+		start := clock.NowIn(clk, user.Location())
+		if plan.StartDate != nil {
+			start = *plan.StartDate
+		}
 		err = db.WithContext(c.Request().Context()).Transaction(func(tx *gorm.DB) error {
 			if txErr := tx.Save(&plan).Error; txErr != nil {
 				return txErr
@@ -656,7 +890,7 @@ func editPlan(cfg model.Config, db *gorm.DB) echo.HandlerFunc {
 			processedIDs := make(map[uint]bool)
 
 			for _, fr := range formReadings {
-				parsedDate, dateType, parseErr := csvservice.ParseDate(fr.Date)
+				parsedDate, dateType, offset, parseErr := csvservice.ResolveDate(fr.Date, start)
 				if parseErr != nil {
 					return errors.Wrap(parseErr, fmt.Sprintf("invalid date: %s", fr.Date))
 				}
@@ -676,7 +910,14 @@ func editPlan(cfg model.Config, db *gorm.DB) echo.HandlerFunc {
 				}
 
 				if existingReading != nil {
-					// Update existing
+					// Update existing. A reading keeps its offset while its
+					// date is unchanged; moving it pins it to the new date.
+					switch {
+					case offset != nil:
+						existingReading.StartOffset = offset
+					case !parsedDate.Equal(existingReading.Date):
+						existingReading.StartOffset = nil
+					}
 					existingReading.Date = parsedDate
 					existingReading.DateType = dateType
 					existingReading.Content = fr.Content
@@ -687,11 +928,12 @@ func editPlan(cfg model.Config, db *gorm.DB) echo.HandlerFunc {
 				} else {
 					// Create new
 					newReading := model.Reading{
-						PlanID:   plan.ID,
-						Date:     parsedDate,
-						DateType: dateType,
-						Content:  fr.Content,
-						Status:   model.StatusPending,
+						PlanID:      plan.ID,
+						Date:        parsedDate,
+						DateType:    dateType,
+						StartOffset: offset,
+						Content:     fr.Content,
+						Status:      model.StatusPending,
 					}
 					if createErr := tx.Create(&newReading).Error; createErr != nil {
 						return createErr
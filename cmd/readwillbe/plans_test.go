@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"mime/multipart"
 	"net/http"
@@ -11,36 +12,23 @@ import (
 	"time"
 
 	"readwillbe/internal/cache"
+	"readwillbe/internal/clock"
+	"readwillbe/internal/database/dbtest"
 	mw "readwillbe/internal/middleware"
 	"readwillbe/internal/model"
+	"readwillbe/internal/service/jobs"
 
 	"github.com/labstack/echo/v5"
-	"github.com/ncruces/go-sqlite3/gormlite"
-	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/gorm"
 )
 
 func setupTestDB(t *testing.T) *gorm.DB {
-	// Use a unique name for each test to ensure isolation and prevent flaky tests
-	// caused by shared in-memory database state.
-	// We sanitize the test name to be safe for URI usage.
-	safeName := strings.ReplaceAll(t.Name(), "/", "_")
-	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", safeName)
-	db, err := gorm.Open(gormlite.Open(dsn), &gorm.Config{})
-	assert.NoError(t, err)
-
-	sqlDB, err := db.DB()
-	assert.NoError(t, err)
-	sqlDB.SetMaxOpenConns(1)
+	db := dbtest.Open(t)
 
-	err = db.AutoMigrate(&model.User{}, &model.Plan{}, &model.Reading{}, &model.PushSubscription{})
+	err := db.AutoMigrate(&model.User{}, &model.Plan{}, &model.Reading{}, &model.PushSubscription{}, &model.Job{}, &model.JournalEntry{}, &model.PasswordReset{}, &model.EmailVerification{}, &model.RecoveryCode{}, &model.Passkey{}, &model.Session{})
 	assert.NoError(t, err)
 
-	t.Cleanup(func() {
-		_ = sqlDB.Close()
-	})
-
 	return db
 }
 
@@ -80,7 +68,9 @@ func TestCreatePlan_BackgroundProcessing(t *testing.T) {
 	c.Set(mw.UserKey, user)
 
 	// Invoke handler
-	h := createPlan(afero.NewMemMapFs(), db)
+	queue := jobs.NewQueue(db, clock.System)
+	queue.Register(jobs.TypeCSVImport, jobs.CSVImportHandler())
+	h := createPlan(db, queue, clock.System)
 	err := h(c)
 	assert.NoError(t, err)
 
@@ -88,16 +78,17 @@ func TestCreatePlan_BackgroundProcessing(t *testing.T) {
 	assert.Equal(t, http.StatusFound, rec.Code)
 	assert.Equal(t, "/plans", rec.Header().Get("Location"))
 
-	// Verify plan status is initially "processing" or eventually "active"
-	// Since it's a goroutine, it might be fast enough to be active immediately or still processing.
-	// We'll poll for "active" status.
-
+	// The plan waits in "processing" until a worker runs the import job.
 	var plan model.Plan
-	assert.Eventually(t, func() bool {
-		db.Preload("Readings").First(&plan, "title = ?", "Test Plan")
-		return plan.Status == "active"
-	}, 2*time.Second, 100*time.Millisecond, "Plan should eventually be active")
+	db.First(&plan, "title = ?", "Test Plan")
+	assert.Equal(t, "processing", plan.Status)
+
+	ran, err := queue.RunNext(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, ran)
 
+	db.Preload("Readings").First(&plan, "title = ?", "Test Plan")
+	assert.Equal(t, "active", plan.Status)
 	assert.Equal(t, "Test Plan", plan.Title)
 	assert.Len(t, plan.Readings, 2)
 }
@@ -132,18 +123,186 @@ invalid-row`
 	c.Set(mw.UserKey, user)
 
 	// Invoke handler
-	h := createPlan(afero.NewMemMapFs(), db)
+	queue := jobs.NewQueue(db, clock.System)
+	queue.Register(jobs.TypeCSVImport, jobs.CSVImportHandler())
+	h := createPlan(db, queue, clock.System)
+	err := h(c)
+	assert.NoError(t, err)
+
+	ran, err := queue.RunNext(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, ran)
+
+	// Parse errors are not retried, so one run fails both job and plan.
+	var plan model.Plan
+	db.First(&plan, "title = ?", "Failed Plan")
+	assert.Equal(t, "failed", plan.Status)
+	assert.Contains(t, plan.ErrorMessage, "row 2, reading: missing column")
+
+	var job model.Job
+	db.First(&job, "plan_id = ?", plan.ID)
+	assert.Equal(t, model.JobFailed, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+}
+
+func TestPreviewPlan_ShowsRowsWithoutSaving(t *testing.T) {
+	db := setupTestDB(t)
+
+	user := model.User{
+		Email: "test@example.com",
+		Name:  "Test User",
+	}
+	db.Create(&user)
+
+	e := echo.New()
+
+	csvContent := `date,reading
+2025-01-01,Genesis 1
+bad-date,Genesis 2
+2025-01-03,=SUM(A1)`
+
+	body := new(strings.Builder)
+	writer := multipart.NewWriter(body)
+	_ = writer.WriteField("title", "Preview Plan")
+	part, _ := writer.CreateFormFile("csv", "readings.csv")
+	_, _ = part.Write([]byte(csvContent))
+	_ = writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/plans/preview", strings.NewReader(body.String()))
+	req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(mw.UserKey, user)
+
+	h := previewPlan(model.Config{}, clock.System)
 	err := h(c)
 	assert.NoError(t, err)
 
-	// Verify plan status eventually "failed"
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Genesis 1")
+	assert.Contains(t, rec.Body.String(), "invalid date format: bad-date")
+	assert.Contains(t, rec.Body.String(), "formula characters")
+
+	var count int64
+	db.Model(&model.Plan{}).Count(&count)
+	assert.Zero(t, count, "preview must not create a plan")
+}
+
+func TestCreatePlan_FromConfirmedPreview(t *testing.T) {
+	db := setupTestDB(t)
+
+	user := model.User{
+		Email: "test@example.com",
+		Name:  "Test User",
+	}
+	db.Create(&user)
+
+	e := echo.New()
+
+	form := url.Values{}
+	form.Set("title", "Confirmed Plan")
+	form.Set("csv_data", "date,reading\n2025-01-01,Genesis 1\n")
+
+	req := httptest.NewRequest(http.MethodPost, "/plans/create", strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(mw.UserKey, user)
+
+	queue := jobs.NewQueue(db, clock.System)
+	queue.Register(jobs.TypeCSVImport, jobs.CSVImportHandler())
+	err := createPlan(db, queue, clock.System)(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusFound, rec.Code)
+
+	_, err = queue.RunNext(context.Background())
+	assert.NoError(t, err)
+
+	var plan model.Plan
+	db.Preload("Readings").First(&plan, "title = ?", "Confirmed Plan")
+	assert.Equal(t, "active", plan.Status)
+	assert.Len(t, plan.Readings, 1)
+}
+
+func TestCreatePlan_RelativeDatesUseChosenStart(t *testing.T) {
+	db := setupTestDB(t)
+
+	user := model.User{
+		Email: "test@example.com",
+		Name:  "Test User",
+	}
+	db.Create(&user)
+
+	e := echo.New()
+
+	form := url.Values{}
+	form.Set("title", "Relative Plan")
+	form.Set("start_date", "2026-04-06")
+	form.Set("csv_data", "date,reading\nDay 1,Genesis 1\nDay 12,Genesis 12\nWeek 3,Exodus\n")
+
+	req := httptest.NewRequest(http.MethodPost, "/plans/create", strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(mw.UserKey, user)
+
+	queue := jobs.NewQueue(db, clock.System)
+	queue.Register(jobs.TypeCSVImport, jobs.CSVImportHandler())
+	err := createPlan(db, queue, clock.System)(c)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusFound, rec.Code)
+
+	_, err = queue.RunNext(context.Background())
+	assert.NoError(t, err)
+
 	var plan model.Plan
-	assert.Eventually(t, func() bool {
-		db.First(&plan, "title = ?", "Failed Plan")
-		return plan.Status == "failed"
-	}, 2*time.Second, 100*time.Millisecond, "Plan should eventually fail")
+	db.Preload("Readings", func(db *gorm.DB) *gorm.DB { return db.Order("date ASC") }).First(&plan, "title = ?", "Relative Plan")
+	assert.Equal(t, "active", plan.Status)
+	if assert.NotNil(t, plan.StartDate) {
+		assert.Equal(t, "2026-04-06", plan.StartDate.Format("2006-01-02"))
+	}
+	if assert.Len(t, plan.Readings, 3) {
+		assert.Equal(t, "2026-04-06", plan.Readings[0].Date.Format("2006-01-02"))
+		assert.Equal(t, "2026-04-17", plan.Readings[1].Date.Format("2006-01-02"))
+		assert.Equal(t, "2026-04-20", plan.Readings[2].Date.Format("2006-01-02"))
+		assert.Equal(t, model.DateTypeWeek, plan.Readings[2].DateType)
+		if assert.NotNil(t, plan.Readings[1].StartOffset) {
+			assert.Equal(t, 11, *plan.Readings[1].StartOffset)
+		}
+	}
+}
+
+func TestCreatePlan_RejectsInvalidStartDate(t *testing.T) {
+	db := setupTestDB(t)
+
+	user := model.User{
+		Email: "test@example.com",
+		Name:  "Test User",
+	}
+	db.Create(&user)
+
+	e := echo.New()
 
-	assert.Contains(t, plan.ErrorMessage, "reading CSV")
+	form := url.Values{}
+	form.Set("title", "Bad Start")
+	form.Set("start_date", "next tuesday")
+	form.Set("csv_data", "date,reading\nDay 1,Genesis 1\n")
+
+	req := httptest.NewRequest(http.MethodPost, "/plans/create", strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(mw.UserKey, user)
+
+	queue := jobs.NewQueue(db, clock.System)
+	err := createPlan(db, queue, clock.System)(c)
+	assert.NoError(t, err)
+	assert.Equal(t, 422, rec.Code)
+	assert.Contains(t, rec.Body.String(), "start date must be in YYYY-MM-DD format")
+
+	var count int64
+	db.Model(&model.Plan{}).Count(&count)
+	assert.Zero(t, count)
 }
 
 func TestUserCache(t *testing.T) {
@@ -306,7 +465,7 @@ func TestEditPlan(t *testing.T) {
 				return next(c)
 			}
 		})
-		e.POST("/plans/:id/edit", editPlan(model.Config{}, db))
+		e.POST("/plans/:id/edit", editPlan(model.Config{}, db, clock.System))
 
 		// Prepare JSON payload for readings
 		// 1. Update Reading 1
@@ -367,7 +526,7 @@ func TestEditPlan(t *testing.T) {
 				return next(c)
 			}
 		})
-		e.POST("/plans/:id/edit", editPlan(model.Config{}, db))
+		e.POST("/plans/:id/edit", editPlan(model.Config{}, db, clock.System))
 
 		form := make(url.Values)
 		form.Set("title", "Updated Title")
@@ -389,3 +548,123 @@ func timeMustParse(value string) time.Time {
 	}
 	return t
 }
+
+func TestPauseResumeAndShiftPlan(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "test_pause@example.com", "password123")
+	plan := createTestPlan(t, db, user, "Vacation")
+
+	reading := model.Reading{PlanID: plan.ID, Date: timeMustParse("2026-03-05"), DateType: model.DateTypeDay, Content: "Reading 1", Status: model.StatusPending}
+	db.Create(&reading)
+
+	// The plan is paused on March 4 and resumed a week later.
+	pausedOn := clock.Fixed(time.Date(2026, time.March, 4, 12, 0, 0, 0, time.UTC))
+	resumedOn := clock.Fixed(time.Date(2026, time.March, 11, 12, 0, 0, 0, time.UTC))
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			c.Set(mw.UserKey, *user)
+			return next(c)
+		}
+	})
+	e.POST("/plans/:id/pause", pausePlan(db, pausedOn))
+	e.POST("/plans/:id/resume", resumePlan(db, resumedOn))
+	e.POST("/plans/:id/shift", shiftPlan(db))
+
+	post := func(path string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/plans/%d/%s", plan.ID, path), strings.NewReader(form.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	readingDate := func() string {
+		var r model.Reading
+		db.First(&r, reading.ID)
+		return r.Date.Format("2006-01-02")
+	}
+
+	t.Run("resume requires a paused plan", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, post("resume", nil).Code)
+	})
+
+	t.Run("pause then resume shifts by the pause length", func(t *testing.T) {
+		assert.Equal(t, http.StatusFound, post("pause", nil).Code)
+		assert.Equal(t, http.StatusBadRequest, post("pause", nil).Code, "already paused")
+
+		assert.Equal(t, http.StatusFound, post("resume", nil).Code)
+		assert.Equal(t, "2026-03-12", readingDate())
+	})
+
+	t.Run("shift by weeks", func(t *testing.T) {
+		rec := post("shift", url.Values{"amount": {"-1"}, "unit": {"weeks"}})
+		assert.Equal(t, http.StatusFound, rec.Code)
+		assert.Equal(t, "2026-03-05", readingDate())
+	})
+
+	t.Run("shift rejects bad input", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, post("shift", url.Values{"amount": {"0"}, "unit": {"days"}}).Code)
+		assert.Equal(t, http.StatusBadRequest, post("shift", url.Values{"amount": {"2"}, "unit": {"months"}}).Code)
+		assert.Equal(t, http.StatusBadRequest, post("shift", url.Values{"amount": {"60"}, "unit": {"weeks"}}).Code)
+		assert.Equal(t, "2026-03-05", readingDate())
+	})
+}
+
+func TestCatchUpPlan(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "test_catchup@example.com", "password123")
+	plan := createTestPlan(t, db, user, "Behind")
+
+	readings := []model.Reading{
+		{PlanID: plan.ID, Date: timeMustParse("2026-03-01"), DateType: model.DateTypeDay, Content: "Missed 1", Status: model.StatusPending},
+		{PlanID: plan.ID, Date: timeMustParse("2026-03-02"), DateType: model.DateTypeDay, Content: "Missed 2", Status: model.StatusPending},
+	}
+	db.Create(&readings)
+
+	clk := clock.Fixed(time.Date(2026, time.March, 10, 12, 0, 0, 0, time.UTC))
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			c.Set(mw.UserKey, *user)
+			return next(c)
+		}
+	})
+	e.GET("/plans/:id/catch-up", catchUpForm(model.Config{}, db, clk))
+	e.POST("/plans/:id/catch-up", catchUpPlan(db, clk))
+
+	t.Run("preview lists new dates without saving", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/plans/%d/catch-up?days=2", plan.ID), nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Missed 1")
+		assert.Contains(t, rec.Body.String(), "Wed, Mar 11")
+
+		var r model.Reading
+		db.First(&r, readings[1].ID)
+		assert.Equal(t, "2026-03-02", r.Date.Format("2006-01-02"))
+	})
+
+	t.Run("preview rejects a bad window", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/plans/%d/catch-up?days=500", plan.ID), nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, 422, rec.Code)
+	})
+
+	t.Run("confirm reschedules", func(t *testing.T) {
+		form := url.Values{"days": {"2"}, "mode": {"spread"}}
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/plans/%d/catch-up", plan.ID), strings.NewReader(form.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusFound, rec.Code)
+
+		var first, second model.Reading
+		db.First(&first, readings[0].ID)
+		db.First(&second, readings[1].ID)
+		assert.Equal(t, "2026-03-10", first.Date.Format("2006-01-02"))
+		assert.Equal(t, "2026-03-11", second.Date.Format("2006-01-02"))
+	})
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
-	"time"
+	"strings"
+	"unicode/utf8"
 
+	"readwillbe/internal/clock"
 	mw "readwillbe/internal/middleware"
 	"readwillbe/internal/model"
 
@@ -14,7 +17,7 @@ import (
 
 // MaxContentLength is defined in plans.go
 
-func completeReading(db *gorm.DB) echo.HandlerFunc {
+func completeReading(db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
 	return func(c *echo.Context) error {
 		user, ok := mw.GetSessionUser(c)
 		if !ok {
@@ -35,9 +38,11 @@ func completeReading(db *gorm.DB) echo.HandlerFunc {
 			return c.String(http.StatusNotFound, "Reading not found")
 		}
 
-		now := time.Now()
+		now := clk.Now().UTC()
 		reading.Status = model.StatusCompleted
 		reading.CompletedAt = &now
+		reading.SkippedAt = nil
+		reading.SkipReason = ""
 
 		if err := db.WithContext(c.Request().Context()).Save(&reading).Error; err != nil {
 			return c.String(http.StatusInternalServerError, "Failed to update reading")
@@ -79,6 +84,103 @@ func uncompleteReading(db *gorm.DB) echo.HandlerFunc {
 	}
 }
 
+// MaxSkipReasonLength bounds, in characters, the optional reason given when
+// skipping.
+const MaxSkipReasonLength = 500
+
+// skipReturnPaths are the pages a skip may send the user back to.
+var skipReturnPaths = map[string]bool{
+	"/dashboard": true,
+	"/plans":     true,
+}
+
+func skipReturnTo(c *echo.Context) string {
+	if to := c.FormValue("return_to"); skipReturnPaths[to] {
+		return to
+	}
+	return "/plans"
+}
+
+// skipReading marks a reading as deliberately not done, with an optional
+// reason, so it leaves the dashboard without counting as completed.
+func skipReading(db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.Redirect(http.StatusFound, "/auth/sign-in")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "Invalid reading ID")
+		}
+
+		reason := strings.TrimSpace(c.FormValue("reason"))
+		if utf8.RuneCountInString(reason) > MaxSkipReasonLength {
+			return c.String(http.StatusBadRequest, fmt.Sprintf("Reason must be at most %d characters", MaxSkipReasonLength))
+		}
+
+		var reading model.Reading
+		if err := db.WithContext(c.Request().Context()).
+			Preload("Plan").
+			Joins("JOIN plans ON plans.id = readings.plan_id").
+			Where("readings.id = ? AND plans.user_id = ?", id, user.ID).
+			First(&reading).Error; err != nil {
+			return c.String(http.StatusNotFound, "Reading not found")
+		}
+		if reading.Status != model.StatusPending {
+			return c.String(http.StatusBadRequest, "Reading is not pending")
+		}
+
+		now := clk.Now().UTC()
+		reading.Status = model.StatusSkipped
+		reading.SkippedAt = &now
+		reading.SkipReason = reason
+
+		if err := db.WithContext(c.Request().Context()).Save(&reading).Error; err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to update reading")
+		}
+
+		return c.Redirect(http.StatusFound, skipReturnTo(c))
+	}
+}
+
+func unskipReading(db *gorm.DB) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.Redirect(http.StatusFound, "/auth/sign-in")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "Invalid reading ID")
+		}
+
+		var reading model.Reading
+		if err := db.WithContext(c.Request().Context()).
+			Preload("Plan").
+			Joins("JOIN plans ON plans.id = readings.plan_id").
+			Where("readings.id = ? AND plans.user_id = ?", id, user.ID).
+			First(&reading).Error; err != nil {
+			return c.String(http.StatusNotFound, "Reading not found")
+		}
+		if reading.Status != model.StatusSkipped {
+			return c.String(http.StatusBadRequest, "Reading is not skipped")
+		}
+
+		reading.Status = model.StatusPending
+		reading.SkippedAt = nil
+		reading.SkipReason = ""
+
+		if err := db.WithContext(c.Request().Context()).Save(&reading).Error; err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to update reading")
+		}
+
+		return c.Redirect(http.StatusFound, "/history")
+	}
+}
+
 func updateReading(db *gorm.DB) echo.HandlerFunc {
 	return func(c *echo.Context) error {
 		user, ok := mw.GetSessionUser(c)
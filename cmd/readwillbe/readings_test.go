@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"readwillbe/internal/clock"
 	"readwillbe/internal/model"
 
 	"github.com/labstack/echo/v5"
@@ -20,6 +21,8 @@ func TestCompleteReading(t *testing.T) {
 	user := createTestUser(t, db, "reader@example.com", "password123")
 	plan := createTestPlan(t, db, user, "Test Plan")
 	reading := createTestReading(t, db, plan, "Genesis 1", time.Now())
+	completedAt := time.Date(2024, time.February, 29, 21, 30, 0, 0, time.UTC)
+	clk := clock.Fixed(completedAt)
 
 	t.Run("successfully complete reading", func(t *testing.T) {
 		e := echo.New()
@@ -29,7 +32,7 @@ func TestCompleteReading(t *testing.T) {
 				return next(c)
 			}
 		})
-		e.POST("/reading/:id/complete", completeReading(db))
+		e.POST("/reading/:id/complete", completeReading(db, clk))
 
 		req := httptest.NewRequest("POST", fmt.Sprintf("/reading/%d/complete", reading.ID), nil)
 		rec := httptest.NewRecorder()
@@ -39,12 +42,13 @@ func TestCompleteReading(t *testing.T) {
 		err := db.First(&updated, reading.ID).Error
 		require.NoError(t, err)
 		assert.Equal(t, model.StatusCompleted, updated.Status)
-		assert.NotNil(t, updated.CompletedAt)
+		require.NotNil(t, updated.CompletedAt)
+		assert.True(t, completedAt.Equal(*updated.CompletedAt), "CompletedAt should come from the injected clock")
 	})
 
 	t.Run("unauthenticated request", func(t *testing.T) {
 		e := echo.New()
-		e.POST("/reading/:id/complete", completeReading(db))
+		e.POST("/reading/:id/complete", completeReading(db, clk))
 
 		req := httptest.NewRequest("POST", fmt.Sprintf("/reading/%d/complete", reading.ID), nil)
 		rec := httptest.NewRecorder()
@@ -60,7 +64,7 @@ func TestCompleteReading(t *testing.T) {
 				return next(c)
 			}
 		})
-		e.POST("/reading/:id/complete", completeReading(db))
+		e.POST("/reading/:id/complete", completeReading(db, clk))
 
 		req := httptest.NewRequest("POST", "/reading/99999/complete", nil)
 		rec := httptest.NewRecorder()
@@ -196,3 +200,83 @@ func TestUpdateReading(t *testing.T) {
 		assert.Equal(t, 302, rec.Code)
 	})
 }
+
+func TestSkipReading(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "skipper@example.com", "password123")
+	plan := createTestPlan(t, db, user, "Test Plan")
+	reading := createTestReading(t, db, plan, "Genesis 1", time.Now())
+	skippedAt := time.Date(2024, time.March, 1, 8, 0, 0, 0, time.UTC)
+
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			c.Set(UserKey, *user)
+			return next(c)
+		}
+	})
+	e.POST("/reading/:id/skip", skipReading(db, clock.Fixed(skippedAt)))
+	e.POST("/reading/:id/unskip", unskipReading(db))
+
+	post := func(path string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("skip with a reason", func(t *testing.T) {
+		rec := post(fmt.Sprintf("/reading/%d/skip", reading.ID), url.Values{"reason": {"  Travelling  "}, "return_to": {"/dashboard"}})
+		assert.Equal(t, 302, rec.Code)
+		assert.Equal(t, "/dashboard", rec.Header().Get("Location"))
+
+		var updated model.Reading
+		require.NoError(t, db.First(&updated, reading.ID).Error)
+		assert.Equal(t, model.StatusSkipped, updated.Status)
+		assert.Equal(t, "Travelling", updated.SkipReason)
+		require.NotNil(t, updated.SkippedAt)
+		assert.True(t, skippedAt.Equal(*updated.SkippedAt))
+		assert.Nil(t, updated.CompletedAt)
+	})
+
+	t.Run("reason too long", func(t *testing.T) {
+		rec := post(fmt.Sprintf("/reading/%d/skip", reading.ID), url.Values{"reason": {strings.Repeat("a", MaxSkipReasonLength+1)}})
+		assert.Equal(t, 400, rec.Code)
+	})
+
+	t.Run("reason length counts characters", func(t *testing.T) {
+		other := createTestReading(t, db, plan, "Genesis 2", time.Now())
+		rec := post(fmt.Sprintf("/reading/%d/skip", other.ID), url.Values{"reason": {strings.Repeat("é", MaxSkipReasonLength)}, "return_to": {"https://evil.example"}})
+		assert.Equal(t, 302, rec.Code)
+		assert.Equal(t, "/plans", rec.Header().Get("Location"), "unknown return paths fall back to plans")
+	})
+
+	t.Run("only pending readings can be skipped", func(t *testing.T) {
+		completedAt := time.Date(2024, time.February, 1, 8, 0, 0, 0, time.UTC)
+		done := createTestReading(t, db, plan, "Genesis 3", time.Now())
+		require.NoError(t, db.Model(done).Updates(map[string]interface{}{"status": model.StatusCompleted, "completed_at": completedAt}).Error)
+
+		rec := post(fmt.Sprintf("/reading/%d/skip", done.ID), nil)
+		assert.Equal(t, 400, rec.Code)
+
+		var updated model.Reading
+		require.NoError(t, db.First(&updated, done.ID).Error)
+		assert.Equal(t, model.StatusCompleted, updated.Status)
+		assert.NotNil(t, updated.CompletedAt)
+	})
+
+	t.Run("unskip", func(t *testing.T) {
+		rec := post(fmt.Sprintf("/reading/%d/unskip", reading.ID), nil)
+		assert.Equal(t, 302, rec.Code)
+
+		var updated model.Reading
+		require.NoError(t, db.First(&updated, reading.ID).Error)
+		assert.Equal(t, model.StatusPending, updated.Status)
+		assert.Empty(t, updated.SkipReason)
+		assert.Nil(t, updated.SkippedAt)
+
+		rec = post(fmt.Sprintf("/reading/%d/unskip", reading.ID), nil)
+		assert.Equal(t, 400, rec.Code, "only skipped readings can be unskipped")
+	})
+}
@@ -56,9 +56,13 @@ func initConfig() {
 
 	viper.SetDefault("port", "8080")
 	viper.SetDefault("log_level", "info")
+	viper.SetDefault("db_driver", "sqlite")
+	viper.SetDefault("db_dsn", "")
 	viper.SetDefault("db_path", "./tmp/readwillbe.db")
 	viper.SetDefault("allow_signup", true)
 	viper.SetDefault("seed_db", false)
+	viper.SetDefault("auto_migrate", false)
+	viper.SetDefault("job_workers", 4)
 
 	// Email configuration defaults
 	viper.SetDefault("email_provider", "")
@@ -71,6 +75,19 @@ func initConfig() {
 	viper.SetDefault("resend_api_key", "")
 	viper.SetDefault("resend_from", "")
 
+	// OpenID Connect defaults
+	viper.SetDefault("oidc_issuer", "")
+	viper.SetDefault("oidc_client_id", "")
+	viper.SetDefault("oidc_client_secret", "")
+	viper.SetDefault("oidc_redirect_url", "")
+	viper.SetDefault("oidc_provider_name", "")
+	viper.SetDefault("oidc_create_users", false)
+
+	// Reverse-proxy authentication defaults
+	viper.SetDefault("proxy_auth_header", "")
+	viper.SetDefault("proxy_auth_name_header", "")
+	viper.SetDefault("trusted_proxies", []string{})
+
 	if err := viper.ReadInConfig(); err != nil {
 		var configFileNotFoundError viper.ConfigFileNotFoundError
 		if !errors.As(err, &configFileNotFoundError) {
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"gorm.io/gorm"
+
+	mw "readwillbe/internal/middleware"
+	"readwillbe/internal/model"
+	"readwillbe/internal/repository"
+	"readwillbe/internal/views"
+)
+
+// Limits on search requests.
+const (
+	MaxSearchQueryLength = 200
+	MaxSearchResults     = 100
+)
+
+// readSearchFilter parses the search options in the query string: q, plan,
+// status, and a from/to reading date range.
+func readSearchFilter(c *echo.Context) (repository.SearchFilter, error) {
+	filter := repository.SearchFilter{Query: strings.TrimSpace(c.QueryParam("q"))}
+	if len(filter.Query) > MaxSearchQueryLength {
+		return filter, fmt.Errorf("search must be less than %d characters", MaxSearchQueryLength)
+	}
+
+	if value := c.QueryParam("plan"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return filter, fmt.Errorf("invalid plan")
+		}
+		filter.PlanID = uint(id)
+	}
+
+	filter.Status = model.ReadingStatus(c.QueryParam("status"))
+	switch filter.Status {
+	case "", model.StatusPending, model.StatusCompleted, model.StatusSkipped:
+	default:
+		return filter, fmt.Errorf("unknown status %q", filter.Status)
+	}
+
+	var err error
+	if filter.From, err = readSearchDate(c, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = readSearchDate(c, "to"); err != nil {
+		return filter, err
+	}
+	if filter.From != nil && filter.To != nil && filter.To.Before(*filter.From) {
+		return filter, fmt.Errorf("from date must not be after to date")
+	}
+
+	return filter, nil
+}
+
+// readSearchDate parses an optional YYYY-MM-DD query parameter.
+func readSearchDate(c *echo.Context, param string) (*time.Time, error) {
+	value := c.QueryParam(param)
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("%s date must be in YYYY-MM-DD format", param)
+	}
+	return &date, nil
+}
+
+func searchHandler(cfg model.Config, db *gorm.DB) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.Redirect(http.StatusFound, "/auth/sign-in")
+		}
+
+		plans, err := repository.GetPlanTitles(db.WithContext(c.Request().Context()), user.ID)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to load plans")
+		}
+
+		filter, err := readSearchFilter(c)
+		if err != nil {
+			return render(c, 400, views.Search(cfg, &user, plans, filter, nil, err))
+		}
+		if filter.IsZero() {
+			return render(c, 200, views.Search(cfg, &user, plans, filter, nil, nil))
+		}
+
+		readings, err := repository.SearchReadings(db.WithContext(c.Request().Context()), user.ID, filter, MaxSearchResults)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to search readings")
+		}
+
+		return render(c, 200, views.Search(cfg, &user, plans, filter, readings, nil))
+	}
+}
+
+type apiSearchResult struct {
+	apiReading
+	Status  string `json:"status"`
+	Journal string `json:"journal,omitempty"`
+	URL     string `json:"url"`
+}
+
+func apiSearch(db *gorm.DB) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		}
+
+		filter, err := readSearchFilter(c)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		readings, err := repository.SearchReadings(db.WithContext(c.Request().Context()), user.ID, filter, MaxSearchResults)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "failed to search readings"})
+		}
+
+		results := make([]apiSearchResult, len(readings))
+		for i, r := range readings {
+			results[i] = apiSearchResult{
+				apiReading: apiReading{
+					ID:      r.ID,
+					Date:    r.FormattedDate(),
+					Content: r.Content,
+					Plan:    &apiPlan{ID: r.Plan.ID, Title: r.Plan.Title},
+				},
+				Status: string(r.Status),
+				URL:    views.ReadingURL(r),
+			}
+			if r.Journal != nil {
+				results[i].Journal = r.Journal.Body
+			}
+		}
+
+		return c.JSON(http.StatusOK, map[string][]apiSearchResult{"readings": results})
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"readwillbe/internal/database/dbtest"
+	"readwillbe/internal/migrate"
+	"readwillbe/internal/model"
+
+	"github.com/labstack/echo/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearch(t *testing.T) {
+	db := dbtest.Open(t)
+	_, err := migrate.New(db).Up()
+	require.NoError(t, err)
+
+	user := createTestUser(t, db, "search@example.com", "password123")
+	plan := createTestPlan(t, db, user, "Gospels")
+	john := createTestReading(t, db, plan, "John 1", time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC))
+	createTestReading(t, db, plan, "Mark 1", time.Date(2026, time.May, 2, 0, 0, 0, 0, time.UTC))
+
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c *echo.Context) error {
+			c.Set(UserKey, *user)
+			return next(c)
+		}
+	})
+	e.GET("/search", searchHandler(model.Config{}, db))
+	e.GET("/api/search", apiSearch(db))
+
+	get := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", target, nil)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("page links results to their plan", func(t *testing.T) {
+		rec := get("/search?q=john")
+		assert.Equal(t, 200, rec.Code)
+		assert.Contains(t, rec.Body.String(), fmt.Sprintf("/plans#reading-%d", john.ID))
+		assert.NotContains(t, rec.Body.String(), "Mark 1")
+	})
+
+	t.Run("empty form shows no results", func(t *testing.T) {
+		rec := get("/search")
+		assert.Equal(t, 200, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "John 1")
+	})
+
+	t.Run("invalid filters", func(t *testing.T) {
+		for _, target := range []string{
+			"/search?status=overdue",
+			"/search?plan=abc",
+			"/search?from=05/01/2026",
+			"/search?from=2026-05-02&to=2026-05-01",
+		} {
+			assert.Equal(t, 400, get(target).Code, target)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		rec := get("/api/search?q=gospels&to=2026-05-01")
+		require.Equal(t, 200, rec.Code)
+
+		var body struct {
+			Readings []apiSearchResult `json:"readings"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		require.Len(t, body.Readings, 1)
+		assert.Equal(t, john.ID, body.Readings[0].ID)
+		assert.Equal(t, "Gospels", body.Readings[0].Plan.Title)
+		assert.Equal(t, "pending", body.Readings[0].Status)
+		assert.Equal(t, fmt.Sprintf("/plans#reading-%d", john.ID), body.Readings[0].URL)
+
+		assert.Equal(t, 400, get("/api/search?status=bogus").Code)
+	})
+}
@@ -5,7 +5,6 @@ package main
 import (
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -13,11 +12,12 @@ import (
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 
+	"readwillbe/internal/clock"
 	"readwillbe/internal/model"
 	csvservice "readwillbe/internal/service/csv"
 )
 
-func seedDatabase(db *gorm.DB, fs afero.Fs) error {
+func seedDatabase(db *gorm.DB, fs afero.Fs, clk clock.Clock) error {
 	logrus.Info("Seeding database (dev environment)...")
 
 	// 1. Ensure Test User Exists
@@ -39,10 +39,11 @@ func seedDatabase(db *gorm.DB, fs afero.Fs) error {
 		}
 
 		testUser = model.User{
-			Name:      "Testy",
-			Email:     "testy@testicular.test",
-			Password:  string(hash),
-			CreatedAt: time.Now(),
+			Name:          "Testy",
+			Email:         "testy@testicular.test",
+			EmailVerified: true,
+			Password:      string(hash),
+			CreatedAt:     clk.Now(),
 		}
 
 		if err := db.Create(&testUser).Error; err != nil {
@@ -92,7 +93,7 @@ func seedDatabase(db *gorm.DB, fs afero.Fs) error {
 			return errors.Wrapf(err, "opening file %s", filename)
 		}
 
-		readings, err := csvservice.ParseCSV(f)
+		readings, err := csvservice.ParseCSV(f, clk.Now())
 		f.Close() // Close immediately after reading
 
 		if err != nil {
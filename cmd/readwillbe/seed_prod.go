@@ -5,8 +5,10 @@ package main
 import (
 	"github.com/spf13/afero"
 	"gorm.io/gorm"
+
+	"readwillbe/internal/clock"
 )
 
-func seedDatabase(_ *gorm.DB, _ afero.Fs) error {
+func seedDatabase(_ *gorm.DB, _ afero.Fs, _ clock.Clock) error {
 	return nil
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io/fs"
 	"net/http"
@@ -8,7 +9,7 @@ import (
 	"time"
 
 	"github.com/a-h/templ"
-	"github.com/gorilla/sessions"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/labstack/echo-contrib/v5/session"
 	"github.com/labstack/echo/v5"
 	"github.com/labstack/echo/v5/middleware"
@@ -20,13 +21,17 @@ import (
 	"golang.org/x/time/rate"
 
 	"readwillbe/internal/cache"
+	"readwillbe/internal/clock"
+	"readwillbe/internal/database"
 	mw "readwillbe/internal/middleware"
+	"readwillbe/internal/migrate"
 	"readwillbe/internal/model"
+	emailservice "readwillbe/internal/service/email"
+	"readwillbe/internal/service/jobs"
+	"readwillbe/internal/service/passkey"
 	"readwillbe/internal/service/push"
+	"readwillbe/internal/service/sso"
 	"readwillbe/static"
-
-	sqlite "github.com/ncruces/go-sqlite3/gormlite"
-	"gorm.io/gorm"
 )
 
 func render(ctx *echo.Context, status int, t templ.Component) error {
@@ -146,43 +151,55 @@ func runServer(_ *cobra.Command, _ []string) error {
 		},
 	}))
 
-	db, err := gorm.Open(sqlite.Open(cfg.DBPath), &gorm.Config{})
+	db, err := database.Open(cfg.DBDriver, cfg.DBDSN)
 	if err != nil {
-		return errors.Wrap(err, "failed to connect database")
+		return err
 	}
 
-	sqlDB, err := db.DB()
-	if err != nil {
-		return errors.Wrap(err, "failed to get underlying sql.DB")
+	migrator := migrate.New(db)
+	if cfg.AutoMigrate {
+		ran, err := migrator.Up()
+		if err != nil {
+			return errors.Wrap(err, "failed to migrate")
+		}
+		for _, mig := range ran {
+			logrus.Infof("Applied migration %d_%s", mig.Version, mig.Name)
+		}
 	}
-
-	sqlDB.SetMaxIdleConns(1)
-	sqlDB.SetMaxOpenConns(1)
-	sqlDB.SetConnMaxLifetime(time.Hour)
-
-	err = db.AutoMigrate(&model.User{}, &model.Plan{}, &model.Reading{}, &model.PushSubscription{})
-	if err != nil {
-		return errors.Wrap(err, "failed to migrate")
+	if err := migrator.Check(); err != nil {
+		return err
 	}
 
+	clk := clock.System
+
 	if cfg.SeedDB {
 		appFS := afero.NewOsFs()
-		if err := seedDatabase(db, appFS); err != nil {
+		if err := seedDatabase(db, appFS, clk); err != nil {
 			return errors.Wrap(err, "seeding database")
 		}
 	}
 
-	_ = push.StartNotificationWorker(cfg, db)
+	_ = push.StartNotificationWorker(cfg, db, clk)
+
+	queue := jobs.NewQueue(db, clk)
+	queue.Register(jobs.TypeCSVImport, jobs.CSVImportHandler())
+	// SQLite means a single process, so any running job was orphaned.
+	stopJobs := queue.Start(cfg.JobWorkers, cfg.DBDriver == database.DriverSQLite)
+	defer stopJobs()
+	if n, err := jobs.RecoverPlans(db); err != nil {
+		logrus.Errorf("Error recovering interrupted plan imports: %v", err)
+	} else if n > 0 {
+		logrus.Warnf("Marked %d interrupted plan import(s) as failed", n)
+	}
 
-	store := sessions.NewCookieStore(cfg.CookieSecret)
-	store.Options = mw.GetSecureSessionOptions(cfg)
+	store := mw.NewDBStore(db, cfg, clk)
+	stopSessionCleanup := store.StartCleanup(time.Hour)
+	defer stopSessionCleanup()
 	e.Use(session.Middleware(store))
 	userCache := cache.NewUserCache(5*time.Minute, 10*time.Minute)
-	e.Use(mw.UserMiddleware(db, userCache, cfg))
-
-	appFS := afero.NewOsFs()
+	e.Use(mw.UserMiddleware(db, userCache, cfg, clk))
 
-	e.GET("/", dashboardHandler(cfg, db))
+	e.GET("/", dashboardHandler(cfg, db, clk))
 	e.GET("/healthz", func(c *echo.Context) error {
 		return c.String(http.StatusOK, "ok")
 	})
@@ -190,22 +207,73 @@ func runServer(_ *cobra.Command, _ []string) error {
 	authRateLimiter := middleware.RateLimiter(middleware.NewRateLimiterMemoryStore(float64(rate.Limit(5))))
 	generalRateLimiter := middleware.RateLimiter(middleware.NewRateLimiterMemoryStore(float64(rate.Limit(30))))
 
+	emailService := emailservice.NewService(cfg)
+
+	var webAuthn *webauthn.WebAuthn
+	if cfg.PasskeysEnabled() {
+		webAuthn, err = passkey.New(cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	var ssoProvider *sso.Provider
+	if cfg.OIDCEnabled() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		ssoProvider, err = sso.New(ctx, cfg)
+		cancel()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Behind an authenticating proxy the proxy is the only way in, so none of
+	// the local sign-in, sign-up or recovery routes are served.
+	localAuth := !cfg.ProxyAuthEnabled()
+
 	e.GET("/auth/sign-in", signIn(cfg))
-	e.POST("/auth/sign-in", signInWithEmailAndPassword(db, cfg), authRateLimiter)
-	if cfg.AllowSignup {
-		e.GET("/auth/sign-up", signUp(cfg))
-		e.POST("/auth/sign-up", signUpWithEmailAndPassword(db, cfg), authRateLimiter)
+	if localAuth {
+		e.POST("/auth/sign-in", signInWithEmailAndPassword(db, cfg, clk), authRateLimiter)
+		e.GET("/auth/two-factor", twoFactorForm(cfg, db, clk))
+		e.POST("/auth/two-factor", verifyTwoFactor(cfg, db, clk), authRateLimiter)
+		if webAuthn != nil {
+			e.POST("/auth/passkey/options", passkeySignInOptions(cfg, webAuthn), authRateLimiter)
+			e.POST("/auth/passkey", signInWithPasskey(cfg, db, clk, webAuthn), authRateLimiter)
+			e.POST("/auth/two-factor/passkey/options", twoFactorPasskeyOptions(cfg, db, clk, webAuthn), authRateLimiter)
+			e.POST("/auth/two-factor/passkey", verifyTwoFactorPasskey(cfg, db, clk, webAuthn), authRateLimiter)
+		}
+		if ssoProvider != nil {
+			e.GET("/auth/oidc", oidcSignIn(cfg, clk, ssoProvider), authRateLimiter)
+			e.GET("/auth/oidc/callback", oidcCallback(cfg, db, clk, ssoProvider), authRateLimiter)
+		}
+		if cfg.AllowSignup {
+			e.GET("/auth/sign-up", signUp(cfg))
+			e.POST("/auth/sign-up", signUpWithEmailAndPassword(db, cfg, clk, emailService), authRateLimiter)
+		}
+		e.POST("/auth/sign-out", signOut(), generalRateLimiter)
+		if cfg.EmailEnabled() {
+			e.GET("/auth/forgot-password", forgotPassword(cfg))
+			e.POST("/auth/forgot-password", requestPasswordReset(cfg, db, clk, emailService), authRateLimiter)
+			e.GET("/auth/reset-password", resetPasswordForm(cfg, db, clk))
+			e.POST("/auth/reset-password", resetPassword(cfg, db, clk, userCache), authRateLimiter)
+		}
+	}
+	if cfg.EmailEnabled() {
+		e.GET("/auth/verify-email", verifyEmail(cfg, db, clk, userCache), authRateLimiter)
+		e.POST("/account/verify-email", resendVerification(cfg, db, clk, emailService), authRateLimiter)
 	}
-	e.POST("/auth/sign-out", signOut(), generalRateLimiter)
 
-	e.GET("/dashboard", dashboardHandler(cfg, db))
-	e.GET("/partials/dashboard-stats", dashboardStatsPartial(db))
+	e.GET("/dashboard", dashboardHandler(cfg, db, clk))
+	e.GET("/partials/dashboard-stats", dashboardStatsPartial(db, clk))
+	e.GET("/partials/heatmap", heatmapPartial(db, clk))
 	e.GET("/history", historyHandler(cfg, db))
-	e.GET("/plans", plansListHandler(cfg, db))
+	e.GET("/search", searchHandler(cfg, db), generalRateLimiter)
+	e.GET("/plans", plansListHandler(cfg, db, clk))
 	e.GET("/plans/create", createPlanForm(cfg, db))
-	e.POST("/plans/create", createPlan(appFS, db), generalRateLimiter)
+	e.POST("/plans/preview", previewPlan(cfg, clk), generalRateLimiter)
+	e.POST("/plans/create", createPlan(db, queue, clk), generalRateLimiter)
 	e.GET("/plans/create-manual", manualPlanForm(cfg))
-	e.POST("/plans/create-manual", createManualPlan(cfg, db), generalRateLimiter)
+	e.POST("/plans/create-manual", createManualPlan(cfg, db, clk), generalRateLimiter)
 	e.POST("/plans/draft/title", updateDraftTitle(), generalRateLimiter)
 	e.POST("/plans/draft/reading", addDraftReading(), generalRateLimiter)
 	e.GET("/plans/draft/reading/:id", getDraftReading())
@@ -214,29 +282,58 @@ func runServer(_ *cobra.Command, _ []string) error {
 	e.DELETE("/plans/draft/reading/:id", deleteDraftReading(), generalRateLimiter)
 	e.DELETE("/plans/draft", deleteDraft(), generalRateLimiter)
 	e.GET("/plans/:id/edit", editPlanForm(cfg, db))
-	e.POST("/plans/:id/edit", editPlan(cfg, db), generalRateLimiter)
+	e.POST("/plans/:id/edit", editPlan(cfg, db, clk), generalRateLimiter)
 	e.POST("/plans/:id/rename", renamePlan(db), generalRateLimiter)
+	e.POST("/plans/:id/pause", pausePlan(db, clk), generalRateLimiter)
+	e.POST("/plans/:id/resume", resumePlan(db, clk), generalRateLimiter)
+	e.POST("/plans/:id/shift", shiftPlan(db), generalRateLimiter)
+	e.GET("/plans/:id/catch-up", catchUpForm(cfg, db, clk))
+	e.POST("/plans/:id/catch-up", catchUpPlan(db, clk), generalRateLimiter)
 	e.DELETE("/plans/:id", deletePlan(db), generalRateLimiter)
 	e.DELETE("/plans/:id/readings/:reading_id", deleteReading(db), generalRateLimiter)
-	e.GET("/account", accountHandler(cfg, db))
-	e.POST("/account/settings", updateSettings(db), generalRateLimiter)
+	e.GET("/account", accountHandler(cfg, db, clk))
+	e.POST("/account/settings", updateSettings(cfg, db, clk, userCache, emailService), generalRateLimiter)
+	if localAuth {
+		e.POST("/account/password", changePassword(cfg, db, clk, userCache), authRateLimiter)
+		e.POST("/account/sessions/:id/revoke", revokeSession(db), generalRateLimiter)
+		e.POST("/account/sessions/revoke-others", revokeOtherSessions(db), authRateLimiter)
+		e.GET("/account/two-factor", twoFactorSetup(cfg))
+		e.POST("/account/two-factor", enableTwoFactor(cfg, db, clk, userCache), authRateLimiter)
+		e.POST("/account/two-factor/disable", disableTwoFactor(cfg, db, clk, userCache), authRateLimiter)
+		e.POST("/account/two-factor/recovery-codes", regenerateRecoveryCodes(cfg, db, clk), authRateLimiter)
+		if webAuthn != nil {
+			e.POST("/account/passkeys/options", passkeyRegistrationOptions(cfg, db, webAuthn), authRateLimiter)
+			e.POST("/account/passkeys", registerPasskey(cfg, db, clk, webAuthn), authRateLimiter)
+			e.POST("/account/passkeys/:id/delete", deletePasskey(db), generalRateLimiter)
+		}
+	}
 	e.POST("/account/test-email", sendTestEmailHandler(cfg), generalRateLimiter)
 
-	e.GET("/notifications/count", notificationCount(db))
-	e.GET("/notifications/dropdown", notificationDropdown(db))
+	e.GET("/notifications/count", notificationCount(db, clk))
+	e.GET("/notifications/dropdown", notificationDropdown(db, clk))
 
 	// JSON API endpoints for React components
-	e.GET("/api/notifications/count", apiNotificationCount(db))
-	e.GET("/api/notifications/readings", apiNotificationReadings(db))
+	e.GET("/api/notifications/count", apiNotificationCount(db, clk))
+	e.GET("/api/notifications/readings", apiNotificationReadings(db, clk))
 	e.GET("/api/plans/:id/status", apiPlanStatus(db))
+	e.GET("/api/search", apiSearch(db), generalRateLimiter)
+	e.GET("/api/history", apiHistory(db))
+	e.GET("/api/stats", apiStats(db, clk))
+	e.GET("/api/heatmap", apiHeatmap(db, clk))
+	e.GET("/api/projections", apiProjections(db, clk))
 	e.PUT("/plans/draft", apiSaveDraft(), generalRateLimiter)
 
 	e.POST("/push/subscribe", saveSubscription(db), generalRateLimiter)
 	e.POST("/push/unsubscribe", removeSubscription(db), generalRateLimiter)
 	e.POST("/push/unsubscribe-all", removeAllSubscriptions(db), generalRateLimiter)
 
-	e.POST("/reading/:id/complete", completeReading(db), generalRateLimiter)
+	e.POST("/reading/:id/complete", completeReading(db, clk), generalRateLimiter)
 	e.POST("/reading/:id/uncomplete", uncompleteReading(db), generalRateLimiter)
+	e.POST("/reading/:id/skip", skipReading(db, clk), generalRateLimiter)
+	e.POST("/reading/:id/unskip", unskipReading(db), generalRateLimiter)
+	e.GET("/reading/:id/journal", journalForm(cfg, db))
+	e.POST("/reading/:id/journal", saveJournal(cfg, db, clk), generalRateLimiter)
+	e.GET("/journal", journalList(cfg, db))
 	e.POST("/reading/:id/update", updateReading(db), generalRateLimiter)
 
 	return e.Start(cfg.Port)
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo-contrib/v5/session"
+	"github.com/labstack/echo/v5"
+	"gorm.io/gorm"
+
+	mw "readwillbe/internal/middleware"
+	"readwillbe/internal/repository"
+)
+
+// revokeSession signs out one of the user's other browsers.
+func revokeSession(db *gorm.DB) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.Redirect(http.StatusFound, "/auth/sign-in")
+		}
+
+		id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			return c.String(http.StatusBadRequest, "Invalid session ID")
+		}
+
+		revoked, err := repository.RevokeSession(db.WithContext(c.Request().Context()), user.ID, uint(id))
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to sign out session")
+		}
+		if !revoked {
+			return c.String(http.StatusNotFound, "Session not found")
+		}
+
+		return c.Redirect(http.StatusFound, "/account?sessions=revoked#sessions")
+	}
+}
+
+// revokeOtherSessions signs out every browser but this one.
+func revokeOtherSessions(db *gorm.DB) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.Redirect(http.StatusFound, "/auth/sign-in")
+		}
+
+		sess, err := session.Get(mw.SessionKey, c)
+		if err != nil || sess.ID == "" {
+			return c.String(http.StatusInternalServerError, "Failed to sign out sessions")
+		}
+		if _, err := repository.RevokeOtherSessions(db.WithContext(c.Request().Context()), user.ID, sess.ID); err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to sign out sessions")
+		}
+
+		return c.Redirect(http.StatusFound, "/account?sessions=revoked#sessions")
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"readwillbe/internal/cache"
+	"readwillbe/internal/clock"
+	mw "readwillbe/internal/middleware"
+	"readwillbe/internal/model"
+	"readwillbe/internal/repository"
+)
+
+func TestAccountSessions(t *testing.T) {
+	db := setupTestDB(t)
+	cfg := model.Config{}
+	clk := clock.Fixed(time.Date(2026, time.May, 1, 8, 0, 0, 0, time.UTC))
+	user := createTestUser(t, db, "devices@example.com", "correctpassword123")
+	other := createTestUser(t, db, "not-devices@example.com", "correctpassword123")
+
+	signIn := func(t *testing.T) *sessionClient {
+		t.Helper()
+		client := newDBSessionClient(db, clk)
+		form := url.Values{"email": {user.Email}, "password": {"correctpassword123"}}
+		rec := client.post(t, signInWithEmailAndPassword(db, cfg, clk), "/auth/sign-in", form, nil)
+		require.Equal(t, http.StatusFound, rec.Code)
+		require.Equal(t, user.ID, client.values(t)[mw.SessionUserIDKey])
+		return client
+	}
+	signedIn := func(t *testing.T, client *sessionClient) bool {
+		t.Helper()
+		return client.values(t)[mw.SessionUserIDKey] == user.ID
+	}
+	list := func(t *testing.T) []model.Session {
+		t.Helper()
+		sessions, err := repository.ListSessions(db, user.ID, clk.Now())
+		require.NoError(t, err)
+		return sessions
+	}
+
+	// current returns the stored session the client's cookie points to.
+	current := func(t *testing.T, client *sessionClient) model.Session {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, cookie := range client.cookies {
+			req.AddCookie(cookie)
+		}
+		sess, err := client.store.Get(req, mw.SessionKey)
+		require.NoError(t, err)
+		row, err := repository.GetSession(db, sess.ID, clk.Now())
+		require.NoError(t, err)
+		return row
+	}
+
+	laptop := signIn(t)
+	phone := signIn(t)
+	require.Len(t, list(t), 2)
+	phoneSession := current(t, phone)
+
+	t.Run("account page lists sessions", func(t *testing.T) {
+		rec := laptop.get(t, accountHandler(cfg, db, clk), "/account", user)
+		require.Equal(t, http.StatusOK, rec.Code)
+		body := rec.Body.String()
+		assert.Contains(t, body, "Signed-In Devices")
+		assert.Contains(t, body, "This device")
+		assert.Contains(t, body, fmt.Sprintf("/account/sessions/%d/revoke", phoneSession.ID))
+		assert.NotContains(t, body, fmt.Sprintf("/account/sessions/%d/revoke", current(t, laptop).ID), "this device is signed out with Sign Out")
+		assert.Contains(t, body, "Sign Out All Other Devices")
+	})
+
+	t.Run("revoke one session", func(t *testing.T) {
+		rec := postFormWithParam(t, revokeSession(db), phoneSession.ID, other)
+		assert.Equal(t, http.StatusNotFound, rec.Code, "another user's session")
+		assert.True(t, signedIn(t, phone))
+
+		rec = postFormWithParam(t, revokeSession(db), phoneSession.ID, user)
+		assert.Equal(t, http.StatusFound, rec.Code)
+		assert.False(t, signedIn(t, phone))
+		assert.True(t, signedIn(t, laptop))
+	})
+
+	t.Run("revoke all others", func(t *testing.T) {
+		tablet := signIn(t)
+		desktop := signIn(t)
+		require.Len(t, list(t), 3)
+
+		rec := laptop.post(t, revokeOtherSessions(db), "/account/sessions/revoke-others", nil, user)
+		assert.Equal(t, http.StatusFound, rec.Code)
+		assert.True(t, signedIn(t, laptop))
+		assert.False(t, signedIn(t, tablet))
+		assert.False(t, signedIn(t, desktop))
+		assert.Len(t, list(t), 1)
+	})
+
+	t.Run("changing the password signs out other devices", func(t *testing.T) {
+		tablet := signIn(t)
+		form := url.Values{"current_password": {"correctpassword123"}, "new_password": {"newpassword12345"}, "confirm_password": {"newpassword12345"}}
+		userCache := cache.NewUserCache(time.Minute, time.Minute)
+
+		rec := laptop.post(t, changePassword(cfg, db, clk, userCache), "/account/password", form, user)
+		require.Equal(t, http.StatusFound, rec.Code)
+		assert.True(t, signedIn(t, laptop))
+		assert.False(t, signedIn(t, tablet))
+	})
+
+	t.Run("signing out ends the session", func(t *testing.T) {
+		rec := laptop.post(t, signOut(), "/auth/sign-out", nil, user)
+		require.Equal(t, http.StatusFound, rec.Code)
+		assert.Empty(t, list(t))
+	})
+}
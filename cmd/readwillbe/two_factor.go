@@ -0,0 +1,304 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/labstack/echo-contrib/v5/session"
+	"github.com/labstack/echo/v5"
+	"github.com/pquerna/otp"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"readwillbe/internal/cache"
+	"readwillbe/internal/clock"
+	mw "readwillbe/internal/middleware"
+	"readwillbe/internal/model"
+	"readwillbe/internal/repository"
+	"readwillbe/internal/service/twofactor"
+	"readwillbe/internal/views"
+)
+
+const (
+	// TwoFactorTimeout is how long after the password is accepted the
+	// second factor must be given.
+	TwoFactorTimeout = 5 * time.Minute
+	// MaxTwoFactorAttempts is how many wrong codes end a sign-in attempt.
+	MaxTwoFactorAttempts = 5
+)
+
+var totpCodeRegex = regexp.MustCompile(`^\s*[0-9]{6}\s*$`)
+
+var (
+	errInvalidCode     = fmt.Errorf("invalid code")
+	errTooManyAttempts = fmt.Errorf("too many invalid codes, please sign in again")
+)
+
+// checkSecondFactor accepts a current TOTP code not used before, or one of
+// the user's unused recovery codes, using it up.
+func checkSecondFactor(tx *gorm.DB, user model.User, code string, now time.Time) (bool, error) {
+	if !user.TwoFactorEnabled() {
+		return false, nil
+	}
+	if totpCodeRegex.MatchString(code) {
+		step, ok := twofactor.Validate(user.TOTPSecret, code, now)
+		if !ok {
+			return false, nil
+		}
+		return repository.UseTOTPStep(tx, user.ID, step)
+	}
+	return repository.UseRecoveryCode(tx, user.ID, twofactor.NormalizeRecoveryCode(code), now)
+}
+
+// startTwoFactor holds userID on the session as waiting for a second factor,
+// without signing them in.
+func startTwoFactor(c *echo.Context, cfg model.Config, userID uint, now time.Time) error {
+	sess, err := session.Get(mw.SessionKey, c)
+	if err != nil {
+		return err
+	}
+	for key := range sess.Values {
+		delete(sess.Values, key)
+	}
+	if err := mw.RenewSessionID(c.Request(), sess); err != nil {
+		return err
+	}
+	sess.Options = mw.GetSecureSessionOptions(cfg)
+	sess.Values[mw.SessionPendingUserIDKey] = userID
+	sess.Values[mw.SessionPendingAtKey] = now.Unix()
+	sess.Values[mw.SessionPendingAttemptsKey] = 0
+
+	return sess.Save(c.Request(), c.Response())
+}
+
+// pendingTwoFactorUser returns the user waiting for a second factor on the
+// session, or false if there is none or it has timed out.
+func pendingTwoFactorUser(c *echo.Context, now time.Time) (uint, bool) {
+	sess, err := session.Get(mw.SessionKey, c)
+	if err != nil {
+		return 0, false
+	}
+	userID, ok := sess.Values[mw.SessionPendingUserIDKey].(uint)
+	if !ok {
+		return 0, false
+	}
+	at, ok := sess.Values[mw.SessionPendingAtKey].(int64)
+	if !ok || now.Sub(time.Unix(at, 0)) > TwoFactorTimeout {
+		return 0, false
+	}
+	return userID, true
+}
+
+// failTwoFactorAttempt counts a wrong second factor against the pending
+// sign-in, abandoning it once MaxTwoFactorAttempts is reached.
+func failTwoFactorAttempt(c *echo.Context) (locked bool, err error) {
+	sess, err := session.Get(mw.SessionKey, c)
+	if err != nil {
+		return false, err
+	}
+	attempts, _ := sess.Values[mw.SessionPendingAttemptsKey].(int)
+	attempts++
+	sess.Values[mw.SessionPendingAttemptsKey] = attempts
+	if attempts >= MaxTwoFactorAttempts {
+		for key := range sess.Values {
+			delete(sess.Values, key)
+		}
+	}
+	return attempts >= MaxTwoFactorAttempts, sess.Save(c.Request(), c.Response())
+}
+
+func twoFactorForm(cfg model.Config, db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		userID, ok := pendingTwoFactorUser(c, clk.Now())
+		if !ok {
+			return c.Redirect(http.StatusFound, "/auth/sign-in")
+		}
+		csrf, _ := c.Get("csrf").(string)
+		return render(c, 200, views.TwoFactorPage(cfg, csrf, hasPasskeys(db.WithContext(c.Request().Context()), userID), nil))
+	}
+}
+
+// verifyTwoFactor completes a sign-in held by startTwoFactor.
+func verifyTwoFactor(cfg model.Config, db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		csrf, _ := c.Get("csrf").(string)
+		now := clk.Now()
+
+		userID, ok := pendingTwoFactorUser(c, now)
+		if !ok {
+			return render(c, 422, views.SignInPage(cfg, csrf, fmt.Errorf("your sign-in timed out, please try again")))
+		}
+
+		tx := db.WithContext(c.Request().Context())
+		user, err := repository.GetUserByID(tx, userID)
+		if err != nil {
+			return render(c, 422, views.SignInPage(cfg, csrf, fmt.Errorf("internal server error")))
+		}
+
+		passed, err := checkSecondFactor(tx, user, c.FormValue("code"), now)
+		if err != nil {
+			logrus.Errorf("Failed to check second factor for user %d: %v", user.ID, err)
+			return render(c, 422, views.TwoFactorPage(cfg, csrf, hasPasskeys(tx, user.ID), fmt.Errorf("internal server error")))
+		}
+		if !passed {
+			locked, err := failTwoFactorAttempt(c)
+			if err != nil {
+				return render(c, 422, views.SignInPage(cfg, csrf, fmt.Errorf("internal server error")))
+			}
+			if locked {
+				return render(c, 422, views.SignInPage(cfg, csrf, errTooManyAttempts))
+			}
+			return render(c, 422, views.TwoFactorPage(cfg, csrf, hasPasskeys(tx, user.ID), errInvalidCode))
+		}
+
+		if err := signInSession(c, cfg, user.ID); err != nil {
+			return render(c, 422, views.SignInPage(cfg, csrf, fmt.Errorf("internal server error")))
+		}
+		return c.Redirect(http.StatusFound, "/")
+	}
+}
+
+// twoFactorSetupKey returns the TOTP key being set up on the session,
+// generating and storing a new one if there is none yet.
+func twoFactorSetupKey(c *echo.Context, cfg model.Config, user model.User) (*otp.Key, error) {
+	sess, err := session.Get(mw.SessionKey, c)
+	if err != nil {
+		return nil, err
+	}
+	if uri, ok := sess.Values[mw.SessionTOTPSetupKey].(string); ok {
+		if key, err := otp.NewKeyFromURL(uri); err == nil {
+			return key, nil
+		}
+	}
+
+	key, err := twofactor.NewKey(user.Email)
+	if err != nil {
+		return nil, err
+	}
+	sess.Options = mw.GetSecureSessionOptions(cfg)
+	sess.Values[mw.SessionTOTPSetupKey] = key.URL()
+	return key, sess.Save(c.Request(), c.Response())
+}
+
+// renderTwoFactorSetup shows the setup page for key, with err if a code was
+// rejected.
+func renderTwoFactorSetup(c *echo.Context, cfg model.Config, user model.User, key *otp.Key, status int, err error) error {
+	qr, qrErr := twofactor.QRCode(key)
+	if qrErr != nil {
+		return c.String(http.StatusInternalServerError, "Failed to render QR code")
+	}
+	setup := views.TwoFactorSetupProps{QRCode: qr, URI: key.URL(), Secret: key.Secret()}
+	return render(c, status, views.TwoFactorSetup(cfg, &user, setup, err))
+}
+
+func twoFactorSetup(cfg model.Config) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.Redirect(http.StatusFound, "/auth/sign-in")
+		}
+		if user.TwoFactorEnabled() {
+			return c.Redirect(http.StatusFound, "/account#two-factor")
+		}
+
+		key, err := twoFactorSetupKey(c, cfg, user)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to set up two-factor authentication")
+		}
+		return renderTwoFactorSetup(c, cfg, user, key, 200, nil)
+	}
+}
+
+// enableTwoFactor turns on two-factor sign-in once the user confirms a code
+// from the key being set up, and shows their first recovery codes.
+func enableTwoFactor(cfg model.Config, db *gorm.DB, clk clock.Clock, userCache *cache.UserCache) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.Redirect(http.StatusFound, "/auth/sign-in")
+		}
+		if user.TwoFactorEnabled() {
+			return c.Redirect(http.StatusFound, "/account#two-factor")
+		}
+
+		key, err := twoFactorSetupKey(c, cfg, user)
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to set up two-factor authentication")
+		}
+		step, valid := twofactor.Validate(key.Secret(), c.FormValue("code"), clk.Now())
+		if !valid {
+			return renderTwoFactorSetup(c, cfg, user, key, 422, errInvalidCode)
+		}
+
+		codes, err := twofactor.NewRecoveryCodes()
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to generate recovery codes")
+		}
+		if err := repository.EnableTOTP(db.WithContext(c.Request().Context()), user.ID, key.Secret(), step, codes, clk.Now()); err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to enable two-factor authentication")
+		}
+		userCache.Invalidate(user.ID)
+
+		if sess, err := session.Get(mw.SessionKey, c); err == nil {
+			delete(sess.Values, mw.SessionTOTPSetupKey)
+			_ = sess.Save(c.Request(), c.Response())
+		}
+
+		return render(c, 200, views.RecoveryCodes(cfg, &user, codes))
+	}
+}
+
+func disableTwoFactor(cfg model.Config, db *gorm.DB, clk clock.Clock, userCache *cache.UserCache) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.Redirect(http.StatusFound, "/auth/sign-in")
+		}
+
+		tx := db.WithContext(c.Request().Context())
+		passed, err := checkSecondFactor(tx, user, c.FormValue("code"), clk.Now())
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to check code")
+		}
+		if !passed {
+			return renderAccount(c, cfg, db, clk, user, 422, views.AccountProps{TwoFactorErr: errInvalidCode})
+		}
+
+		if err := repository.DisableTOTP(tx, user.ID); err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to disable two-factor authentication")
+		}
+		userCache.Invalidate(user.ID)
+
+		return c.Redirect(http.StatusFound, "/account?two_factor=disabled#two-factor")
+	}
+}
+
+func regenerateRecoveryCodes(cfg model.Config, db *gorm.DB, clk clock.Clock) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.Redirect(http.StatusFound, "/auth/sign-in")
+		}
+
+		tx := db.WithContext(c.Request().Context())
+		passed, err := checkSecondFactor(tx, user, c.FormValue("code"), clk.Now())
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to check code")
+		}
+		if !passed {
+			return renderAccount(c, cfg, db, clk, user, 422, views.AccountProps{TwoFactorErr: errInvalidCode})
+		}
+
+		codes, err := twofactor.NewRecoveryCodes()
+		if err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to generate recovery codes")
+		}
+		if err := repository.ReplaceRecoveryCodes(tx, user.ID, codes, clk.Now()); err != nil {
+			return c.String(http.StatusInternalServerError, "Failed to save recovery codes")
+		}
+
+		return render(c, 200, views.RecoveryCodes(cfg, &user, codes))
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"readwillbe/internal/cache"
+	"readwillbe/internal/clock"
+	mw "readwillbe/internal/middleware"
+	"readwillbe/internal/model"
+	"readwillbe/internal/repository"
+	"readwillbe/internal/service/twofactor"
+
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo-contrib/v5/session"
+	"github.com/labstack/echo/v5"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// sessionClient runs handlers behind the session middleware, carrying the
+// session cookie from one request to the next like a browser would.
+type sessionClient struct {
+	store   sessions.Store
+	cookies []*http.Cookie
+}
+
+func newSessionClient() *sessionClient {
+	return &sessionClient{store: sessions.NewCookieStore([]byte("0123456789abcdef0123456789abcdef"))}
+}
+
+// newDBSessionClient is a sessionClient whose sessions are kept in db, as
+// the server keeps them.
+func newDBSessionClient(db *gorm.DB, clk clock.Clock) *sessionClient {
+	cfg := model.Config{CookieSecret: []byte("0123456789abcdef0123456789abcdef")}
+	return &sessionClient{store: mw.NewDBStore(db, cfg, clk)}
+}
+
+func (s *sessionClient) post(t *testing.T, handler echo.HandlerFunc, target string, form url.Values, user *model.User) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+	return s.do(t, handler, req, user)
+}
+
+func (s *sessionClient) get(t *testing.T, handler echo.HandlerFunc, target string, user *model.User) *httptest.ResponseRecorder {
+	t.Helper()
+	return s.do(t, handler, httptest.NewRequest(http.MethodGet, target, nil), user)
+}
+
+func (s *sessionClient) do(t *testing.T, handler echo.HandlerFunc, req *http.Request, user *model.User) *httptest.ResponseRecorder {
+	t.Helper()
+	for _, cookie := range s.cookies {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	if user != nil {
+		c.Set(mw.UserKey, *user)
+	}
+	require.NoError(t, session.Middleware(s.store)(handler)(c))
+	// A handler may save the session more than once; like a browser, keep
+	// the last cookie set under each name.
+	for _, cookie := range rec.Result().Cookies() {
+		s.setCookie(cookie)
+	}
+	return rec
+}
+
+func (s *sessionClient) setCookie(cookie *http.Cookie) {
+	for i, existing := range s.cookies {
+		if existing.Name == cookie.Name {
+			if cookie.MaxAge < 0 {
+				s.cookies = append(s.cookies[:i], s.cookies[i+1:]...)
+			} else {
+				s.cookies[i] = cookie
+			}
+			return
+		}
+	}
+	if cookie.MaxAge >= 0 {
+		s.cookies = append(s.cookies, cookie)
+	}
+}
+
+// values decodes the current session.
+func (s *sessionClient) values(t *testing.T) map[interface{}]interface{} {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range s.cookies {
+		req.AddCookie(cookie)
+	}
+	sess, err := s.store.Get(req, mw.SessionKey)
+	require.NoError(t, err)
+	return sess.Values
+}
+
+func enableTestTwoFactor(t *testing.T, db *gorm.DB, user *model.User, now time.Time) (string, []string) {
+	t.Helper()
+	key, err := twofactor.NewKey(user.Email)
+	require.NoError(t, err)
+	codes, err := twofactor.NewRecoveryCodes()
+	require.NoError(t, err)
+	require.NoError(t, repository.EnableTOTP(db, user.ID, key.Secret(), 0, codes, now))
+	require.NoError(t, db.First(user, user.ID).Error)
+	return key.Secret(), codes
+}
+
+func TestTwoFactorSignIn(t *testing.T) {
+	db := setupTestDB(t)
+	cfg := model.Config{}
+	clk := clock.Fixed(time.Date(2026, time.May, 1, 8, 0, 0, 0, time.UTC))
+	user := createTestUser(t, db, "totp@example.com", "correctpassword123")
+	secret, recovery := enableTestTwoFactor(t, db, user, clk.Now())
+
+	signInForm := url.Values{"email": {user.Email}, "password": {"correctpassword123"}}
+	code, err := totp.GenerateCode(secret, clk.Now())
+	require.NoError(t, err)
+
+	t.Run("password alone does not sign in", func(t *testing.T) {
+		client := newSessionClient()
+		rec := client.post(t, signInWithEmailAndPassword(db, cfg, clk), "/auth/sign-in", signInForm, nil)
+		assert.Equal(t, http.StatusFound, rec.Code)
+		assert.Equal(t, "/auth/two-factor", rec.Header().Get("Location"))
+
+		values := client.values(t)
+		assert.NotContains(t, values, mw.SessionUserIDKey)
+		assert.Equal(t, user.ID, values[mw.SessionPendingUserIDKey])
+
+		rec = client.get(t, twoFactorForm(cfg, db, clk), "/auth/two-factor", nil)
+		assert.Equal(t, 200, rec.Code)
+	})
+
+	t.Run("no pending sign-in", func(t *testing.T) {
+		client := newSessionClient()
+		rec := client.get(t, twoFactorForm(cfg, db, clk), "/auth/two-factor", nil)
+		assert.Equal(t, http.StatusFound, rec.Code)
+		assert.Equal(t, "/auth/sign-in", rec.Header().Get("Location"))
+
+		rec = client.post(t, verifyTwoFactor(cfg, db, clk), "/auth/two-factor", url.Values{"code": {code}}, nil)
+		assert.Equal(t, 422, rec.Code)
+		assert.NotContains(t, client.values(t), mw.SessionUserIDKey)
+	})
+
+	t.Run("totp code", func(t *testing.T) {
+		client := newSessionClient()
+		client.post(t, signInWithEmailAndPassword(db, cfg, clk), "/auth/sign-in", signInForm, nil)
+
+		rec := client.post(t, verifyTwoFactor(cfg, db, clk), "/auth/two-factor", url.Values{"code": {"000000"}}, nil)
+		assert.Equal(t, 422, rec.Code)
+		assert.NotContains(t, client.values(t), mw.SessionUserIDKey)
+
+		rec = client.post(t, verifyTwoFactor(cfg, db, clk), "/auth/two-factor", url.Values{"code": {code}}, nil)
+		assert.Equal(t, http.StatusFound, rec.Code)
+		assert.Equal(t, "/", rec.Header().Get("Location"))
+
+		values := client.values(t)
+		assert.Equal(t, user.ID, values[mw.SessionUserIDKey])
+		assert.NotContains(t, values, mw.SessionPendingUserIDKey)
+	})
+
+	t.Run("totp code cannot be replayed", func(t *testing.T) {
+		client := newSessionClient()
+		client.post(t, signInWithEmailAndPassword(db, cfg, clk), "/auth/sign-in", signInForm, nil)
+
+		rec := client.post(t, verifyTwoFactor(cfg, db, clk), "/auth/two-factor", url.Values{"code": {code}}, nil)
+		assert.Equal(t, 422, rec.Code)
+		assert.NotContains(t, client.values(t), mw.SessionUserIDKey)
+	})
+
+	t.Run("recovery code", func(t *testing.T) {
+		client := newSessionClient()
+		client.post(t, signInWithEmailAndPassword(db, cfg, clk), "/auth/sign-in", signInForm, nil)
+
+		typed := strings.ToUpper(strings.ReplaceAll(recovery[0], "-", " "))
+		rec := client.post(t, verifyTwoFactor(cfg, db, clk), "/auth/two-factor", url.Values{"code": {typed}}, nil)
+		assert.Equal(t, http.StatusFound, rec.Code)
+		assert.Equal(t, user.ID, client.values(t)[mw.SessionUserIDKey])
+
+		client = newSessionClient()
+		client.post(t, signInWithEmailAndPassword(db, cfg, clk), "/auth/sign-in", signInForm, nil)
+		rec = client.post(t, verifyTwoFactor(cfg, db, clk), "/auth/two-factor", url.Values{"code": {recovery[0]}}, nil)
+		assert.Equal(t, 422, rec.Code, "recovery codes are single use")
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		client := newSessionClient()
+		client.post(t, signInWithEmailAndPassword(db, cfg, clk), "/auth/sign-in", signInForm, nil)
+
+		later := clock.Fixed(clk.Now().Add(TwoFactorTimeout + time.Second))
+		rec := client.post(t, verifyTwoFactor(cfg, db, later), "/auth/two-factor", url.Values{"code": {recovery[1]}}, nil)
+		assert.Equal(t, 422, rec.Code)
+		assert.Contains(t, rec.Body.String(), "timed out")
+		assert.NotContains(t, client.values(t), mw.SessionUserIDKey)
+	})
+
+	t.Run("too many attempts", func(t *testing.T) {
+		client := newSessionClient()
+		client.post(t, signInWithEmailAndPassword(db, cfg, clk), "/auth/sign-in", signInForm, nil)
+
+		for i := 0; i < MaxTwoFactorAttempts; i++ {
+			rec := client.post(t, verifyTwoFactor(cfg, db, clk), "/auth/two-factor", url.Values{"code": {"000000"}}, nil)
+			assert.Equal(t, 422, rec.Code)
+		}
+		assert.NotContains(t, client.values(t), mw.SessionPendingUserIDKey)
+
+		rec := client.post(t, verifyTwoFactor(cfg, db, clk), "/auth/two-factor", url.Values{"code": {recovery[1]}}, nil)
+		assert.Equal(t, 422, rec.Code)
+		assert.NotContains(t, client.values(t), mw.SessionUserIDKey)
+	})
+}
+
+func TestTwoFactorEnrolment(t *testing.T) {
+	db := setupTestDB(t)
+	cfg := model.Config{}
+	clk := clock.Fixed(time.Date(2026, time.May, 1, 8, 0, 0, 0, time.UTC))
+	user := createTestUser(t, db, "enrol@example.com", "correctpassword123")
+	userCache := cache.NewUserCache(time.Minute, time.Minute)
+	client := newSessionClient()
+
+	rec := client.get(t, twoFactorSetup(cfg), "/account/two-factor", user)
+	require.Equal(t, 200, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, "data:image/png;base64,")
+	assert.Contains(t, body, "otpauth://totp/")
+
+	uri, ok := client.values(t)[mw.SessionTOTPSetupKey].(string)
+	require.True(t, ok)
+	setupURL, err := url.Parse(uri)
+	require.NoError(t, err)
+	secret := setupURL.Query().Get("secret")
+	require.NotEmpty(t, secret)
+
+	rec = client.get(t, twoFactorSetup(cfg), "/account/two-factor", user)
+	assert.Contains(t, rec.Body.String(), secret, "reloading the page keeps the same key")
+
+	rec = client.post(t, enableTwoFactor(cfg, db, clk, userCache), "/account/two-factor", url.Values{"code": {"000000"}}, user)
+	assert.Equal(t, 422, rec.Code)
+
+	code, err := totp.GenerateCode(secret, clk.Now())
+	require.NoError(t, err)
+	userCache.Set(*user)
+	rec = client.post(t, enableTwoFactor(cfg, db, clk, userCache), "/account/two-factor", url.Values{"code": {code}}, user)
+	require.Equal(t, 200, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Recovery Codes")
+	assert.NotContains(t, client.values(t), mw.SessionTOTPSetupKey)
+	_, found := userCache.Get(user.ID)
+	assert.False(t, found)
+
+	require.NoError(t, db.First(user, user.ID).Error)
+	assert.True(t, user.TwoFactorEnabled())
+	assert.Equal(t, secret, user.TOTPSecret)
+	left, err := repository.CountRecoveryCodes(db, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(twofactor.RecoveryCodeCount), left)
+
+	ok, err = repository.UseTOTPStep(db, user.ID, clk.Now().Unix()/twofactor.Period)
+	require.NoError(t, err)
+	assert.False(t, ok, "the code used to turn it on cannot be used to sign in")
+}
+
+func TestTwoFactorManage(t *testing.T) {
+	db := setupTestDB(t)
+	cfg := model.Config{}
+	clk := clock.Fixed(time.Date(2026, time.May, 1, 8, 0, 0, 0, time.UTC))
+	user := createTestUser(t, db, "manage@example.com", "correctpassword123")
+	userCache := cache.NewUserCache(time.Minute, time.Minute)
+	secret, recovery := enableTestTwoFactor(t, db, user, clk.Now())
+
+	t.Run("account page", func(t *testing.T) {
+		rec := postForm(t, accountHandler(cfg, db, clk), "/account", nil, user)
+		assert.Equal(t, 200, rec.Code)
+		assert.Contains(t, rec.Body.String(), "10 recovery codes")
+	})
+
+	t.Run("new recovery codes", func(t *testing.T) {
+		rec := postForm(t, regenerateRecoveryCodes(cfg, db, clk), "/account/two-factor/recovery-codes", url.Values{"code": {"000000"}}, user)
+		assert.Equal(t, 422, rec.Code)
+
+		rec = postForm(t, regenerateRecoveryCodes(cfg, db, clk), "/account/two-factor/recovery-codes", url.Values{"code": {recovery[0]}}, user)
+		assert.Equal(t, 200, rec.Code)
+
+		ok, err := repository.UseRecoveryCode(db, user.ID, recovery[1], clk.Now())
+		require.NoError(t, err)
+		assert.False(t, ok, "the old codes are replaced")
+	})
+
+	t.Run("turn off", func(t *testing.T) {
+		rec := postForm(t, disableTwoFactor(cfg, db, clk, userCache), "/account/two-factor/disable", url.Values{"code": {recovery[2]}}, user)
+		assert.Equal(t, 422, rec.Code)
+
+		code, err := totp.GenerateCode(secret, clk.Now())
+		require.NoError(t, err)
+		rec = postForm(t, disableTwoFactor(cfg, db, clk, userCache), "/account/two-factor/disable", url.Values{"code": {code}}, user)
+		assert.Equal(t, http.StatusFound, rec.Code)
+		assert.Equal(t, "/account?two_factor=disabled#two-factor", rec.Header().Get("Location"))
+
+		var disabled model.User
+		require.NoError(t, db.First(&disabled, user.ID).Error)
+		assert.False(t, disabled.TwoFactorEnabled())
+	})
+}
@@ -9,12 +9,15 @@ import (
 	"github.com/labstack/echo-contrib/v5/session"
 	"github.com/labstack/echo/v5"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 
+	"readwillbe/internal/clock"
 	mw "readwillbe/internal/middleware"
 	"readwillbe/internal/model"
 	"readwillbe/internal/repository"
+	emailservice "readwillbe/internal/service/email"
 	"readwillbe/internal/views"
 )
 
@@ -32,12 +35,15 @@ var dummyHash = []byte("$2a$12$LQv3c1yqBWVHxkd0LHAkCOYz6TtxMQJqhN8/X4.qfTJ.l5NmY
 
 func signUp(cfg model.Config) echo.HandlerFunc {
 	return func(c *echo.Context) error {
+		if _, ok := mw.GetSessionUser(c); ok {
+			return c.Redirect(http.StatusFound, "/")
+		}
 		csrf, _ := c.Get("csrf").(string)
 		return render(c, 200, views.SignUpPage(cfg, csrf, nil))
 	}
 }
 
-func signUpWithEmailAndPassword(db *gorm.DB, cfg model.Config) echo.HandlerFunc {
+func signUpWithEmailAndPassword(db *gorm.DB, cfg model.Config, clk clock.Clock, sender emailservice.Service) echo.HandlerFunc {
 	return func(c *echo.Context) error {
 		name := c.FormValue("name")
 		email := c.FormValue("email")
@@ -58,8 +64,8 @@ func signUpWithEmailAndPassword(db *gorm.DB, cfg model.Config) echo.HandlerFunc
 		}
 		email = parsedEmail.Address
 
-		if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
-			return render(c, 422, views.SignUpPage(cfg, csrf, fmt.Errorf("password must be between %d and %d characters", MinPasswordLength, MaxPasswordLength)))
+		if err := validatePassword(password); err != nil {
+			return render(c, 422, views.SignUpPage(cfg, csrf, err))
 		}
 
 		if repository.UserExists(email, db) {
@@ -83,10 +89,17 @@ func signUpWithEmailAndPassword(db *gorm.DB, cfg model.Config) echo.HandlerFunc
 			return render(c, 422, views.SignUpPage(cfg, csrf, wrappedErr))
 		}
 
+		if err := sendEmailVerification(cfg, db, clk, sender, user, user.Email); err != nil {
+			logrus.Errorf("Failed to issue email verification for user %d: %v", user.ID, err)
+		}
+
 		sess, err := session.Get(mw.SessionKey, c)
 		if err != nil {
 			return render(c, 422, views.SignUpPage(cfg, csrf, fmt.Errorf("internal server error")))
 		}
+		if err := mw.RenewSessionID(c.Request(), sess); err != nil {
+			return render(c, 422, views.SignUpPage(cfg, csrf, fmt.Errorf("internal server error")))
+		}
 		sess.Options = mw.GetSecureSessionOptions(cfg)
 
 		sess.Values[mw.SessionUserIDKey] = user.ID
@@ -102,12 +115,18 @@ func signUpWithEmailAndPassword(db *gorm.DB, cfg model.Config) echo.HandlerFunc
 
 func signIn(cfg model.Config) echo.HandlerFunc {
 	return func(c *echo.Context) error {
+		if _, ok := mw.GetSessionUser(c); ok {
+			return c.Redirect(http.StatusFound, "/")
+		}
+		if cfg.ProxyAuthEnabled() {
+			return c.String(http.StatusUnauthorized, "Sign in through the reverse proxy in front of ReadWillBe")
+		}
 		csrf, _ := c.Get("csrf").(string)
 		return render(c, 200, views.SignInPage(cfg, csrf, nil))
 	}
 }
 
-func signInWithEmailAndPassword(db *gorm.DB, cfg model.Config) echo.HandlerFunc {
+func signInWithEmailAndPassword(db *gorm.DB, cfg model.Config, clk clock.Clock) echo.HandlerFunc {
 	return func(c *echo.Context) error {
 		email := c.FormValue("email")
 		password := c.FormValue("password")
@@ -135,19 +154,14 @@ func signInWithEmailAndPassword(db *gorm.DB, cfg model.Config) echo.HandlerFunc
 			return render(c, 422, views.SignInPage(cfg, csrf, fmt.Errorf("invalid email or password")))
 		}
 
-		// Clear existing session values to prevent session fixation
-		sess, err := session.Get(mw.SessionKey, c)
-		if err != nil {
-			return render(c, 422, views.SignInPage(cfg, csrf, fmt.Errorf("internal server error")))
-		}
-		for key := range sess.Values {
-			delete(sess.Values, key)
+		if user.TwoFactorEnabled() {
+			if err := startTwoFactor(c, cfg, user.ID, clk.Now()); err != nil {
+				return render(c, 422, views.SignInPage(cfg, csrf, errors.Wrap(err, "Internal server error")))
+			}
+			return c.Redirect(http.StatusFound, "/auth/two-factor")
 		}
-		sess.Options = mw.GetSecureSessionOptions(cfg)
-		sess.Values[mw.SessionUserIDKey] = user.ID
 
-		err = sess.Save(c.Request(), c.Response())
-		if err != nil {
+		if err := signInSession(c, cfg, user.ID); err != nil {
 			return render(c, 422, views.SignInPage(cfg, csrf, errors.Wrap(err, "Internal server error")))
 		}
 
@@ -155,6 +169,25 @@ func signInWithEmailAndPassword(db *gorm.DB, cfg model.Config) echo.HandlerFunc
 	}
 }
 
+// signInSession signs userID in on a fresh session.
+func signInSession(c *echo.Context, cfg model.Config, userID uint) error {
+	// Clear existing session values to prevent session fixation
+	sess, err := session.Get(mw.SessionKey, c)
+	if err != nil {
+		return err
+	}
+	for key := range sess.Values {
+		delete(sess.Values, key)
+	}
+	if err := mw.RenewSessionID(c.Request(), sess); err != nil {
+		return err
+	}
+	sess.Options = mw.GetSecureSessionOptions(cfg)
+	sess.Values[mw.SessionUserIDKey] = userID
+
+	return sess.Save(c.Request(), c.Response())
+}
+
 func signOut() echo.HandlerFunc {
 	return func(c *echo.Context) error {
 		sess, _ := session.Get("session", c)
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"testing"
 
+	"readwillbe/internal/clock"
 	"readwillbe/internal/model"
 	"readwillbe/internal/repository"
 
@@ -65,7 +66,7 @@ func TestSignUpWithEmailAndPassword(t *testing.T) {
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 
-		handler := signUpWithEmailAndPassword(db, cfg)
+		handler := signUpWithEmailAndPassword(db, cfg, clock.System, nil)
 		err := handler(c)
 		require.NoError(t, err)
 
@@ -92,7 +93,7 @@ func TestSignUpWithEmailAndPassword(t *testing.T) {
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 
-		handler := signUpWithEmailAndPassword(db, cfg)
+		handler := signUpWithEmailAndPassword(db, cfg, clock.System, nil)
 		err := handler(c)
 		require.NoError(t, err)
 		assert.Equal(t, 422, rec.Code)
@@ -110,7 +111,7 @@ func TestSignUpWithEmailAndPassword(t *testing.T) {
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 
-		handler := signUpWithEmailAndPassword(db, cfg)
+		handler := signUpWithEmailAndPassword(db, cfg, clock.System, nil)
 		err := handler(c)
 		require.NoError(t, err)
 		assert.Equal(t, 422, rec.Code)
@@ -128,7 +129,7 @@ func TestSignUpWithEmailAndPassword(t *testing.T) {
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 
-		handler := signUpWithEmailAndPassword(db, cfg)
+		handler := signUpWithEmailAndPassword(db, cfg, clock.System, nil)
 		err := handler(c)
 		require.NoError(t, err)
 		assert.Equal(t, 422, rec.Code)
@@ -146,7 +147,7 @@ func TestSignUpWithEmailAndPassword(t *testing.T) {
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 
-		handler := signUpWithEmailAndPassword(db, cfg)
+		handler := signUpWithEmailAndPassword(db, cfg, clock.System, nil)
 		err := handler(c)
 		require.NoError(t, err)
 		assert.Equal(t, 422, rec.Code)
@@ -169,7 +170,7 @@ func TestSignInWithEmailAndPassword(t *testing.T) {
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 
-		handler := signInWithEmailAndPassword(db, cfg)
+		handler := signInWithEmailAndPassword(db, cfg, clock.System)
 		err := handler(c)
 		require.NoError(t, err)
 	})
@@ -185,7 +186,7 @@ func TestSignInWithEmailAndPassword(t *testing.T) {
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 
-		handler := signInWithEmailAndPassword(db, cfg)
+		handler := signInWithEmailAndPassword(db, cfg, clock.System)
 		err := handler(c)
 		require.NoError(t, err)
 		assert.Equal(t, 422, rec.Code)
@@ -202,7 +203,7 @@ func TestSignInWithEmailAndPassword(t *testing.T) {
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 
-		handler := signInWithEmailAndPassword(db, cfg)
+		handler := signInWithEmailAndPassword(db, cfg, clock.System)
 		err := handler(c)
 		require.NoError(t, err)
 		assert.Equal(t, 422, rec.Code)
@@ -219,7 +220,7 @@ func TestSignInWithEmailAndPassword(t *testing.T) {
 		rec := httptest.NewRecorder()
 		c := e.NewContext(req, rec)
 
-		handler := signInWithEmailAndPassword(db, cfg)
+		handler := signInWithEmailAndPassword(db, cfg, clock.System)
 		err := handler(c)
 		require.NoError(t, err)
 		assert.Equal(t, 422, rec.Code)
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v5"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"readwillbe/internal/cache"
+	"readwillbe/internal/clock"
+	mw "readwillbe/internal/middleware"
+	"readwillbe/internal/model"
+	"readwillbe/internal/repository"
+	emailservice "readwillbe/internal/service/email"
+	"readwillbe/internal/views"
+)
+
+// MaxVerificationEmailsPerHour caps the verification emails sent for one
+// account.
+const MaxVerificationEmailsPerHour = 3
+
+// sendEmailVerification issues a verification token for address and emails
+// it, unless the account has already had MaxVerificationEmailsPerHour of
+// them. It does nothing when email is not configured.
+func sendEmailVerification(cfg model.Config, tx *gorm.DB, clk clock.Clock, sender emailservice.Service, user model.User, address string) error {
+	if sender == nil {
+		return nil
+	}
+
+	now := clk.Now()
+	recent, err := repository.CountEmailVerifications(tx, user.ID, now.Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	if recent >= MaxVerificationEmailsPerHour {
+		logrus.Warnf("Not sending email verification for user %d: %d sent in the last hour", user.ID, recent)
+		return nil
+	}
+
+	token, err := repository.CreateEmailVerification(tx, user.ID, address, now)
+	if err != nil {
+		return err
+	}
+	verifyURL := fmt.Sprintf("https://%s/auth/verify-email?token=%s", cfg.Hostname, url.QueryEscape(token))
+
+	go func() {
+		if err := sender.SendEmailVerification(user, address, verifyURL); err != nil {
+			logrus.Errorf("Failed to send verification email: %v", err)
+		}
+	}()
+	return nil
+}
+
+// verifyEmail follows a link from a verification email. It works signed out
+// too, since the link may be opened on another device.
+func verifyEmail(cfg model.Config, db *gorm.DB, clk clock.Clock, userCache *cache.UserCache) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		var sessionUser *model.User
+		if user, ok := mw.GetSessionUser(c); ok {
+			sessionUser = &user
+		}
+
+		userID, err := repository.VerifyEmail(db.WithContext(c.Request().Context()), c.QueryParam("token"), clk.Now())
+		if err != nil {
+			if !errors.Is(err, repository.ErrInvalidVerificationToken) {
+				return c.String(http.StatusInternalServerError, "Failed to verify email")
+			}
+			return render(c, 400, views.VerifyEmailPage(cfg, sessionUser, err))
+		}
+		userCache.Invalidate(userID)
+
+		return render(c, 200, views.VerifyEmailPage(cfg, sessionUser, nil))
+	}
+}
+
+// resendVerification emails a new verification link for the signed-in
+// user's primary address, or their notification address if the address
+// form value is "notification".
+func resendVerification(cfg model.Config, db *gorm.DB, clk clock.Clock, sender emailservice.Service) echo.HandlerFunc {
+	return func(c *echo.Context) error {
+		if sender == nil {
+			return c.String(http.StatusServiceUnavailable, "Email not configured")
+		}
+
+		user, ok := mw.GetSessionUser(c)
+		if !ok {
+			return c.Redirect(http.StatusFound, "/auth/sign-in")
+		}
+
+		address, verified := user.Email, user.EmailVerified
+		if c.FormValue("address") == "notification" && user.NotificationEmail != "" {
+			address, verified = user.NotificationEmail, user.NotificationEmailVerified
+		}
+		if verified {
+			return c.Redirect(http.StatusFound, "/account")
+		}
+
+		if err := sendEmailVerification(cfg, db.WithContext(c.Request().Context()), clk, sender, user, address); err != nil {
+			logrus.Errorf("Failed to issue email verification for user %d: %v", user.ID, err)
+			return c.String(http.StatusInternalServerError, "Failed to send verification email")
+		}
+
+		return c.Redirect(http.StatusFound, "/account?verification=sent")
+	}
+}
+
+// notificationEmailVerified decides whether a newly saved notification
+// address needs confirming. Clearing it, or setting it to the user's own
+// primary address, carries over what is already known.
+func notificationEmailVerified(user model.User, address string) bool {
+	switch {
+	case address == "":
+		return false
+	case strings.EqualFold(address, user.NotificationEmail):
+		return user.NotificationEmailVerified
+	case strings.EqualFold(address, user.Email):
+		return user.EmailVerified
+	default:
+		return false
+	}
+}
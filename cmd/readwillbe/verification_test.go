@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"readwillbe/internal/cache"
+	"readwillbe/internal/clock"
+	"readwillbe/internal/model"
+
+	"github.com/labstack/echo/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestEmailVerification(t *testing.T) {
+	db := setupTestDB(t)
+	cfg := model.Config{AllowSignup: true, Hostname: "readwillbe.example"}
+	clk := clock.Fixed(time.Date(2026, time.May, 1, 8, 0, 0, 0, time.UTC))
+	userCache := cache.NewUserCache(time.Minute, time.Minute)
+	mailer := newFakeMailer()
+
+	follow := func(t *testing.T, token string) *httptest.ResponseRecorder {
+		t.Helper()
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/auth/verify-email?token="+url.QueryEscape(token), nil)
+		rec := httptest.NewRecorder()
+		require.NoError(t, verifyEmail(cfg, db, clk, userCache)(e.NewContext(req, rec)))
+		return rec
+	}
+	reload := func(t *testing.T, db *gorm.DB, email string) model.User {
+		t.Helper()
+		var user model.User
+		require.NoError(t, db.First(&user, "email = ?", email).Error)
+		return user
+	}
+
+	t.Run("sign-up", func(t *testing.T) {
+		form := url.Values{"name": {"New User"}, "email": {"new@example.com"}, "password": {"securepassword123"}}
+		postForm(t, signUpWithEmailAndPassword(db, cfg, clk, mailer), "/auth/sign-up", form, nil)
+		assert.False(t, reload(t, db, "new@example.com").EmailVerified)
+
+		sent := receiveEmail(t, mailer.verifications)
+		assert.Equal(t, "new@example.com", sent.to)
+
+		rec := follow(t, "bogus")
+		assert.Equal(t, 400, rec.Code)
+
+		rec = follow(t, emailToken(t, sent.url))
+		assert.Equal(t, 200, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Your email address is verified.")
+		assert.True(t, reload(t, db, "new@example.com").EmailVerified)
+	})
+
+	t.Run("notification email change", func(t *testing.T) {
+		user := reload(t, db, "new@example.com")
+		update := updateSettings(cfg, db, clk, userCache, mailer)
+
+		form := url.Values{"email_notifications_enabled": {"on"}, "notification_email": {"inbox@example.com"}}
+		rec := postForm(t, update, "/account/settings", form, &user)
+		assert.Equal(t, "/account?verification=sent", rec.Header().Get("Location"))
+		user = reload(t, db, "new@example.com")
+		assert.Equal(t, "inbox@example.com", user.NotificationEmail)
+		assert.False(t, user.NotificationEmailIsVerified())
+
+		sent := receiveEmail(t, mailer.verifications)
+		assert.Equal(t, "inbox@example.com", sent.to)
+		assert.Equal(t, 200, follow(t, emailToken(t, sent.url)).Code)
+		user = reload(t, db, "new@example.com")
+		assert.True(t, user.NotificationEmailIsVerified())
+
+		// Saving other settings keeps the verified address.
+		form.Set("timezone", "UTC")
+		rec = postForm(t, update, "/account/settings", form, &user)
+		assert.Equal(t, "/account", rec.Header().Get("Location"))
+		assert.True(t, reload(t, db, "new@example.com").NotificationEmailIsVerified())
+		assert.Empty(t, mailer.verifications)
+	})
+
+	t.Run("resend", func(t *testing.T) {
+		user := reload(t, db, "new@example.com")
+		resend := resendVerification(cfg, db, clk, mailer)
+
+		rec := postForm(t, resend, "/account/verify-email", url.Values{"address": {"primary"}}, &user)
+		assert.Equal(t, "/account", rec.Header().Get("Location"), "already verified")
+		assert.Empty(t, mailer.verifications)
+
+		require.NoError(t, db.Model(&user).Update("notification_email_verified", false).Error)
+		user = reload(t, db, "new@example.com")
+		rec = postForm(t, resend, "/account/verify-email", url.Values{"address": {"notification"}}, &user)
+		assert.Equal(t, "/account?verification=sent", rec.Header().Get("Location"))
+		assert.Equal(t, "inbox@example.com", receiveEmail(t, mailer.verifications).to)
+	})
+}
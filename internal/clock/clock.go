@@ -0,0 +1,38 @@
+// Package clock provides an injectable source of the current time so that
+// date-dependent code can be exercised at fixed instants in tests.
+package clock
+
+import "time"
+
+// Clock reports the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// System is the [Clock] backed by [time.Now]. It is what the server uses
+// outside of tests.
+var System Clock = systemClock{}
+
+type fixedClock struct {
+	t time.Time
+}
+
+func (f fixedClock) Now() time.Time {
+	return f.t
+}
+
+// Fixed returns a [Clock] that always reports t.
+func Fixed(t time.Time) Clock {
+	return fixedClock{t: t}
+}
+
+// NowIn returns the current time of c expressed in loc.
+func NowIn(c Clock, loc *time.Location) time.Time {
+	return c.Now().In(loc)
+}
@@ -0,0 +1,86 @@
+// Package database opens the ReadWillBe database for the configured driver.
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/stdlib"
+	sqlite "github.com/ncruces/go-sqlite3/gormlite"
+	"github.com/pkg/errors"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Supported values for the db_driver setting.
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// Open connects to the database identified by driver and dsn and configures
+// the connection pool. SQLite is limited to a single connection because it
+// serialises writers; PostgreSQL gets a pool so several replicas can share
+// one database.
+func Open(driver, dsn string) (*gorm.DB, error) {
+	dialector, err := Dialector(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect database")
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get underlying sql.DB")
+	}
+
+	switch driver {
+	case DriverPostgres:
+		sqlDB.SetMaxIdleConns(5)
+		sqlDB.SetMaxOpenConns(20)
+	default:
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetMaxOpenConns(1)
+	}
+	sqlDB.SetConnMaxLifetime(time.Hour)
+
+	return db, nil
+}
+
+// Dialector returns the gorm dialector for driver. The PostgreSQL
+// connection is opened lazily, on first use.
+func Dialector(driver, dsn string) (gorm.Dialector, error) {
+	switch driver {
+	case DriverSQLite, "":
+		return sqlite.Open(dsn), nil
+	case DriverPostgres:
+		connConfig, err := pgx.ParseConfig(dsn)
+		if err != nil {
+			return nil, errors.Wrap(err, "parsing db_dsn")
+		}
+		return postgres.New(postgres.Config{
+			Conn: stdlib.OpenDB(*connConfig, stdlib.OptionAfterConnect(scanTimestamptzAsUTC)),
+		}), nil
+	default:
+		return nil, errors.Errorf("unsupported db_driver %q (must be %q or %q)", driver, DriverSQLite, DriverPostgres)
+	}
+}
+
+// scanTimestamptzAsUTC makes timestamptz values come back in UTC rather than
+// the server's local zone. Reading dates are stored as UTC midnight and
+// their calendar day must not shift with the TZ setting, matching how SQLite
+// returns them.
+func scanTimestamptzAsUTC(_ context.Context, conn *pgx.Conn) error {
+	conn.TypeMap().RegisterType(&pgtype.Type{
+		Name:  "timestamptz",
+		OID:   pgtype.TimestamptzOID,
+		Codec: &pgtype.TimestamptzCodec{ScanLocation: time.UTC},
+	})
+	return nil
+}
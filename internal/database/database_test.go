@@ -0,0 +1,32 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen_SQLite(t *testing.T) {
+	db, err := Open(DriverSQLite, "file:TestOpen_SQLite?mode=memory&cache=shared")
+	require.NoError(t, err)
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	assert.Equal(t, "sqlite", db.Name())
+	assert.Equal(t, 1, sqlDB.Stats().MaxOpenConnections)
+}
+
+func TestDialector(t *testing.T) {
+	d, err := Dialector(DriverPostgres, "postgres://readwillbe@localhost:5432/readwillbe?sslmode=disable")
+	require.NoError(t, err)
+	assert.Equal(t, "postgres", d.Name())
+
+	_, err = Dialector(DriverPostgres, "postgres://%zz")
+	assert.Error(t, err)
+
+	_, err = Dialector("mysql", "")
+	assert.ErrorContains(t, err, `unsupported db_driver "mysql"`)
+}
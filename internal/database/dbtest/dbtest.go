@@ -0,0 +1,121 @@
+// Package dbtest opens isolated databases for tests. By default each test
+// gets a private in-memory SQLite database. Setting READWILLBE_TEST_DB_DRIVER=postgres
+// and READWILLBE_TEST_DB_DSN runs the same tests against PostgreSQL, with each
+// test confined to its own schema.
+package dbtest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"readwillbe/internal/database"
+)
+
+// Environment variables selecting the test backend.
+const (
+	DriverEnv = "READWILLBE_TEST_DB_DRIVER"
+	DSNEnv    = "READWILLBE_TEST_DB_DSN"
+)
+
+var unsafeChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// Driver returns the backend tests run against.
+func Driver() string {
+	if d := os.Getenv(DriverEnv); d != "" {
+		return d
+	}
+	return database.DriverSQLite
+}
+
+// Open returns an empty database private to t. It is closed, and for
+// PostgreSQL dropped, when t finishes.
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	switch Driver() {
+	case database.DriverPostgres:
+		return openPostgres(t)
+	case database.DriverSQLite:
+		return openSQLite(t)
+	default:
+		t.Fatalf("unsupported %s %q", DriverEnv, Driver())
+		return nil
+	}
+}
+
+func openSQLite(t testing.TB) *gorm.DB {
+	// A unique name per test keeps shared-cache in-memory databases isolated.
+	safeName := strings.ReplaceAll(t.Name(), "/", "_")
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", safeName)
+
+	db, err := database.Open(database.DriverSQLite, dsn)
+	require.NoError(t, err)
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = sqlDB.Close()
+	})
+
+	return db
+}
+
+func openPostgres(t testing.TB) *gorm.DB {
+	dsn := os.Getenv(DSNEnv)
+	if dsn == "" {
+		t.Fatalf("%s must be set when %s=postgres", DSNEnv, DriverEnv)
+	}
+
+	admin, err := database.Open(database.DriverPostgres, dsn)
+	require.NoError(t, err)
+	adminDB, err := admin.DB()
+	require.NoError(t, err)
+
+	schema := schemaName(t.Name())
+	require.NoError(t, admin.Exec("CREATE SCHEMA "+schema).Error)
+
+	db, err := database.Open(database.DriverPostgres, withSearchPath(dsn, schema))
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = sqlDB.Close()
+		_ = admin.Exec("DROP SCHEMA " + schema + " CASCADE").Error
+		_ = adminDB.Close()
+	})
+
+	return db
+}
+
+func schemaName(testName string) string {
+	name := unsafeChars.ReplaceAllString(strings.ToLower(testName), "_")
+	if len(name) > 40 {
+		name = name[:40]
+	}
+
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return "t_" + name + "_" + hex.EncodeToString(suffix)
+}
+
+// withSearchPath adds a search_path runtime parameter to either form of
+// PostgreSQL connection string.
+func withSearchPath(dsn, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		return dsn + sep + "search_path=" + schema
+	}
+	return dsn + " search_path=" + schema
+}
@@ -3,8 +3,6 @@
 package middleware
 
 import (
-	"time"
-
 	"github.com/labstack/echo-contrib/v5/session"
 	"github.com/labstack/echo/v5"
 	"github.com/pkg/errors"
@@ -12,15 +10,36 @@ import (
 	"gorm.io/gorm"
 
 	"readwillbe/internal/cache"
+	"readwillbe/internal/clock"
 	"readwillbe/internal/model"
 	"readwillbe/internal/repository"
 )
 
 // UserMiddleware returns Echo middleware that resolves the session user from db
-// (with a userCache fast path) and stores it on the request context.
-func UserMiddleware(db *gorm.DB, userCache *cache.UserCache, cfg model.Config) echo.MiddlewareFunc {
+// (with a userCache fast path) and stores it on the request context. When
+// proxy authentication is configured, only a trusted proxy's header decides
+// the user; the session never does.
+func UserMiddleware(db *gorm.DB, userCache *cache.UserCache, cfg model.Config, clk clock.Clock) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c *echo.Context) error {
+			if cfg.ProxyAuthEnabled() {
+				email, ok := proxyAuthEmail(c, cfg)
+				if !ok {
+					if err := clearSessionUser(c); err != nil {
+						return err
+					}
+					return next(c)
+				}
+				user, found, err := proxyAuthUser(c, db, userCache, cfg, clk, email)
+				if err != nil {
+					return err
+				}
+				if found {
+					c.Set(UserKey, user)
+				}
+				return next(c)
+			}
+
 			sess, err := session.Get(SessionKey, c)
 			if err != nil {
 				logrus.Warnf("Failed to get session: %v", err)
@@ -57,7 +76,7 @@ func UserMiddleware(db *gorm.DB, userCache *cache.UserCache, cfg model.Config) e
 				}
 
 				lastSeen, ok := sess.Values[SessionLastSeenKey].(int64)
-				now := time.Now().Unix()
+				now := clk.Now().Unix()
 				if !ok || now-lastSeen > SessionRefreshInterval {
 					sess.Values[SessionLastSeenKey] = now
 					shouldSave = true
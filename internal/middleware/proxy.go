@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/mail"
+	"net/netip"
+	"strings"
+
+	"github.com/labstack/echo-contrib/v5/session"
+	"github.com/labstack/echo/v5"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"readwillbe/internal/cache"
+	"readwillbe/internal/clock"
+	"readwillbe/internal/model"
+	"readwillbe/internal/repository"
+)
+
+// fromTrustedProxy reports whether r came straight from one of
+// cfg.TrustedProxies. Only the connection's address counts; X-Forwarded-For
+// and friends are set by the client as far as this check is concerned.
+func fromTrustedProxy(r *http.Request, cfg model.Config) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	return trustedAddr(addr, cfg)
+}
+
+func trustedAddr(addr netip.Addr, cfg model.Config) bool {
+	addr = addr.Unmap()
+	for _, prefix := range cfg.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// proxyAuthEmail returns the email address a trusted proxy signed the
+// request in as, if any.
+func proxyAuthEmail(c *echo.Context, cfg model.Config) (string, bool) {
+	if !cfg.ProxyAuthEnabled() {
+		return "", false
+	}
+	value := strings.TrimSpace(c.Request().Header.Get(cfg.ProxyAuthHeader))
+	if value == "" {
+		return "", false
+	}
+	if !fromTrustedProxy(c.Request(), cfg) {
+		logrus.Warnf("Ignoring %s header from untrusted address %s", cfg.ProxyAuthHeader, c.Request().RemoteAddr)
+		return "", false
+	}
+	address, err := mail.ParseAddress(value)
+	if err != nil {
+		logrus.Warnf("Ignoring %s header that is not an email address: %q", cfg.ProxyAuthHeader, value)
+		return "", false
+	}
+	return address.Address, true
+}
+
+// proxyAuthUser returns the user with email, creating them if cfg.AllowSignup
+// is set, or false if there is no such user. The user's ID is kept on the
+// session so later requests for the same email can use userCache.
+func proxyAuthUser(c *echo.Context, db *gorm.DB, userCache *cache.UserCache, cfg model.Config, clk clock.Clock, email string) (model.User, bool, error) {
+	sess, err := session.Get(SessionKey, c)
+	if err != nil {
+		return model.User{}, false, errors.Wrap(err, "getting session")
+	}
+	if userID, ok := sess.Values[SessionUserIDKey].(uint); ok {
+		if user, found := userCache.Get(userID); found && user.Email == email {
+			return user, true, nil
+		}
+	}
+
+	tx := db.WithContext(c.Request().Context())
+	user, err := repository.GetUserByEmail(tx, email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if !cfg.AllowSignup {
+			return model.User{}, false, clearSessionUser(c)
+		}
+		user, err = createProxyAuthUser(c, tx, cfg, clk, email)
+	}
+	if err != nil {
+		return model.User{}, false, errors.Wrap(err, "getting user by email")
+	}
+	userCache.Set(user)
+
+	for key := range sess.Values {
+		delete(sess.Values, key)
+	}
+	if err := RenewSessionID(c.Request(), sess); err != nil {
+		return model.User{}, false, err
+	}
+	sess.Options = GetSecureSessionOptions(cfg)
+	sess.Values[SessionUserIDKey] = user.ID
+	sess.Values[SessionLastSeenKey] = clk.Now().Unix()
+	if err := sess.Save(c.Request(), c.Response()); err != nil {
+		return model.User{}, false, errors.Wrap(err, "saving session")
+	}
+	return user, true, nil
+}
+
+// createProxyAuthUser creates an account without a password for a user the
+// proxy has signed in. The proxy vouches for the email address.
+func createProxyAuthUser(c *echo.Context, tx *gorm.DB, cfg model.Config, clk clock.Clock, email string) (model.User, error) {
+	name := email
+	if cfg.ProxyAuthNameHeader != "" {
+		if value := strings.TrimSpace(c.Request().Header.Get(cfg.ProxyAuthNameHeader)); value != "" {
+			name = value
+		}
+	}
+	user := model.User{
+		Name:          name,
+		Email:         email,
+		EmailVerified: true,
+		CreatedAt:     clk.Now(),
+	}
+	if err := repository.CreateUser(tx, &user); err != nil {
+		return model.User{}, err
+	}
+	logrus.Infof("Created user %d signed in by the reverse proxy", user.ID)
+	return user, nil
+}
+
+// clearSessionUser drops the user from the session of a proxy-mode request
+// that signs no known user in, so an earlier sign-in does not linger.
+func clearSessionUser(c *echo.Context) error {
+	sess, err := session.Get(SessionKey, c)
+	if err != nil {
+		logrus.Warnf("Failed to get session: %v", err)
+		return nil
+	}
+	if _, ok := sess.Values[SessionUserIDKey]; !ok {
+		return nil
+	}
+	delete(sess.Values, SessionUserIDKey)
+	delete(sess.Values, SessionLastSeenKey)
+	if err := sess.Save(c.Request(), c.Response()); err != nil {
+		return errors.Wrap(err, "saving session")
+	}
+	return nil
+}
@@ -15,6 +15,31 @@ const (
 	SessionUserIDKey       = "userid"
 	SessionLastSeenKey     = "last_seen"
 	SessionRefreshInterval = 3600
+
+	// A user who has passed the password check but not yet the second
+	// factor is held under these keys, not SessionUserIDKey, so they are
+	// not signed in.
+	SessionPendingUserIDKey   = "pending_userid"
+	SessionPendingAtKey       = "pending_at"
+	SessionPendingAttemptsKey = "pending_attempts"
+	// SessionTOTPSetupKey holds the otpauth URL of a TOTP key being set up
+	// until the user confirms a code from it.
+	SessionTOTPSetupKey = "totp_setup"
+	// A WebAuthn ceremony's challenge is held under these keys between
+	// sending the options and checking the browser's response.
+	SessionPasskeyRegistrationKey = "passkey_registration"
+	SessionPasskeyLoginKey        = "passkey_login"
+
+	// OIDCSessionKey names a second session cookie that holds an OpenID
+	// Connect sign-in's state, nonce and PKCE verifier while the user is at
+	// the identity provider.
+	OIDCSessionKey      = "oidc"
+	OIDCStateKey        = "state"
+	OIDCNonceKey        = "nonce"
+	OIDCVerifierKey     = "verifier"
+	OIDCStartedAtKey    = "started_at"
+	OIDCSessionPath     = "/auth/oidc"
+	OIDCSessionDuration = 600 // 10 minutes
 )
 
 // GetSecureSessionOptions returns gorilla/sessions options with secure defaults
@@ -28,3 +53,16 @@ func GetSecureSessionOptions(cfg model.Config) *sessions.Options {
 		SameSite: http.SameSiteStrictMode,
 	}
 }
+
+// GetOIDCSessionOptions returns the options for the [OIDCSessionKey] cookie.
+// Unlike the main session it is SameSite=Lax, as it must be sent when the
+// identity provider redirects back to the callback.
+func GetOIDCSessionOptions(cfg model.Config) *sessions.Options {
+	return &sessions.Options{
+		Path:     OIDCSessionPath,
+		MaxAge:   OIDCSessionDuration,
+		HttpOnly: true,
+		Secure:   cfg.IsProduction(),
+		SameSite: http.SameSiteLaxMode,
+	}
+}
@@ -0,0 +1,218 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"readwillbe/internal/clock"
+	"readwillbe/internal/model"
+	"readwillbe/internal/repository"
+)
+
+// MaxUserAgentLength caps the user agent stored with a session.
+const MaxUserAgentLength = 512
+
+// DBStore is a [sessions.Store] that keeps session values in the database.
+// The cookie only carries a signed random token, so deleting a session's row
+// revokes it.
+type DBStore struct {
+	Codecs  []securecookie.Codec
+	Options *sessions.Options
+
+	db  *gorm.DB
+	cfg model.Config
+	clk clock.Clock
+}
+
+// NewDBStore returns a store that signs its cookies with cfg.CookieSecret and
+// gives them [GetSecureSessionOptions].
+func NewDBStore(db *gorm.DB, cfg model.Config, clk clock.Clock) *DBStore {
+	s := &DBStore{
+		Codecs:  securecookie.CodecsFromPairs(cfg.CookieSecret),
+		Options: GetSecureSessionOptions(cfg),
+		db:      db,
+		cfg:     cfg,
+		clk:     clk,
+	}
+	for _, codec := range s.Codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(s.Options.MaxAge)
+		}
+	}
+	return s
+}
+
+// Get implements [sessions.Store], returning the session cached for r if
+// there is one.
+func (s *DBStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New implements [sessions.Store]. A cookie for a session that has expired
+// or been revoked gives a new, empty session, as does one that does not
+// decode, such as a cookie from the old cookie-only store or a forgery.
+func (s *DBStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return session, nil
+	}
+	var token string
+	if err := securecookie.DecodeMulti(name, cookie.Value, &token, s.Codecs...); err != nil {
+		logrus.Debugf("Ignoring undecodable %s cookie: %v", name, err)
+		return session, nil
+	}
+	row, err := repository.GetSession(s.db.WithContext(r.Context()), token, s.clk.Now().UTC())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return session, nil
+	}
+	if err != nil {
+		return session, errors.Wrap(err, "loading session")
+	}
+	if err := securecookie.DecodeMulti(name, row.Data, &session.Values, s.Codecs...); err != nil {
+		return session, err
+	}
+	session.ID = token
+	session.IsNew = false
+	return session, nil
+}
+
+// Save implements [sessions.Store]. A session with a negative MaxAge is
+// deleted, and one without an ID is stored under a new token.
+func (s *DBStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	tx := s.db.WithContext(r.Context())
+	if session.Options.MaxAge < 0 {
+		if session.ID != "" {
+			if err := repository.DeleteSession(tx, session.ID); err != nil {
+				return errors.Wrap(err, "deleting session")
+			}
+		}
+		http.SetCookie(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	data, err := securecookie.EncodeMulti(session.Name(), session.Values, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	now := s.clk.Now().UTC()
+	row := model.Session{
+		Name:       session.Name(),
+		Data:       data,
+		UserAgent:  truncateUserAgent(r.UserAgent()),
+		IP:         clientIP(r, s.cfg),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(time.Duration(session.Options.MaxAge) * time.Second),
+	}
+	if userID, ok := session.Values[SessionUserIDKey].(uint); ok {
+		row.UserID = &userID
+	}
+
+	if session.ID == "" {
+		token, err := repository.CreateSession(tx, &row)
+		if err != nil {
+			return errors.Wrap(err, "creating session")
+		}
+		session.ID = token
+	} else {
+		found, err := repository.UpdateSession(tx, session.ID, row)
+		if err != nil {
+			return errors.Wrap(err, "updating session")
+		}
+		if !found {
+			// Revoked while this request was running.
+			expired := *session.Options
+			expired.MaxAge = -1
+			http.SetCookie(w, sessions.NewCookie(session.Name(), "", &expired))
+			return nil
+		}
+	}
+
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// StartCleanup deletes expired sessions every interval until the returned
+// function is called.
+func (s *DBStore) StartCleanup(interval time.Duration) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if n, err := repository.DeleteExpiredSessions(s.db.WithContext(ctx), s.clk.Now().UTC()); err != nil {
+					logrus.Errorf("Error deleting expired sessions: %v", err)
+				} else if n > 0 {
+					logrus.Debugf("Deleted %d expired session(s)", n)
+				}
+			}
+		}
+	}()
+	return cancel
+}
+
+// RenewSessionID ends sess's server-side session and has it saved under a
+// new ID, so that an ID planted before sign-in is useless after it. Call it
+// before saving a session that has just signed someone in.
+func RenewSessionID(r *http.Request, sess *sessions.Session) error {
+	if store, ok := sess.Store().(*DBStore); ok && sess.ID != "" {
+		if err := repository.DeleteSession(store.db.WithContext(r.Context()), sess.ID); err != nil {
+			return errors.Wrap(err, "deleting session")
+		}
+	}
+	sess.ID = ""
+	return nil
+}
+
+func truncateUserAgent(ua string) string {
+	if len(ua) <= MaxUserAgentLength {
+		return ua
+	}
+	return strings.ToValidUTF8(ua[:MaxUserAgentLength], "")
+}
+
+// clientIP is the address r came from. Behind one of cfg.TrustedProxies that
+// is the last address in X-Forwarded-For the proxies did not add themselves.
+func clientIP(r *http.Request, cfg model.Config) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !fromTrustedProxy(r, cfg) {
+		return host
+	}
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			break
+		}
+		if !trustedAddr(addr, cfg) {
+			return addr.String()
+		}
+	}
+	return host
+}
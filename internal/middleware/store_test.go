@@ -0,0 +1,185 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"readwillbe/internal/clock"
+	"readwillbe/internal/database/dbtest"
+	"readwillbe/internal/model"
+	"readwillbe/internal/repository"
+)
+
+func TestDBStore(t *testing.T) {
+	db := dbtest.Open(t)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Session{}))
+	now := time.Date(2026, time.May, 1, 8, 0, 0, 0, time.UTC)
+	cfg := model.Config{CookieSecret: []byte("0123456789abcdef0123456789abcdef")}
+	store := NewDBStore(db, cfg, clock.Fixed(now))
+
+	// save stores values on a fresh session, or the one cookies point to,
+	// and returns the resulting cookie.
+	save := func(t *testing.T, values map[interface{}]interface{}, cookies ...*http.Cookie) *http.Cookie {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("User-Agent", "TestBrowser/1.0")
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		sess, err := store.Get(req, SessionKey)
+		require.NoError(t, err)
+		for k, v := range values {
+			sess.Values[k] = v
+		}
+		rec := httptest.NewRecorder()
+		require.NoError(t, sess.Save(req, rec))
+		result := rec.Result().Cookies()
+		require.Len(t, result, 1)
+		return result[0]
+	}
+	load := func(t *testing.T, cookie *http.Cookie) map[interface{}]interface{} {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(cookie)
+		sess, err := store.Get(req, SessionKey)
+		require.NoError(t, err)
+		return sess.Values
+	}
+
+	t.Run("keeps values in the database", func(t *testing.T) {
+		cookie := save(t, map[interface{}]interface{}{SessionUserIDKey: uint(7), "note": "hello"})
+		assert.NotContains(t, cookie.Value, "hello")
+		assert.Equal(t, 86400, cookie.MaxAge)
+
+		values := load(t, cookie)
+		assert.Equal(t, uint(7), values[SessionUserIDKey])
+		assert.Equal(t, "hello", values["note"])
+
+		sessions, err := repository.ListSessions(db, 7, now)
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		assert.Equal(t, "TestBrowser/1.0", sessions[0].UserAgent)
+		assert.Equal(t, "192.0.2.1", sessions[0].IP)
+		assert.Equal(t, now.Add(24*time.Hour), sessions[0].ExpiresAt.UTC())
+	})
+
+	t.Run("a revoked session is empty", func(t *testing.T) {
+		cookie := save(t, map[interface{}]interface{}{SessionUserIDKey: uint(8)})
+		sessions, err := repository.ListSessions(db, 8, now)
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+
+		revoked, err := repository.RevokeSession(db, 8, sessions[0].ID)
+		require.NoError(t, err)
+		require.True(t, revoked)
+		assert.Empty(t, load(t, cookie))
+	})
+
+	t.Run("a forged cookie gives a new session", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(&http.Cookie{Name: SessionKey, Value: "forged"})
+		sess, err := store.Get(req, SessionKey)
+		require.NoError(t, err)
+		assert.True(t, sess.IsNew)
+		assert.Empty(t, sess.Values)
+	})
+
+	t.Run("a cookie from the old cookie store gives a new session", func(t *testing.T) {
+		old := sessions.NewCookieStore(cfg.CookieSecret)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		legacy, err := old.New(req, SessionKey)
+		require.NoError(t, err)
+		legacy.Values[SessionUserIDKey] = uint(11)
+		rec := httptest.NewRecorder()
+		require.NoError(t, legacy.Save(req, rec))
+
+		cookie := save(t, map[interface{}]interface{}{"fresh": true}, rec.Result().Cookies()[0])
+		values := load(t, cookie)
+		assert.Nil(t, values[SessionUserIDKey])
+		assert.Equal(t, true, values["fresh"])
+	})
+
+	t.Run("renewing the ID ends the old session", func(t *testing.T) {
+		cookie := save(t, map[interface{}]interface{}{"pending": true})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(cookie)
+		sess, err := store.Get(req, SessionKey)
+		require.NoError(t, err)
+		oldID := sess.ID
+		require.NoError(t, RenewSessionID(req, sess))
+		sess.Values[SessionUserIDKey] = uint(9)
+		rec := httptest.NewRecorder()
+		require.NoError(t, sess.Save(req, rec))
+
+		assert.NotEqual(t, oldID, sess.ID)
+		assert.Empty(t, load(t, cookie), "the old cookie no longer works")
+		assert.Equal(t, uint(9), load(t, rec.Result().Cookies()[0])[SessionUserIDKey])
+	})
+
+	t.Run("signing out deletes the session", func(t *testing.T) {
+		cookie := save(t, map[interface{}]interface{}{SessionUserIDKey: uint(10)})
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(cookie)
+		sess, err := store.Get(req, SessionKey)
+		require.NoError(t, err)
+		sess.Options.MaxAge = -1
+		rec := httptest.NewRecorder()
+		require.NoError(t, sess.Save(req, rec))
+
+		assert.Equal(t, -1, rec.Result().Cookies()[0].MaxAge)
+		sessions, err := repository.ListSessions(db, 10, now)
+		require.NoError(t, err)
+		assert.Empty(t, sessions)
+	})
+}
+
+func TestDBStore_ExpiryIgnoresServerZone(t *testing.T) {
+	db := dbtest.Open(t)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Session{}))
+	cfg := model.Config{CookieSecret: []byte("0123456789abcdef0123456789abcdef")}
+	issued := time.Date(2026, time.May, 1, 8, 0, 0, 0, time.FixedZone("east", 10*60*60))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	sess, err := NewDBStore(db, cfg, clock.Fixed(issued)).Get(req, SessionKey)
+	require.NoError(t, err)
+	sess.Values[SessionUserIDKey] = uint(12)
+	rec := httptest.NewRecorder()
+	require.NoError(t, sess.Save(req, rec))
+
+	// An hour past expiry, on a clock whose offset sorts before the one the
+	// session was written with.
+	later := issued.Add(25 * time.Hour).In(time.FixedZone("west", -10*60*60))
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(rec.Result().Cookies()[0])
+	sess, err = NewDBStore(db, cfg, clock.Fixed(later)).Get(req, SessionKey)
+	require.NoError(t, err)
+	assert.True(t, sess.IsNew, "the expired session is not loaded")
+	assert.Empty(t, sess.Values)
+}
+
+func TestClientIP(t *testing.T) {
+	cfg := model.Config{TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}
+	ip := func(remoteAddr string, forwardedFor ...string) string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		for _, v := range forwardedFor {
+			req.Header.Add("X-Forwarded-For", v)
+		}
+		return clientIP(req, cfg)
+	}
+
+	assert.Equal(t, "192.0.2.1", ip("192.0.2.1:1234", "198.51.100.7"), "untrusted peers cannot claim another address")
+	assert.Equal(t, "198.51.100.7", ip("10.0.0.1:1234", "198.51.100.7"))
+	assert.Equal(t, "198.51.100.7", ip("10.0.0.1:1234", "203.0.113.9, 198.51.100.7, 10.0.0.2"), "the proxies' own hops are skipped")
+	assert.Equal(t, "198.51.100.7", ip("10.0.0.1:1234", "203.0.113.9", "198.51.100.7"))
+	assert.Equal(t, "10.0.0.1", ip("10.0.0.1:1234"))
+}
@@ -0,0 +1,260 @@
+// Package migrate applies and rolls back versioned schema migrations for the
+// ReadWillBe database. Applied versions are recorded in the schema_migrations
+// table.
+package migrate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// ErrSchemaOutdated is returned by [Migrator.Check] when the database has
+// pending migrations.
+var ErrSchemaOutdated = errors.New("database schema is out of date")
+
+// advisoryLockID is an arbitrary key identifying the migration lock.
+const advisoryLockID = 7_352_019
+
+// Migration is a single versioned schema change. Up and Down each run in
+// their own transaction.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration is the row recorded for each applied [Migration].
+type SchemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// TableName pins the bookkeeping table name.
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status describes one known migration and whether it has been applied.
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// Migrator runs migrations against a database.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// New returns a Migrator for the migrations shipped with this binary.
+func New(db *gorm.DB) *Migrator {
+	return newMigrator(db, migrations)
+}
+
+func newMigrator(db *gorm.DB, ms []Migration) *Migrator {
+	sorted := make([]Migration, len(ms))
+	copy(sorted, ms)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return &Migrator{db: db, migrations: sorted}
+}
+
+// Latest returns the highest migration version known to this binary.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Current returns the highest applied migration version, or 0 for a database
+// that has never been migrated.
+func (m *Migrator) Current() (int, error) {
+	if err := m.ensureTable(); err != nil {
+		return 0, err
+	}
+
+	var version *int
+	err := m.db.Model(&SchemaMigration{}).Select("MAX(version)").Scan(&version).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "reading schema version")
+	}
+	if version == nil {
+		return 0, nil
+	}
+	return *version, nil
+}
+
+// Status lists every known migration along with its applied state.
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := Status{Version: mig.Version, Name: mig.Name}
+		if row, ok := applied[mig.Version]; ok {
+			s.Applied = true
+			appliedAt := row.AppliedAt
+			s.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// Up applies every pending migration in version order and returns the ones
+// that ran.
+func (m *Migrator) Up() ([]Migration, error) {
+	var ran []Migration
+	err := m.locked(func(locked *Migrator) error {
+		var err error
+		ran, err = locked.up()
+		return err
+	})
+	return ran, err
+}
+
+// Down rolls back the most recently applied steps migrations, newest first,
+// and returns the ones that ran.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	var ran []Migration
+	err := m.locked(func(locked *Migrator) error {
+		var err error
+		ran, err = locked.down(steps)
+		return err
+	})
+	return ran, err
+}
+
+// locked runs fn while holding a PostgreSQL advisory lock, so replicas
+// starting together with auto_migrate do not race each other. SQLite needs
+// no lock as it only allows one writer.
+func (m *Migrator) locked(fn func(*Migrator) error) error {
+	if m.db.Name() != "postgres" {
+		return fn(m)
+	}
+
+	return m.db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", advisoryLockID).Error; err != nil {
+			return errors.Wrap(err, "acquiring migration lock")
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", advisoryLockID)
+
+		return fn(&Migrator{db: conn, migrations: m.migrations})
+	})
+}
+
+func (m *Migrator) up() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := mig.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{
+				Version:   mig.Version,
+				Name:      mig.Name,
+				AppliedAt: time.Now().UTC(),
+			}).Error
+		})
+		if err != nil {
+			return ran, errors.Wrapf(err, "applying migration %d (%s)", mig.Version, mig.Name)
+		}
+		ran = append(ran, mig)
+	}
+	return ran, nil
+}
+
+func (m *Migrator) down(steps int) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(ran) < steps; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := mig.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{}, "version = ?", mig.Version).Error
+		})
+		if err != nil {
+			return ran, errors.Wrapf(err, "reverting migration %d (%s)", mig.Version, mig.Name)
+		}
+		ran = append(ran, mig)
+	}
+	return ran, nil
+}
+
+// Check returns an error wrapping [ErrSchemaOutdated] if any migration known
+// to this binary has not been applied.
+func (m *Migrator) Check() error {
+	statuses, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, s := range statuses {
+		if !s.Applied {
+			pending++
+		}
+	}
+	if pending == 0 {
+		return nil
+	}
+
+	current, err := m.Current()
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(ErrSchemaOutdated, fmt.Sprintf(
+		"schema is at version %d, binary expects %d (%d pending); run `readwillbe migrate up`",
+		current, m.Latest(), pending))
+}
+
+func (m *Migrator) ensureTable() error {
+	return errors.Wrap(m.db.AutoMigrate(&SchemaMigration{}), "creating schema_migrations table")
+}
+
+func (m *Migrator) applied() (map[int]SchemaMigration, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	var rows []SchemaMigration
+	if err := m.db.Find(&rows).Error; err != nil {
+		return nil, errors.Wrap(err, "reading applied migrations")
+	}
+
+	applied := make(map[int]SchemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
@@ -0,0 +1,151 @@
+package migrate
+
+import (
+	"sync"
+	"testing"
+
+	"readwillbe/internal/database/dbtest"
+	"readwillbe/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+func TestMigrator_UpDownStatus(t *testing.T) {
+	db := dbtest.Open(t)
+	m := New(db)
+
+	current, err := m.Current()
+	require.NoError(t, err)
+	assert.Equal(t, 0, current)
+	assert.ErrorIs(t, m.Check(), ErrSchemaOutdated)
+
+	ran, err := m.Up()
+	require.NoError(t, err)
+	assert.Len(t, ran, len(migrations))
+
+	current, err = m.Current()
+	require.NoError(t, err)
+	assert.Equal(t, m.Latest(), current)
+	assert.NoError(t, m.Check())
+
+	statuses, err := m.Status()
+	require.NoError(t, err)
+	for _, s := range statuses {
+		assert.True(t, s.Applied, "migration %d should be applied", s.Version)
+		assert.NotNil(t, s.AppliedAt)
+	}
+
+	// Up is idempotent.
+	ran, err = m.Up()
+	require.NoError(t, err)
+	assert.Empty(t, ran)
+
+	ran, err = m.Down(1)
+	require.NoError(t, err)
+	require.Len(t, ran, 1)
+	assert.Equal(t, m.Latest(), ran[0].Version)
+	assert.ErrorIs(t, m.Check(), ErrSchemaOutdated)
+
+	ran, err = m.Down(len(migrations))
+	require.NoError(t, err)
+	assert.Len(t, ran, len(migrations)-1)
+	assert.False(t, db.Migrator().HasTable("users"))
+
+	current, err = m.Current()
+	require.NoError(t, err)
+	assert.Equal(t, 0, current)
+}
+
+func TestMigrator_MatchesModels(t *testing.T) {
+	db := dbtest.Open(t)
+	_, err := New(db).Up()
+	require.NoError(t, err)
+
+	cache := &sync.Map{}
+	for _, value := range []interface{}{&model.User{}, &model.Plan{}, &model.Reading{}, &model.PushSubscription{}, &model.Job{}, &model.JournalEntry{}, &model.PasswordReset{}, &model.EmailVerification{}, &model.RecoveryCode{}, &model.Passkey{}, &model.Session{}} {
+		s, err := schema.Parse(value, cache, db.NamingStrategy)
+		require.NoError(t, err)
+
+		require.True(t, db.Migrator().HasTable(s.Table), "missing table %s", s.Table)
+		for _, field := range s.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			assert.True(t, db.Migrator().HasColumn(value, field.DBName),
+				"%s.%s has no migration; add one to migrations.go", s.Table, field.DBName)
+		}
+		for _, idx := range s.ParseIndexes() {
+			assert.True(t, db.Migrator().HasIndex(value, idx.Name),
+				"index %s has no migration; add one to migrations.go", idx.Name)
+		}
+	}
+}
+
+func TestMigrator_LegacyDatabase(t *testing.T) {
+	db := dbtest.Open(t)
+
+	// Databases created before versioned migrations were built by AutoMigrate
+	// and have no schema_migrations table.
+	require.NoError(t, db.AutoMigrate(&userV1{}, &planV1{}, &readingV1{}, &pushSubscriptionV1{}))
+	require.NoError(t, db.Create(&userV1{Email: "legacy@example.com"}).Error)
+	require.NoError(t, db.Create(&userV1{Email: "digest@example.com", NotificationEmail: "inbox@example.com"}).Error)
+
+	m := New(db)
+	assert.ErrorIs(t, m.Check(), ErrSchemaOutdated)
+
+	_, err := m.Up()
+	require.NoError(t, err)
+	require.NoError(t, m.Check())
+
+	var user model.User
+	require.NoError(t, db.First(&user, "email = ?", "legacy@example.com").Error)
+	assert.Empty(t, user.Timezone)
+	assert.True(t, user.EmailVerified, "existing accounts keep their sign-up address verified")
+	assert.False(t, user.NotificationEmailVerified)
+
+	var digest model.User
+	require.NoError(t, db.First(&digest, "email = ?", "digest@example.com").Error)
+	assert.True(t, digest.NotificationEmailVerified, "existing notification addresses keep receiving digests")
+}
+
+func TestMigrator_FailedMigrationRollsBack(t *testing.T) {
+	db := dbtest.Open(t)
+
+	type widget struct {
+		ID   uint
+		Name string
+	}
+
+	m := newMigrator(db, []Migration{
+		{
+			Version: 2,
+			Name:    "broken",
+			Up: func(tx *gorm.DB) error {
+				if err := tx.AutoMigrate(&widget{}); err != nil {
+					return err
+				}
+				return tx.Exec("INSERT INTO no_such_table VALUES (1)").Error
+			},
+			Down: func(*gorm.DB) error { return nil },
+		},
+		{
+			Version: 1,
+			Name:    "widgets",
+			Up:      func(tx *gorm.DB) error { return tx.AutoMigrate(&widget{}) },
+			Down:    func(tx *gorm.DB) error { return tx.Migrator().DropTable(&widget{}) },
+		},
+	})
+
+	ran, err := m.Up()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "applying migration 2 (broken)")
+	require.Len(t, ran, 1, "migrations run in version order")
+	assert.Equal(t, 1, ran[0].Version)
+
+	current, err := m.Current()
+	require.NoError(t, err)
+	assert.Equal(t, 1, current, "failed migration must not be recorded")
+}
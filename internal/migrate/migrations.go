@@ -0,0 +1,526 @@
+package migrate
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrations is the ordered schema history. Never edit or renumber an entry
+// once it has shipped; add a new one instead. Each step declares its own
+// snapshot structs rather than using package model, so later model changes
+// cannot alter what an old migration does.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "baseline",
+		Up:      baselineUp,
+		Down:    baselineDown,
+	},
+	{
+		Version: 2,
+		Name:    "add_user_timezone",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&userTimezoneV2{}, "Timezone") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&userTimezoneV2{}, "Timezone")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&userTimezoneV2{}, "Timezone")
+		},
+	},
+	{
+		Version: 3,
+		Name:    "create_jobs",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&jobV3{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&jobV3{})
+		},
+	},
+	{
+		Version: 4,
+		Name:    "add_reading_details",
+		Up: func(tx *gorm.DB) error {
+			for _, col := range readingDetailColumnsV4 {
+				if tx.Migrator().HasColumn(&readingDetailsV4{}, col) {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(&readingDetailsV4{}, col); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, col := range readingDetailColumnsV4 {
+				if err := tx.Migrator().DropColumn(&readingDetailsV4{}, col); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 5,
+		Name:    "add_relative_dates",
+		Up: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn(&readingOffsetV5{}, "StartOffset") {
+				if err := tx.Migrator().AddColumn(&readingOffsetV5{}, "StartOffset"); err != nil {
+					return err
+				}
+			}
+			if tx.Migrator().HasColumn(&planStartDateV5{}, "StartDate") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&planStartDateV5{}, "StartDate")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&planStartDateV5{}, "StartDate"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&readingOffsetV5{}, "StartOffset")
+		},
+	},
+	{
+		Version: 6,
+		Name:    "add_plan_paused_at",
+		Up: func(tx *gorm.DB) error {
+			if tx.Migrator().HasColumn(&planPausedAtV6{}, "PausedAt") {
+				return nil
+			}
+			return tx.Migrator().AddColumn(&planPausedAtV6{}, "PausedAt")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&planPausedAtV6{}, "PausedAt")
+		},
+	},
+	{
+		Version: 7,
+		Name:    "add_reading_skip",
+		Up: func(tx *gorm.DB) error {
+			for _, col := range []string{"SkippedAt", "SkipReason"} {
+				if tx.Migrator().HasColumn(&readingSkipV7{}, col) {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(&readingSkipV7{}, col); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&readingSkipV7{}, "SkipReason"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&readingSkipV7{}, "SkippedAt")
+		},
+	},
+	{
+		Version: 8,
+		Name:    "create_journal_entries",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&journalEntryV8{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&journalEntryV8{})
+		},
+	},
+	{
+		Version: 9,
+		Name:    "create_reading_search",
+		Up: func(tx *gorm.DB) error {
+			if tx.Name() != "sqlite" {
+				return nil
+			}
+			for _, stmt := range readingSearchUpV9 {
+				if err := tx.Exec(stmt).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Name() != "sqlite" {
+				return nil
+			}
+			for _, stmt := range readingSearchDownV9 {
+				if err := tx.Exec(stmt).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 10,
+		Name:    "create_password_resets",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&passwordResetV10{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&passwordResetV10{})
+		},
+	},
+	{
+		Version: 11,
+		Name:    "add_email_verification",
+		Up: func(tx *gorm.DB) error {
+			for _, col := range []string{"EmailVerified", "NotificationEmailVerified"} {
+				if tx.Migrator().HasColumn(&userEmailVerifiedV11{}, col) {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(&userEmailVerifiedV11{}, col); err != nil {
+					return err
+				}
+				// Existing accounts keep receiving digests at the addresses
+				// they already use; only addresses set from now on must be
+				// confirmed.
+				var err error
+				switch col {
+				case "EmailVerified":
+					err = tx.Model(&userEmailVerifiedV11{}).Where("1 = 1").Update("email_verified", true).Error
+				case "NotificationEmailVerified":
+					err = tx.Model(&userEmailVerifiedV11{}).Where("notification_email <> ''").Update("notification_email_verified", true).Error
+				}
+				if err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().CreateTable(&emailVerificationV11{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&emailVerificationV11{}); err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropColumn(&userEmailVerifiedV11{}, "NotificationEmailVerified"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&userEmailVerifiedV11{}, "EmailVerified")
+		},
+	},
+	{
+		Version: 12,
+		Name:    "add_two_factor",
+		Up: func(tx *gorm.DB) error {
+			for _, col := range userTOTPColumnsV12 {
+				if tx.Migrator().HasColumn(&userTOTPV12{}, col) {
+					continue
+				}
+				if err := tx.Migrator().AddColumn(&userTOTPV12{}, col); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().CreateTable(&recoveryCodeV12{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&recoveryCodeV12{}); err != nil {
+				return err
+			}
+			for _, col := range userTOTPColumnsV12 {
+				if err := tx.Migrator().DropColumn(&userTOTPV12{}, col); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 13,
+		Name:    "create_passkeys",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&passkeyV13{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&passkeyV13{})
+		},
+	},
+	{
+		Version: 14,
+		Name:    "create_sessions",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&sessionV14{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&sessionV14{})
+		},
+	},
+}
+
+// Version 1 mirrors the tables previously created by AutoMigrate, so running
+// it against a database from before versioned migrations is a no-op.
+
+type userV1 struct {
+	gorm.Model
+	Name                      string
+	Email                     string `gorm:"uniqueIndex"`
+	Password                  string
+	Plans                     []planV1             `gorm:"foreignKey:UserID"`
+	PushSubscriptions         []pushSubscriptionV1 `gorm:"foreignKey:UserID"`
+	NotificationsEnabled      bool
+	NotificationTime          string
+	CreatedAt                 time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt                 *time.Time `gorm:"autoUpdateTime"`
+	DeletedAt                 *time.Time
+	EmailNotificationsEnabled bool `gorm:"default:false"`
+	NotificationEmail         string
+}
+
+func (userV1) TableName() string { return "users" }
+
+type planV1 struct {
+	gorm.Model
+	Title        string
+	UserID       uint
+	Readings     []readingV1 `gorm:"foreignKey:PlanID"`
+	Status       string      `gorm:"default:'active'"`
+	ErrorMessage string
+	CreatedAt    time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt    *time.Time `gorm:"autoUpdateTime"`
+	DeletedAt    *time.Time
+}
+
+func (planV1) TableName() string { return "plans" }
+
+type readingV1 struct {
+	gorm.Model
+	PlanID      uint
+	Date        time.Time
+	DateType    string
+	Content     string
+	Status      string `gorm:"default:pending"`
+	CompletedAt *time.Time
+	CreatedAt   time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt   *time.Time `gorm:"autoUpdateTime"`
+	DeletedAt   *time.Time
+}
+
+func (readingV1) TableName() string { return "readings" }
+
+type pushSubscriptionV1 struct {
+	gorm.Model
+	UserID   uint   `gorm:"index"`
+	Endpoint string `gorm:"uniqueIndex"`
+	P256DH   string
+	Auth     string
+}
+
+func (pushSubscriptionV1) TableName() string { return "push_subscriptions" }
+
+func baselineUp(tx *gorm.DB) error {
+	return tx.AutoMigrate(&userV1{}, &planV1{}, &readingV1{}, &pushSubscriptionV1{})
+}
+
+func baselineDown(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&pushSubscriptionV1{}, &readingV1{}, &planV1{}, &userV1{})
+}
+
+type userTimezoneV2 struct {
+	Timezone string `gorm:"default:''"`
+}
+
+func (userTimezoneV2) TableName() string { return "users" }
+
+type jobV3 struct {
+	gorm.Model
+	Type        string `gorm:"index"`
+	Status      string `gorm:"index;default:pending"`
+	UserID      uint   `gorm:"index"`
+	PlanID      uint   `gorm:"index"`
+	Payload     []byte
+	Attempts    int
+	MaxAttempts int
+	LastError   string
+	RunAfter    time.Time `gorm:"index"`
+	LockedAt    *time.Time
+	LockedBy    string
+	FinishedAt  *time.Time
+}
+
+func (jobV3) TableName() string { return "jobs" }
+
+type readingDetailsV4 struct {
+	Notes           string `gorm:"default:''"`
+	Link            string `gorm:"default:''"`
+	SortOrder       int    `gorm:"default:0"`
+	Tags            string `gorm:"default:''"`
+	DurationMinutes int    `gorm:"default:0"`
+}
+
+func (readingDetailsV4) TableName() string { return "readings" }
+
+var readingDetailColumnsV4 = []string{"Notes", "Link", "SortOrder", "Tags", "DurationMinutes"}
+
+type readingOffsetV5 struct {
+	StartOffset *int
+}
+
+func (readingOffsetV5) TableName() string { return "readings" }
+
+type planStartDateV5 struct {
+	StartDate *time.Time
+}
+
+func (planStartDateV5) TableName() string { return "plans" }
+
+type planPausedAtV6 struct {
+	PausedAt *time.Time
+}
+
+func (planPausedAtV6) TableName() string { return "plans" }
+
+type readingSkipV7 struct {
+	SkippedAt  *time.Time
+	SkipReason string `gorm:"default:''"`
+}
+
+func (readingSkipV7) TableName() string { return "readings" }
+
+type journalEntryV8 struct {
+	gorm.Model
+	ReadingID uint `gorm:"uniqueIndex"`
+	UserID    uint `gorm:"index"`
+	Body      string
+	EditedAt  *time.Time
+}
+
+func (journalEntryV8) TableName() string { return "journal_entries" }
+
+// Version 9 indexes readings for full-text search on SQLite with an FTS5
+// table keyed by reading ID, kept current by triggers on the tables it
+// draws from. PostgreSQL builds its tsvector at query time instead.
+
+var readingSearchUpV9 = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS reading_search USING fts5(
+		content, notes, plan_title, journal,
+		tokenize = 'unicode61 remove_diacritics 2'
+	)`,
+	`INSERT INTO reading_search (rowid, content, notes, plan_title, journal)
+		SELECT readings.id, COALESCE(readings.content, ''), COALESCE(readings.notes, ''),
+			COALESCE(plans.title, ''), COALESCE(journal_entries.body, '')
+		FROM readings
+		LEFT JOIN plans ON plans.id = readings.plan_id
+		LEFT JOIN journal_entries ON journal_entries.reading_id = readings.id`,
+	`CREATE TRIGGER IF NOT EXISTS reading_search_reading_insert AFTER INSERT ON readings BEGIN
+		INSERT INTO reading_search (rowid, content, notes, plan_title, journal)
+		VALUES (new.id, COALESCE(new.content, ''), COALESCE(new.notes, ''),
+			COALESCE((SELECT title FROM plans WHERE id = new.plan_id), ''), '');
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS reading_search_reading_update AFTER UPDATE OF content, notes, plan_id ON readings BEGIN
+		UPDATE reading_search SET content = COALESCE(new.content, ''), notes = COALESCE(new.notes, ''),
+			plan_title = COALESCE((SELECT title FROM plans WHERE id = new.plan_id), '')
+		WHERE rowid = new.id;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS reading_search_reading_delete AFTER DELETE ON readings BEGIN
+		DELETE FROM reading_search WHERE rowid = old.id;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS reading_search_plan_update AFTER UPDATE OF title ON plans BEGIN
+		UPDATE reading_search SET plan_title = COALESCE(new.title, '')
+		WHERE rowid IN (SELECT id FROM readings WHERE plan_id = new.id);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS reading_search_journal_insert AFTER INSERT ON journal_entries BEGIN
+		UPDATE reading_search SET journal = COALESCE(new.body, '') WHERE rowid = new.reading_id;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS reading_search_journal_update AFTER UPDATE OF body ON journal_entries BEGIN
+		UPDATE reading_search SET journal = COALESCE(new.body, '') WHERE rowid = new.reading_id;
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS reading_search_journal_delete AFTER DELETE ON journal_entries BEGIN
+		UPDATE reading_search SET journal = '' WHERE rowid = old.reading_id;
+	END`,
+}
+
+var readingSearchDownV9 = []string{
+	`DROP TRIGGER IF EXISTS reading_search_journal_delete`,
+	`DROP TRIGGER IF EXISTS reading_search_journal_update`,
+	`DROP TRIGGER IF EXISTS reading_search_journal_insert`,
+	`DROP TRIGGER IF EXISTS reading_search_plan_update`,
+	`DROP TRIGGER IF EXISTS reading_search_reading_delete`,
+	`DROP TRIGGER IF EXISTS reading_search_reading_update`,
+	`DROP TRIGGER IF EXISTS reading_search_reading_insert`,
+	`DROP TABLE IF EXISTS reading_search`,
+}
+
+type passwordResetV10 struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index"`
+	TokenHash string `gorm:"uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (passwordResetV10) TableName() string { return "password_resets" }
+
+type userEmailVerifiedV11 struct {
+	EmailVerified             bool `gorm:"default:false"`
+	NotificationEmailVerified bool `gorm:"default:false"`
+}
+
+func (userEmailVerifiedV11) TableName() string { return "users" }
+
+type emailVerificationV11 struct {
+	ID        uint `gorm:"primarykey"`
+	UserID    uint `gorm:"index"`
+	Email     string
+	TokenHash string `gorm:"uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (emailVerificationV11) TableName() string { return "email_verifications" }
+
+type userTOTPV12 struct {
+	TOTPSecret    string
+	TOTPEnabledAt *time.Time
+	TOTPLastStep  int64
+}
+
+func (userTOTPV12) TableName() string { return "users" }
+
+var userTOTPColumnsV12 = []string{"TOTPSecret", "TOTPEnabledAt", "TOTPLastStep"}
+
+type recoveryCodeV12 struct {
+	ID        uint `gorm:"primarykey"`
+	UserID    uint `gorm:"index"`
+	CodeHash  string
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (recoveryCodeV12) TableName() string { return "recovery_codes" }
+
+type passkeyV13 struct {
+	ID                uint `gorm:"primarykey"`
+	UserID            uint `gorm:"index"`
+	Name              string
+	CredentialID      []byte `gorm:"uniqueIndex"`
+	PublicKey         []byte
+	AttestationType   string
+	AttestationFormat string
+	Transports        string
+	AAGUID            []byte
+	SignCount         uint32
+	Flags             uint8
+	LastUsedAt        *time.Time
+	CreatedAt         time.Time
+}
+
+func (passkeyV13) TableName() string { return "passkeys" }
+
+type sessionV14 struct {
+	ID         uint   `gorm:"primarykey"`
+	TokenHash  string `gorm:"uniqueIndex"`
+	Name       string
+	UserID     *uint `gorm:"index"`
+	Data       string
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time `gorm:"index"`
+}
+
+func (sessionV14) TableName() string { return "sessions" }
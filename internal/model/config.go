@@ -4,6 +4,7 @@ package model
 
 import (
 	"encoding/base64"
+	"net/netip"
 	"os"
 	"strings"
 	"unicode"
@@ -24,10 +25,14 @@ const (
 
 // Config holds the runtime configuration for the readwillbe server.
 type Config struct {
+	DBDriver        string // "sqlite" (default) or "postgres"
+	DBDSN           string // connection string; for sqlite defaults to DBPath
 	DBPath          string
 	CookieSecret    []byte
 	AllowSignup     bool
 	SeedDB          bool
+	AutoMigrate     bool // apply pending migrations at startup instead of refusing to start
+	JobWorkers      int  // background job workers; SQLite is always limited to one
 	Port            string
 	VAPIDPublicKey  string
 	VAPIDPrivateKey string
@@ -47,6 +52,21 @@ type Config struct {
 	// Resend settings (used when EmailProvider = "resend")
 	ResendAPIKey string
 	ResendFrom   string // "ReadWillBe <noreply@example.com>"
+
+	// OpenID Connect single sign-on (disabled unless OIDCIssuer is set)
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string // defaults to https://<hostname>/auth/oidc/callback
+	OIDCProviderName string // shown on the sign-in button
+	OIDCCreateUsers  bool   // create accounts on first sign-in, if AllowSignup is also set
+
+	// Reverse-proxy authentication (disabled unless ProxyAuthHeader is set).
+	// Requests from TrustedProxies are signed in as the email address in
+	// ProxyAuthHeader, e.g. "Remote-Email" or "X-Forwarded-Email".
+	ProxyAuthHeader     string
+	ProxyAuthNameHeader string // optional display name for new accounts, e.g. "Remote-Name"
+	TrustedProxies      []netip.Prefix
 }
 
 // IsProduction reports whether the server is running with GO_ENV set to
@@ -61,6 +81,23 @@ func (c Config) EmailEnabled() bool {
 	return c.EmailProvider == "smtp" || c.EmailProvider == "resend"
 }
 
+// OIDCEnabled reports whether users can sign in through an OpenID Connect
+// identity provider.
+func (c Config) OIDCEnabled() bool {
+	return c.OIDCIssuer != ""
+}
+
+// ProxyAuthEnabled reports whether a trusted reverse proxy signs users in.
+func (c Config) ProxyAuthEnabled() bool {
+	return c.ProxyAuthHeader != ""
+}
+
+// PasskeysEnabled reports whether passkey sign-in can be offered. WebAuthn
+// binds credentials to the site's hostname, so one must be configured.
+func (c Config) PasskeysEnabled() bool {
+	return c.Hostname != ""
+}
+
 func estimateEntropy(s string) int {
 	hasLower := false
 	hasUpper := false
@@ -107,6 +144,29 @@ func estimateEntropy(s string) int {
 	return len(s) * bitsPerChar
 }
 
+// DatabaseFromViper returns the database driver and DSN from the currently
+// configured viper values. For SQLite the DSN falls back to db_path.
+func DatabaseFromViper() (driver, dsn string, err error) {
+	driver = strings.ToLower(viper.GetString("db_driver"))
+	dsn = viper.GetString("db_dsn")
+
+	switch driver {
+	case "", "sqlite":
+		driver = "sqlite"
+		if dsn == "" {
+			dsn = viper.GetString("db_path")
+		}
+	case "postgres":
+		if dsn == "" {
+			return "", "", errors.New("db_dsn is required when db_driver is 'postgres'")
+		}
+	default:
+		return "", "", errors.New("db_driver must be 'sqlite' or 'postgres'")
+	}
+
+	return driver, dsn, nil
+}
+
 // ConfigFromViper builds a [Config] from the currently configured viper
 // values, validating required fields.
 func ConfigFromViper() (Config, error) {
@@ -130,6 +190,16 @@ func ConfigFromViper() (Config, error) {
 		}
 	}
 
+	dbDriver, dbDSN, err := DatabaseFromViper()
+	if err != nil {
+		return Config{}, err
+	}
+
+	jobWorkers := viper.GetInt("job_workers")
+	if jobWorkers < 1 || dbDriver == "sqlite" {
+		jobWorkers = 1
+	}
+
 	port := viper.GetString("port")
 	if port != "" && port[0] != ':' {
 		port = ":" + port
@@ -159,28 +229,91 @@ func ConfigFromViper() (Config, error) {
 		}
 	}
 
+	oidcIssuer := viper.GetString("oidc_issuer")
+	oidcRedirectURL := viper.GetString("oidc_redirect_url")
+	oidcProviderName := viper.GetString("oidc_provider_name")
+	if oidcIssuer != "" {
+		if viper.GetString("oidc_client_id") == "" {
+			return Config{}, errors.New("oidc_client_id is required when oidc_issuer is set")
+		}
+		if oidcRedirectURL == "" {
+			if viper.GetString("hostname") == "" {
+				return Config{}, errors.New("oidc_redirect_url or hostname is required when oidc_issuer is set")
+			}
+			oidcRedirectURL = "https://" + viper.GetString("hostname") + "/auth/oidc/callback"
+		}
+		if oidcProviderName == "" {
+			oidcProviderName = "Single Sign-On"
+		}
+	}
+
+	trustedProxies, err := parseTrustedProxies(viper.GetStringSlice("trusted_proxies"))
+	if err != nil {
+		return Config{}, err
+	}
+	if viper.GetString("proxy_auth_header") != "" && len(trustedProxies) == 0 {
+		return Config{}, errors.New("trusted_proxies is required when proxy_auth_header is set")
+	}
+
 	smtpTLS := strings.ToLower(viper.GetString("smtp_tls"))
 	if smtpTLS == "" {
 		smtpTLS = "starttls"
 	}
 
 	return Config{
-		DBPath:          viper.GetString("db_path"),
-		CookieSecret:    []byte(cookieSecret),
-		AllowSignup:     viper.GetBool("allow_signup"),
-		SeedDB:          viper.GetBool("seed_db"),
-		Port:            port,
-		VAPIDPublicKey:  viper.GetString("vapid_public_key"),
-		VAPIDPrivateKey: viper.GetString("vapid_private_key"),
-		Hostname:        viper.GetString("hostname"),
-		EmailProvider:   emailProvider,
-		SMTPHost:        viper.GetString("smtp_host"),
-		SMTPPort:        viper.GetInt("smtp_port"),
-		SMTPUsername:    viper.GetString("smtp_username"),
-		SMTPPassword:    viper.GetString("smtp_password"),
-		SMTPFrom:        viper.GetString("smtp_from"),
-		SMTPTLS:         smtpTLS,
-		ResendAPIKey:    viper.GetString("resend_api_key"),
-		ResendFrom:      viper.GetString("resend_from"),
+		DBDriver:            dbDriver,
+		DBDSN:               dbDSN,
+		DBPath:              viper.GetString("db_path"),
+		CookieSecret:        []byte(cookieSecret),
+		AllowSignup:         viper.GetBool("allow_signup"),
+		SeedDB:              viper.GetBool("seed_db"),
+		AutoMigrate:         viper.GetBool("auto_migrate"),
+		JobWorkers:          jobWorkers,
+		Port:                port,
+		VAPIDPublicKey:      viper.GetString("vapid_public_key"),
+		VAPIDPrivateKey:     viper.GetString("vapid_private_key"),
+		Hostname:            viper.GetString("hostname"),
+		EmailProvider:       emailProvider,
+		SMTPHost:            viper.GetString("smtp_host"),
+		SMTPPort:            viper.GetInt("smtp_port"),
+		SMTPUsername:        viper.GetString("smtp_username"),
+		SMTPPassword:        viper.GetString("smtp_password"),
+		SMTPFrom:            viper.GetString("smtp_from"),
+		SMTPTLS:             smtpTLS,
+		ResendAPIKey:        viper.GetString("resend_api_key"),
+		ResendFrom:          viper.GetString("resend_from"),
+		OIDCIssuer:          oidcIssuer,
+		OIDCClientID:        viper.GetString("oidc_client_id"),
+		OIDCClientSecret:    viper.GetString("oidc_client_secret"),
+		OIDCRedirectURL:     oidcRedirectURL,
+		OIDCProviderName:    oidcProviderName,
+		OIDCCreateUsers:     viper.GetBool("oidc_create_users"),
+		ProxyAuthHeader:     viper.GetString("proxy_auth_header"),
+		ProxyAuthNameHeader: viper.GetString("proxy_auth_name_header"),
+		TrustedProxies:      trustedProxies,
 	}, nil
 }
+
+// parseTrustedProxies parses CIDR ranges, which may also be given as a single
+// comma-separated string. A bare address is a range of one.
+func parseTrustedProxies(values []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, value := range values {
+		for _, s := range strings.Split(value, ",") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+			if addr, err := netip.ParseAddr(s); err == nil {
+				prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+				continue
+			}
+			prefix, err := netip.ParsePrefix(s)
+			if err != nil {
+				return nil, errors.Errorf("trusted_proxies: %q is not a CIDR range or IP address", s)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+		}
+	}
+	return prefixes, nil
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// EmailVerification is a single-use token emailed to an address to confirm
+// that it belongs to the user. Email records which address it was sent to,
+// so a token goes stale if the user changes that address meanwhile. Only a
+// hash of the token is stored.
+type EmailVerification struct {
+	ID        uint `gorm:"primarykey"`
+	UserID    uint `gorm:"index"`
+	Email     string
+	TokenHash string `gorm:"uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// JobStatus enumerates the lifecycle states of a [Job].
+type JobStatus string
+
+// Job status values.
+const (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+)
+
+// Job is a unit of background work persisted so it survives restarts.
+// Payload is handler-specific and is cleared once the job finishes.
+type Job struct {
+	gorm.Model
+	Type        string    `gorm:"index"`
+	Status      JobStatus `gorm:"index;default:pending"`
+	UserID      uint      `gorm:"index"`
+	PlanID      uint      `gorm:"index"` // plan the job acts on, if any
+	Payload     []byte
+	Attempts    int
+	MaxAttempts int
+	LastError   string
+	RunAfter    time.Time `gorm:"index"`
+	LockedAt    *time.Time
+	LockedBy    string
+	FinishedAt  *time.Time
+}
+
+// IsFinished reports whether the job has succeeded or permanently failed.
+func (j Job) IsFinished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed
+}
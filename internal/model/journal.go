@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaxJournalLength bounds the markdown body of a [JournalEntry].
+const MaxJournalLength = 10000
+
+// JournalEntry is a user's markdown reflection on one [Reading]. A reading
+// has at most one entry.
+type JournalEntry struct {
+	gorm.Model
+	ReadingID uint `gorm:"uniqueIndex"`
+	Reading   Reading
+	UserID    uint `gorm:"index"`
+	Body      string
+	EditedAt  *time.Time // last change to Body after the entry was first written
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// Passkey is a WebAuthn credential the user has registered, used to sign in
+// without a password or as a second factor after one.
+type Passkey struct {
+	ID                uint `gorm:"primarykey"`
+	UserID            uint `gorm:"index"`
+	Name              string
+	CredentialID      []byte `gorm:"uniqueIndex"`
+	PublicKey         []byte
+	AttestationType   string
+	AttestationFormat string
+	Transports        string // comma-separated, as reported at registration
+	AAGUID            []byte
+	SignCount         uint32
+	Flags             uint8 // raw authenticator data flags
+	LastUsedAt        *time.Time
+	CreatedAt         time.Time
+}
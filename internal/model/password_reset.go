@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// PasswordReset is a single-use token emailed to a user who has forgotten
+// their password. Only a hash of the token is stored, so a leaked database
+// cannot be used to take over accounts.
+type PasswordReset struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index"`
+	TokenHash string `gorm:"uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -15,21 +15,29 @@ type Plan struct {
 	Readings     []Reading
 	Status       string `gorm:"default:'active'"`
 	ErrorMessage string
+	StartDate    *time.Time // day the plan begins; readings with a StartOffset count from it
+	PausedAt     *time.Time // calendar day the plan was paused; nil while running
 	CreatedAt    time.Time  `gorm:"autoCreateTime"`
 	UpdatedAt    *time.Time `gorm:"autoUpdateTime"`
 	DeletedAt    *time.Time
 }
 
 // IsComplete reports whether the plan has at least one reading and every
-// reading is in StatusCompleted.
+// reading is completed or skipped.
 func (p Plan) IsComplete() bool {
 	if len(p.Readings) == 0 {
 		return false
 	}
 	for _, r := range p.Readings {
-		if r.Status != StatusCompleted {
+		if !r.IsDone() {
 			return false
 		}
 	}
 	return true
 }
+
+// IsPaused reports whether the plan is paused. Readings of a paused plan are
+// left off the dashboard and out of reminders until it is resumed.
+func (p Plan) IsPaused() bool {
+	return p.PausedAt != nil
+}
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"math"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -14,6 +16,7 @@ const (
 	StatusPending   ReadingStatus = "pending"
 	StatusCompleted ReadingStatus = "completed"
 	StatusOverdue   ReadingStatus = "overdue"
+	StatusSkipped   ReadingStatus = "skipped"
 )
 
 // DateType describes the cadence on which a [Reading] is scheduled.
@@ -26,6 +29,20 @@ const (
 	DateTypeMonth DateType = "month"
 )
 
+// Steps returns how many whole units of the cadence, days, weeks or months, a
+// reading of this type moves when its schedule shifts by days. Weeks and
+// months round to the nearest, so a short shift may not move them at all.
+func (t DateType) Steps(days int) int {
+	switch t {
+	case DateTypeWeek:
+		return int(math.Round(float64(days) / 7))
+	case DateTypeMonth:
+		return int(math.Round(float64(days) / 30))
+	default:
+		return days
+	}
+}
+
 // Reading is a single dated entry within a [Plan].
 type Reading struct {
 	gorm.Model
@@ -36,9 +53,38 @@ type Reading struct {
 	Content     string
 	Status      ReadingStatus `gorm:"default:pending"`
 	CompletedAt *time.Time
+	SkippedAt   *time.Time
+	SkipReason  string
+	Journal     *JournalEntry
 	CreatedAt   time.Time  `gorm:"autoCreateTime"`
 	UpdatedAt   *time.Time `gorm:"autoUpdateTime"`
 	DeletedAt   *time.Time
+
+	// StartOffset is set for readings scheduled relative to the plan's StartDate
+	// ("Day n", "Week n") rather than on a calendar date. It counts units of
+	// DateType from the start, so Day 1 and Week 1 are both 0.
+	StartOffset *int
+
+	// Optional details imported from extra CSV columns.
+	Notes           string
+	Link            string
+	SortOrder       int    // position among readings on the same date
+	Tags            string // comma-separated
+	DurationMinutes int
+}
+
+// IsDone reports whether the reading needs no further action, having been
+// either completed or skipped.
+func (r Reading) IsDone() bool {
+	return r.Status == StatusCompleted || r.Status == StatusSkipped
+}
+
+// TagList returns the reading's tags, or nil if it has none.
+func (r Reading) TagList() []string {
+	if r.Tags == "" {
+		return nil
+	}
+	return strings.Split(r.Tags, ",")
 }
 
 // PlanGroup bundles a [Plan] with a subset of its [Reading]s for rendering.
@@ -47,10 +93,10 @@ type PlanGroup struct {
 	Readings []Reading
 }
 
-// HasOverdue reports whether any reading in the group is overdue.
-func (pg PlanGroup) HasOverdue() bool {
+// HasOverdue reports whether any reading in the group is overdue as of now.
+func (pg PlanGroup) HasOverdue(now time.Time) bool {
 	for _, r := range pg.Readings {
-		if r.IsOverdue() {
+		if r.IsOverdue(now) {
 			return true
 		}
 	}
@@ -58,23 +104,43 @@ func (pg PlanGroup) HasOverdue() bool {
 }
 
 // IsOverdue reports whether the reading is past its scheduled cadence and
-// still pending.
-func (r Reading) IsOverdue() bool {
-	now := time.Now()
+// still pending. now should be expressed in the viewing user's timezone; the
+// comparison is made on calendar dates, not instants.
+func (r Reading) IsOverdue(now time.Time) bool {
+	if r.Status != StatusPending {
+		return false
+	}
+	today := CivilDate(now)
+	start := CivilDate(r.Date)
 	switch r.DateType {
 	case DateTypeDay:
-		return now.After(r.Date.AddDate(0, 0, 1)) && r.Status == StatusPending
+		return !today.Before(start.AddDate(0, 0, 1))
 	case DateTypeWeek:
-		return now.After(r.Date.AddDate(0, 0, 7)) && r.Status == StatusPending
+		return !today.Before(start.AddDate(0, 0, 7))
 	case DateTypeMonth:
-		return now.After(r.Date.AddDate(0, 1, 0)) && r.Status == StatusPending
+		return !today.Before(start.AddDate(0, 1, 0))
 	}
 	return false
 }
 
-// IsActiveToday reports whether the reading's scheduled window contains today.
-func (r Reading) IsActiveToday() bool {
-	now := time.Now()
+// LastDay returns the final calendar day of the reading's scheduled window,
+// as UTC midnight: its date for day readings, six days on for week readings
+// and the day before the same date next month for month readings.
+func (r Reading) LastDay() time.Time {
+	start := CivilDate(r.Date)
+	switch r.DateType {
+	case DateTypeWeek:
+		return start.AddDate(0, 0, 6)
+	case DateTypeMonth:
+		return start.AddDate(0, 1, -1)
+	default:
+		return start
+	}
+}
+
+// IsActiveToday reports whether the reading's scheduled window contains the
+// calendar day of now, which should be in the viewing user's timezone.
+func (r Reading) IsActiveToday(now time.Time) bool {
 	switch r.DateType {
 	case DateTypeDay:
 		return r.Date.Year() == now.Year() && r.Date.YearDay() == now.YearDay()
@@ -88,6 +154,47 @@ func (r Reading) IsActiveToday() bool {
 	return false
 }
 
+// ShiftedDate returns the reading's date moved by days while keeping its
+// cadence: week readings move by whole weeks and month readings by whole
+// months, rounding to the nearest, so they stay aligned to the start of their
+// week or month.
+func (r Reading) ShiftedDate(days int) time.Time {
+	steps := r.DateType.Steps(days)
+	switch r.DateType {
+	case DateTypeWeek:
+		return r.Date.AddDate(0, 0, 7*steps)
+	case DateTypeMonth:
+		return r.Date.AddDate(0, steps, 0)
+	default:
+		return r.Date.AddDate(0, 0, steps)
+	}
+}
+
+// RelativeDate returns the calendar date of the reading offset units of
+// dateType into a plan starting on start. Week readings begin on the Monday
+// of the ISO week containing start, matching how week readings are tracked.
+func RelativeDate(start time.Time, dateType DateType, offset int) time.Time {
+	day := CivilDate(start)
+	switch dateType {
+	case DateTypeWeek:
+		weekday := int(day.Weekday())
+		if weekday == 0 {
+			weekday = 7
+		}
+		return day.AddDate(0, 0, 1-weekday+7*offset)
+	default:
+		return day.AddDate(0, 0, offset)
+	}
+}
+
+// CivilDate returns midnight UTC on t's wall-clock date. Reading dates are
+// calendar dates, so they are compared on that basis regardless of the zone
+// they or the caller's clock happen to carry.
+func CivilDate(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
 // FormattedDate returns a human-readable date string based on the reading's DateType.
 // - Day: "Jan 2, 2006"
 // - Week: "Jan 2-8, 2006" (or "Dec 30 - Jan 5" for month-spanning weeks)
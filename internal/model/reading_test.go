@@ -65,8 +65,8 @@ func TestReading_IsOverdue(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := tt.reading.IsOverdue(); got != tt.want {
-				t.Errorf("Reading.IsOverdue() = %v, want %v", got, tt.want)
+			if got := tt.reading.IsOverdue(now); got != tt.want {
+				t.Errorf("Reading.IsOverdue(now) = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -117,9 +117,101 @@ func TestReading_IsActiveToday(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := tt.reading.IsActiveToday(); got != tt.want {
-				t.Errorf("Reading.IsActiveToday() = %v, want %v", got, tt.want)
+			if got := tt.reading.IsActiveToday(now); got != tt.want {
+				t.Errorf("Reading.IsActiveToday(now) = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestReading_UserTimezone(t *testing.T) {
+	la, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	// 8pm Jan 15 in Los Angeles is already 4am Jan 16 in UTC.
+	instant := time.Date(2025, time.January, 16, 4, 0, 0, 0, time.UTC)
+	reading := Reading{
+		Date:     time.Date(2025, time.January, 15, 0, 0, 0, 0, time.UTC),
+		DateType: DateTypeDay,
+		Status:   StatusPending,
+	}
+
+	laNow := instant.In(la)
+	if !reading.IsActiveToday(laNow) {
+		t.Errorf("reading should still be active for a Los Angeles user")
+	}
+	if reading.IsOverdue(laNow) {
+		t.Errorf("reading should not be overdue for a Los Angeles user")
+	}
+
+	utcNow := instant.In(time.UTC)
+	if reading.IsActiveToday(utcNow) {
+		t.Errorf("reading should no longer be active for a UTC user")
+	}
+	if !reading.IsOverdue(utcNow) {
+		t.Errorf("reading should be overdue for a UTC user")
+	}
+}
+
+func TestUser_Location(t *testing.T) {
+	if got := (User{}).Location(); got != time.Local {
+		t.Errorf("empty timezone should fall back to time.Local, got %v", got)
+	}
+	if got := (User{Timezone: "Not/AZone"}).Location(); got != time.Local {
+		t.Errorf("unknown timezone should fall back to time.Local, got %v", got)
+	}
+	if got := (User{Timezone: "UTC"}).Location(); got.String() != "UTC" {
+		t.Errorf("Location() = %v, want UTC", got)
+	}
+}
+
+func TestPlan_IsCompleteCountsSkipped(t *testing.T) {
+	plan := Plan{Readings: []Reading{{Status: StatusCompleted}, {Status: StatusSkipped}}}
+	if !plan.IsComplete() {
+		t.Errorf("plan with only completed and skipped readings should be complete")
+	}
+
+	plan.Readings = append(plan.Readings, Reading{Status: StatusPending})
+	if plan.IsComplete() {
+		t.Errorf("plan with a pending reading should not be complete")
+	}
+}
+
+func TestReading_ShiftedDateKeepsCadence(t *testing.T) {
+	monday := time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		reading Reading
+		days    int
+		want    time.Time
+	}{
+		{Reading{Date: monday, DateType: DateTypeDay}, 3, monday.AddDate(0, 0, 3)},
+		{Reading{Date: monday, DateType: DateTypeWeek}, 3, monday},
+		{Reading{Date: monday, DateType: DateTypeWeek}, 11, monday.AddDate(0, 0, 14)},
+		{Reading{Date: monday, DateType: DateTypeWeek}, -10, monday.AddDate(0, 0, -7)},
+		{Reading{Date: time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC), DateType: DateTypeMonth}, 45, time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := tt.reading.ShiftedDate(tt.days); !got.Equal(tt.want) {
+			t.Errorf("%s reading shifted %d days = %v, want %v", tt.reading.DateType, tt.days, got, tt.want)
+		}
+	}
+}
+
+func TestReading_LastDay(t *testing.T) {
+	monday := time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		reading Reading
+		want    time.Time
+	}{
+		{Reading{Date: monday, DateType: DateTypeDay}, monday},
+		{Reading{Date: monday, DateType: DateTypeWeek}, time.Date(2026, time.March, 8, 0, 0, 0, 0, time.UTC)},
+		{Reading{Date: time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC), DateType: DateTypeMonth}, time.Date(2026, time.February, 28, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := tt.reading.LastDay(); !got.Equal(tt.want) {
+			t.Errorf("%s reading last day = %v, want %v", tt.reading.DateType, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// RecoveryCode is a single-use code that stands in for a one-time password
+// when the user has lost their authenticator. Only a hash is stored.
+type RecoveryCode struct {
+	ID        uint `gorm:"primarykey"`
+	UserID    uint `gorm:"index"`
+	CodeHash  string
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// Session is a browser's server-side session. Its cookie carries only a
+// random token, of which just the hash is stored, so deleting the row
+// revokes the session.
+type Session struct {
+	ID         uint   `gorm:"primarykey"`
+	TokenHash  string `gorm:"uniqueIndex"`
+	Name       string // the cookie it belongs to
+	UserID     *uint  `gorm:"index"` // nil until a user signs in
+	Data       string // encoded session values
+	UserAgent  string
+	IP         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time // refreshed at least every SessionRefreshInterval of use
+	ExpiresAt  time.Time `gorm:"index"`
+}
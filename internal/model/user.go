@@ -11,11 +11,13 @@ type User struct {
 	gorm.Model
 	Name                 string
 	Email                string `gorm:"uniqueIndex"`
+	EmailVerified        bool   `gorm:"default:false"`
 	Password             string
 	Plans                []Plan
 	PushSubscriptions    []PushSubscription
 	NotificationsEnabled bool
 	NotificationTime     string
+	Timezone             string     `gorm:"default:''"` // IANA zone name; empty = server default
 	CreatedAt            time.Time  `gorm:"autoCreateTime"`
 	UpdatedAt            *time.Time `gorm:"autoUpdateTime"`
 	DeletedAt            *time.Time
@@ -23,6 +25,14 @@ type User struct {
 	// Email notifications (in addition to push)
 	EmailNotificationsEnabled bool   `gorm:"default:false"`
 	NotificationEmail         string // Empty = use user's primary Email
+	NotificationEmailVerified bool   `gorm:"default:false"`
+
+	// Two-factor authentication. TOTPSecret is only set while it is enabled.
+	TOTPSecret    string
+	TOTPEnabledAt *time.Time
+	TOTPLastStep  int64 // time step of the last code accepted, so none is reused
+	RecoveryCodes []RecoveryCode
+	Passkeys      []Passkey
 }
 
 // IsSet reports whether the user has a non-empty email address, used as a
@@ -31,6 +41,31 @@ func (u User) IsSet() bool {
 	return u.Email != ""
 }
 
+// TwoFactorEnabled reports whether signing in needs a one-time code after
+// the password.
+func (u User) TwoFactorEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
+// Location returns the user's configured timezone, falling back to the
+// server default when none is set.
+func (u User) Location() *time.Location {
+	return ResolveLocation(u.Timezone)
+}
+
+// ResolveLocation loads the named IANA timezone, returning [time.Local] (the
+// server default set from the tz config) when name is empty or unknown.
+func ResolveLocation(name string) *time.Location {
+	if name == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
 // GetNotificationEmail returns the dedicated notification email if set,
 // falling back to the primary user email.
 func (u User) GetNotificationEmail() string {
@@ -39,3 +74,13 @@ func (u User) GetNotificationEmail() string {
 	}
 	return u.Email
 }
+
+// NotificationEmailIsVerified reports whether the address returned by
+// [User.GetNotificationEmail] has been confirmed by its owner. Digests are
+// only sent to verified addresses.
+func (u User) NotificationEmailIsVerified() bool {
+	if u.NotificationEmail != "" {
+		return u.NotificationEmailVerified
+	}
+	return u.EmailVerified
+}
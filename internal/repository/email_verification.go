@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"readwillbe/internal/model"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// EmailVerificationTTL is how long an emailed verification link stays valid.
+const EmailVerificationTTL = 48 * time.Hour
+
+// ErrInvalidVerificationToken is returned for a verification token that is
+// unknown, expired, already used, or for an address the user no longer has.
+var ErrInvalidVerificationToken = errors.New("this verification link is invalid or has expired")
+
+// CreateEmailVerification issues a token confirming that email belongs to
+// the user, valid for EmailVerificationTTL from now. The returned token is
+// the only copy; just its hash is stored.
+func CreateEmailVerification(tx *gorm.DB, userID uint, email string, now time.Time) (string, error) {
+	now = now.UTC()
+	token, hash, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	verification := model.EmailVerification{
+		UserID:    userID,
+		Email:     email,
+		TokenHash: hash,
+		ExpiresAt: now.Add(EmailVerificationTTL),
+		CreatedAt: now,
+	}
+	if err := tx.Create(&verification).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// CountEmailVerifications counts the verification tokens issued to the user
+// since the given time.
+func CountEmailVerifications(tx *gorm.DB, userID uint, since time.Time) (int64, error) {
+	var n int64
+	err := tx.Model(&model.EmailVerification{}).
+		Where("user_id = ? AND created_at >= ?", userID, since.UTC()).
+		Count(&n).Error
+	return n, err
+}
+
+// VerifyEmail uses up a verification token and marks the address it was
+// sent to as verified, as the user's primary address, notification address,
+// or both. It returns the user's ID.
+func VerifyEmail(tx *gorm.DB, token string, now time.Time) (uint, error) {
+	now = now.UTC()
+	var userID uint
+	err := tx.Transaction(func(tx *gorm.DB) error {
+		var verification model.EmailVerification
+		err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashToken(token), now).
+			Limit(1).
+			Find(&verification).Error
+		if err != nil {
+			return err
+		}
+		if verification.ID == 0 {
+			return ErrInvalidVerificationToken
+		}
+
+		result := tx.Model(&verification).Where("used_at IS NULL").Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidVerificationToken
+		}
+
+		var user model.User
+		if err := tx.First(&user, verification.UserID).Error; err != nil {
+			return err
+		}
+		updates := map[string]interface{}{}
+		if strings.EqualFold(user.Email, verification.Email) {
+			updates["email_verified"] = true
+		}
+		if strings.EqualFold(user.NotificationEmail, verification.Email) {
+			updates["notification_email_verified"] = true
+		}
+		if len(updates) == 0 {
+			return ErrInvalidVerificationToken
+		}
+
+		userID = user.ID
+		return tx.Model(&user).Updates(updates).Error
+	})
+	return userID, err
+}
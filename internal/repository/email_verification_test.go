@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"readwillbe/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyEmail(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "verify@example.com", "password")
+	require.NoError(t, db.Model(user).Update("notification_email", "inbox@example.com").Error)
+	now := time.Date(2026, time.May, 1, 8, 0, 0, 0, time.UTC)
+
+	reload := func() model.User {
+		var u model.User
+		require.NoError(t, db.First(&u, user.ID).Error)
+		return u
+	}
+
+	t.Run("primary address", func(t *testing.T) {
+		token, err := CreateEmailVerification(db, user.ID, "Verify@Example.com", now)
+		require.NoError(t, err)
+
+		_, err = VerifyEmail(db, token, now.Add(EmailVerificationTTL))
+		assert.ErrorIs(t, err, ErrInvalidVerificationToken, "expired")
+
+		userID, err := VerifyEmail(db, token, now.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, userID)
+		assert.True(t, reload().EmailVerified)
+		assert.False(t, reload().NotificationEmailVerified)
+
+		_, err = VerifyEmail(db, token, now.Add(time.Hour))
+		assert.ErrorIs(t, err, ErrInvalidVerificationToken, "single use")
+	})
+
+	t.Run("expiry ignores the server's zone", func(t *testing.T) {
+		zoned := createTestUser(t, db, "zoned@example.com", "password")
+		issued := now.In(time.FixedZone("east", 10*60*60))
+		token, err := CreateEmailVerification(db, zoned.ID, "zoned@example.com", issued)
+		require.NoError(t, err)
+
+		later := issued.Add(EmailVerificationTTL + time.Hour).In(time.FixedZone("west", -10*60*60))
+		_, err = VerifyEmail(db, token, later)
+		assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+	})
+
+	t.Run("stale address", func(t *testing.T) {
+		token, err := CreateEmailVerification(db, user.ID, "inbox@example.com", now)
+		require.NoError(t, err)
+		require.NoError(t, db.Model(user).Update("notification_email", "elsewhere@example.com").Error)
+
+		_, err = VerifyEmail(db, token, now.Add(time.Hour))
+		assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+		assert.False(t, reload().NotificationEmailVerified)
+	})
+
+	t.Run("notification address", func(t *testing.T) {
+		token, err := CreateEmailVerification(db, user.ID, "elsewhere@example.com", now)
+		require.NoError(t, err)
+
+		_, err = VerifyEmail(db, token, now.Add(time.Hour))
+		require.NoError(t, err)
+		assert.True(t, reload().NotificationEmailVerified)
+	})
+
+	n, err := CountEmailVerifications(db, user.ID, now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"readwillbe/internal/model"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// HistoryPageSize is how many readings one page of history holds.
+const HistoryPageSize = 25
+
+// doneAt is when a history reading was finished, by completing or skipping.
+// Readings from before either was recorded fall back to their creation time
+// so every row has a position in the order.
+const doneAt = "COALESCE(readings.completed_at, readings.skipped_at, readings.created_at)"
+
+// HistoryFilter narrows the reading history. Zero-valued fields are ignored.
+type HistoryFilter struct {
+	PlanID   uint
+	DateType model.DateType
+	From     *time.Time // earliest completion time, inclusive
+	Until    *time.Time // latest completion time, exclusive
+}
+
+// HistoryCursor marks the last reading of a history page; the next page
+// starts after it.
+type HistoryCursor struct {
+	At time.Time
+	ID uint
+}
+
+// String encodes the cursor for use in a URL.
+func (c HistoryCursor) String() string {
+	raw := fmt.Sprintf("%d.%d", c.At.UnixNano(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseHistoryCursor decodes a cursor produced by [HistoryCursor.String].
+func ParseHistoryCursor(s string) (HistoryCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return HistoryCursor{}, errors.New("invalid cursor")
+	}
+	nanos, id, ok := strings.Cut(string(raw), ".")
+	if !ok {
+		return HistoryCursor{}, errors.New("invalid cursor")
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return HistoryCursor{}, errors.New("invalid cursor")
+	}
+	i, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return HistoryCursor{}, errors.New("invalid cursor")
+	}
+	return HistoryCursor{At: time.Unix(0, n).UTC(), ID: uint(i)}, nil
+}
+
+// HistoryPage is one page of completed and skipped readings, newest first.
+type HistoryPage struct {
+	Readings []model.Reading
+	Next     *HistoryCursor // nil on the last page
+}
+
+// historyQuery selects the user's completed and skipped readings matching
+// filter. Readings of deleted plans are left out.
+func historyQuery(tx *gorm.DB, userID uint, filter HistoryFilter) *gorm.DB {
+	q := tx.Model(&model.Reading{}).
+		Joins("JOIN plans ON plans.id = readings.plan_id AND plans.deleted_at IS NULL").
+		Where("plans.user_id = ? AND readings.status IN ?", userID, doneStatuses)
+	if filter.PlanID != 0 {
+		q = q.Where("readings.plan_id = ?", filter.PlanID)
+	}
+	if filter.DateType != "" {
+		q = q.Where("readings.date_type = ?", filter.DateType)
+	}
+	if filter.From != nil {
+		q = q.Where(doneAt+" >= ?", filter.From.UTC())
+	}
+	if filter.Until != nil {
+		q = q.Where(doneAt+" < ?", filter.Until.UTC())
+	}
+	return q
+}
+
+// GetHistory returns up to limit of the user's completed and skipped
+// readings matching filter, most recently finished first, starting after
+// the cursor if one is given. Readings come with their plan and journal
+// entry loaded.
+func GetHistory(tx *gorm.DB, userID uint, filter HistoryFilter, after *HistoryCursor, limit int) (HistoryPage, error) {
+	q := historyQuery(tx, userID, filter).Preload("Plan").Preload("Journal")
+	if after != nil {
+		at := after.At.UTC()
+		q = q.Where(doneAt+" < ? OR ("+doneAt+" = ? AND readings.id < ?)", at, at, after.ID)
+	}
+
+	var page HistoryPage
+	// Fetch one extra row to learn whether another page follows.
+	err := q.Order(doneAt + " DESC").Order("readings.id DESC").
+		Limit(limit + 1).
+		Find(&page.Readings).Error
+	if err != nil {
+		return page, err
+	}
+
+	if len(page.Readings) > limit {
+		page.Readings = page.Readings[:limit]
+		last := page.Readings[limit-1]
+		page.Next = &HistoryCursor{At: readingDoneAt(last), ID: last.ID}
+	}
+	return page, nil
+}
+
+// readingDoneAt mirrors the doneAt expression for a loaded reading.
+func readingDoneAt(r model.Reading) time.Time {
+	switch {
+	case r.CompletedAt != nil:
+		return *r.CompletedAt
+	case r.SkippedAt != nil:
+		return *r.SkippedAt
+	default:
+		return r.CreatedAt
+	}
+}
+
+// CountHistory counts the user's completed and skipped readings matching
+// filter.
+func CountHistory(tx *gorm.DB, userID uint, filter HistoryFilter) (completed, skipped int64, err error) {
+	var rows []struct {
+		Status model.ReadingStatus
+		Count  int64
+	}
+	err = historyQuery(tx, userID, filter).
+		Select("readings.status AS status, COUNT(*) AS count").
+		Group("readings.status").
+		Scan(&rows).Error
+	for _, row := range rows {
+		switch row.Status {
+		case model.StatusCompleted:
+			completed = row.Count
+		case model.StatusSkipped:
+			skipped = row.Count
+		}
+	}
+	return completed, skipped, err
+}
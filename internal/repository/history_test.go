@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"readwillbe/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetHistory(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "history@example.com", "password")
+	other := createTestUser(t, db, "other@example.com", "password")
+	daily := createTestPlan(t, db, user, "Daily")
+	weekly := createTestPlan(t, db, user, "Weekly")
+	foreign := createTestPlan(t, db, other, "Foreign")
+
+	base := time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC)
+	finish := func(plan *model.Plan, dateType model.DateType, status model.ReadingStatus, at time.Time) *model.Reading {
+		r := createTestReading(t, db, plan, "Reading", at)
+		r.DateType, r.Status = dateType, status
+		if status == model.StatusSkipped {
+			r.SkippedAt = &at
+		} else {
+			r.CompletedAt = &at
+		}
+		require.NoError(t, db.Save(r).Error)
+		return r
+	}
+
+	var want []uint // newest first
+	for i := 0; i < 5; i++ {
+		r := finish(daily, model.DateTypeDay, model.StatusCompleted, base.AddDate(0, 0, i))
+		want = append([]uint{r.ID}, want...)
+	}
+	// Two readings finished at the same moment are ordered by ID.
+	tie := base.AddDate(0, 0, 10)
+	tieA := finish(weekly, model.DateTypeWeek, model.StatusSkipped, tie)
+	tieB := finish(weekly, model.DateTypeWeek, model.StatusCompleted, tie)
+	want = append([]uint{tieB.ID, tieA.ID}, want...)
+	createTestReading(t, db, daily, "Pending", base)
+	finish(foreign, model.DateTypeDay, model.StatusCompleted, base)
+
+	var got []uint
+	var after *HistoryCursor
+	pages := 0
+	for {
+		page, err := GetHistory(db, user.ID, HistoryFilter{}, after, 3)
+		require.NoError(t, err)
+		pages++
+		for _, r := range page.Readings {
+			got = append(got, r.ID)
+			assert.Equal(t, user.ID, r.Plan.UserID)
+		}
+		if page.Next == nil {
+			break
+		}
+		cursor, err := ParseHistoryCursor(page.Next.String())
+		require.NoError(t, err)
+		after = &cursor
+	}
+	assert.Equal(t, want, got)
+	assert.Equal(t, 3, pages)
+
+	page, err := GetHistory(db, user.ID, HistoryFilter{PlanID: weekly.ID}, nil, 10)
+	require.NoError(t, err)
+	assert.Len(t, page.Readings, 2)
+	assert.Nil(t, page.Next)
+
+	page, err = GetHistory(db, user.ID, HistoryFilter{DateType: model.DateTypeDay}, nil, 10)
+	require.NoError(t, err)
+	assert.Len(t, page.Readings, 5)
+
+	from, until := base.AddDate(0, 0, 1), base.AddDate(0, 0, 3)
+	page, err = GetHistory(db, user.ID, HistoryFilter{From: &from, Until: &until}, nil, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{want[4], want[5]}, readingIDs(page.Readings), "From is inclusive and Until exclusive")
+
+	completed, skipped, err := CountHistory(db, user.ID, HistoryFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), completed)
+	assert.Equal(t, int64(1), skipped)
+}
+
+func TestParseHistoryCursor(t *testing.T) {
+	cursor := HistoryCursor{At: time.Date(2026, time.March, 1, 12, 0, 0, 123456789, time.UTC), ID: 42}
+	got, err := ParseHistoryCursor(cursor.String())
+	require.NoError(t, err)
+	assert.True(t, cursor.At.Equal(got.At))
+	assert.Equal(t, cursor.ID, got.ID)
+
+	for _, bad := range []string{"!!", "bm9wZQ", "MS54"} {
+		_, err := ParseHistoryCursor(bad)
+		assert.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"readwillbe/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// GetJournalEntry returns the user's journal entry for a reading, or a zero
+// entry if none has been written.
+func GetJournalEntry(tx *gorm.DB, userID, readingID uint) (model.JournalEntry, error) {
+	var entry model.JournalEntry
+	err := tx.Where("user_id = ? AND reading_id = ?", userID, readingID).Limit(1).Find(&entry).Error
+	return entry, err
+}
+
+// SaveJournalEntry writes body as the user's journal entry for a reading,
+// stamping EditedAt with now when an existing entry changes. An empty body
+// deletes the entry.
+func SaveJournalEntry(tx *gorm.DB, userID, readingID uint, body string, now time.Time) (model.JournalEntry, error) {
+	entry, err := GetJournalEntry(tx, userID, readingID)
+	if err != nil {
+		return entry, err
+	}
+
+	body = strings.TrimSpace(body)
+	switch {
+	case body == "":
+		if entry.ID != 0 {
+			// Hard delete so the reading can be journalled again under the
+			// unique reading_id index.
+			err = tx.Unscoped().Delete(&entry).Error
+		}
+		return model.JournalEntry{}, err
+	case entry.ID == 0:
+		entry = model.JournalEntry{UserID: userID, ReadingID: readingID, Body: body}
+		err = tx.Create(&entry).Error
+	case entry.Body != body:
+		edited := now.UTC()
+		entry.Body, entry.EditedAt = body, &edited
+		err = tx.Save(&entry).Error
+	}
+	return entry, err
+}
+
+// SearchJournal returns the user's journal entries whose body contains
+// query, ignoring case, newest first, with their reading and plan loaded.
+// An empty query returns every entry. Entries on deleted readings or plans
+// are left out.
+func SearchJournal(tx *gorm.DB, userID uint, query string, limit int) ([]model.JournalEntry, error) {
+	q := tx.Preload("Reading.Plan").
+		Joins("JOIN readings ON readings.id = journal_entries.reading_id AND readings.deleted_at IS NULL").
+		Joins("JOIN plans ON plans.id = readings.plan_id AND plans.deleted_at IS NULL").
+		Where("journal_entries.user_id = ?", userID)
+	if query = strings.TrimSpace(query); query != "" {
+		q = q.Where("LOWER(journal_entries.body) LIKE ? ESCAPE '\\'", "%"+escapeLike(strings.ToLower(query))+"%")
+	}
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+
+	var entries []model.JournalEntry
+	err := q.Order("journal_entries.created_at DESC").Find(&entries).Error
+	return entries, err
+}
+
+// escapeLike escapes the LIKE wildcards in s so it matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"readwillbe/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveJournalEntry(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "journal@example.com", "password")
+	plan := createTestPlan(t, db, user, "Journal Plan")
+	reading := createTestReading(t, db, plan, "Psalm 23", time.Now())
+	now := time.Date(2026, time.May, 1, 8, 0, 0, 0, time.UTC)
+
+	entry, err := SaveJournalEntry(db, user.ID, reading.ID, "  The Lord is my shepherd.\n", now)
+	require.NoError(t, err)
+	require.NotZero(t, entry.ID)
+	assert.Equal(t, "The Lord is my shepherd.", entry.Body)
+	assert.Nil(t, entry.EditedAt, "a new entry has not been edited")
+
+	same, err := SaveJournalEntry(db, user.ID, reading.ID, "The Lord is my shepherd.", now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Nil(t, same.EditedAt, "saving unchanged text is not an edit")
+
+	edited, err := SaveJournalEntry(db, user.ID, reading.ID, "I shall not want.", now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, entry.ID, edited.ID)
+	require.NotNil(t, edited.EditedAt)
+	assert.True(t, now.Add(2*time.Hour).Equal(*edited.EditedAt))
+
+	var withJournal model.Reading
+	require.NoError(t, db.Preload("Journal").First(&withJournal, reading.ID).Error)
+	require.NotNil(t, withJournal.Journal)
+	assert.Equal(t, "I shall not want.", withJournal.Journal.Body)
+
+	deleted, err := SaveJournalEntry(db, user.ID, reading.ID, "   ", now)
+	require.NoError(t, err)
+	assert.Zero(t, deleted.ID)
+	got, err := GetJournalEntry(db, user.ID, reading.ID)
+	require.NoError(t, err)
+	assert.Zero(t, got.ID, "an empty body deletes the entry")
+
+	again, err := SaveJournalEntry(db, user.ID, reading.ID, "Written again.", now)
+	require.NoError(t, err)
+	assert.NotZero(t, again.ID, "a deleted entry can be written again")
+}
+
+func TestSearchJournal(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "search@example.com", "password")
+	other := createTestUser(t, db, "other@example.com", "password")
+	plan := createTestPlan(t, db, user, "Gospels")
+	otherPlan := createTestPlan(t, db, other, "Other")
+	now := time.Date(2026, time.May, 1, 8, 0, 0, 0, time.UTC)
+
+	first := createTestReading(t, db, plan, "John 1", now)
+	second := createTestReading(t, db, plan, "John 2", now)
+	third := createTestReading(t, db, plan, "John 3", now)
+	foreign := createTestReading(t, db, otherPlan, "John 1", now)
+
+	_, err := SaveJournalEntry(db, user.ID, first.ID, "In the beginning was the Word", now)
+	require.NoError(t, err)
+	_, err = SaveJournalEntry(db, user.ID, second.ID, "Water into wine, 100% joy", now)
+	require.NoError(t, err)
+	_, err = SaveJournalEntry(db, user.ID, third.ID, "Born again_of water", now)
+	require.NoError(t, err)
+	_, err = SaveJournalEntry(db, other.ID, foreign.ID, "The word of someone else", now)
+	require.NoError(t, err)
+
+	all, err := SearchJournal(db, user.ID, "", 0)
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	word, err := SearchJournal(db, user.ID, "WORD", 0)
+	require.NoError(t, err)
+	require.Len(t, word, 1, "search ignores case and other users' entries")
+	assert.Equal(t, first.ID, word[0].ReadingID)
+	assert.Equal(t, "Gospels", word[0].Reading.Plan.Title)
+
+	percent, err := SearchJournal(db, user.ID, "100%", 0)
+	require.NoError(t, err)
+	require.Len(t, percent, 1)
+	assert.Equal(t, second.ID, percent[0].ReadingID)
+
+	underscore, err := SearchJournal(db, user.ID, "wat_r", 0)
+	require.NoError(t, err)
+	assert.Empty(t, underscore, "LIKE wildcards match literally")
+
+	limited, err := SearchJournal(db, user.ID, "water", 1)
+	require.NoError(t, err)
+	assert.Len(t, limited, 1)
+
+	require.NoError(t, db.Delete(third).Error)
+	remaining, err := SearchJournal(db, user.ID, "water", 0)
+	require.NoError(t, err)
+	assert.Len(t, remaining, 1, "entries on deleted readings are hidden")
+}
+
+func TestGetCompletedTodayReadings(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "today@example.com", "password")
+	plan := createTestPlan(t, db, user, "Today Plan")
+
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	now := time.Date(2026, time.May, 1, 21, 0, 0, 0, loc)
+
+	complete := func(content string, at time.Time) *model.Reading {
+		r := createTestReading(t, db, plan, content, at)
+		r.Status, r.CompletedAt = model.StatusCompleted, &at
+		require.NoError(t, db.Save(r).Error)
+		return r
+	}
+	morning := complete("Morning", time.Date(2026, time.May, 1, 7, 0, 0, 0, loc))
+	complete("Yesterday", time.Date(2026, time.May, 1, 3, 0, 0, 0, time.UTC))
+	createTestReading(t, db, plan, "Pending", now)
+	_, err = SaveJournalEntry(db, user.ID, morning.ID, "Good start", now)
+	require.NoError(t, err)
+
+	readings, err := GetCompletedTodayReadings(db, user.ID, now)
+	require.NoError(t, err)
+	require.Len(t, readings, 1)
+	assert.Equal(t, morning.ID, readings[0].ID)
+	require.NotNil(t, readings[0].Journal)
+	assert.Equal(t, "Good start", readings[0].Journal.Body)
+}
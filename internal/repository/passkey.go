@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"time"
+
+	"readwillbe/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ListPasskeys returns the user's passkeys, oldest first.
+func ListPasskeys(tx *gorm.DB, userID uint) ([]model.Passkey, error) {
+	var passkeys []model.Passkey
+	err := tx.Where("user_id = ?", userID).Order("created_at, id").Find(&passkeys).Error
+	return passkeys, err
+}
+
+// CountPasskeys counts the user's passkeys.
+func CountPasskeys(tx *gorm.DB, userID uint) (int64, error) {
+	var n int64
+	err := tx.Model(&model.Passkey{}).Where("user_id = ?", userID).Count(&n).Error
+	return n, err
+}
+
+// CreatePasskey stores a newly registered passkey.
+func CreatePasskey(tx *gorm.DB, passkey *model.Passkey) error {
+	return tx.Create(passkey).Error
+}
+
+// RecordPasskeyUse saves the signature counter and flags reported when the
+// passkey with credentialID was used to sign in.
+func RecordPasskeyUse(tx *gorm.DB, credentialID []byte, signCount uint32, flags uint8, now time.Time) error {
+	return tx.Model(&model.Passkey{}).Where("credential_id = ?", credentialID).Updates(map[string]interface{}{
+		"sign_count":   signCount,
+		"flags":        flags,
+		"last_used_at": now,
+	}).Error
+}
+
+// DeletePasskey removes one of the user's passkeys, returning false if they
+// have none with that ID.
+func DeletePasskey(tx *gorm.DB, userID, id uint) (bool, error) {
+	result := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&model.Passkey{})
+	return result.RowsAffected > 0, result.Error
+}
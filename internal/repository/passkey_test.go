@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"readwillbe/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasskeys(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "passkeys@example.com", "password")
+	other := createTestUser(t, db, "other@example.com", "password")
+	now := time.Date(2026, time.May, 1, 8, 0, 0, 0, time.UTC)
+
+	laptop := model.Passkey{UserID: user.ID, Name: "Laptop", CredentialID: []byte{1}, CreatedAt: now}
+	phone := model.Passkey{UserID: user.ID, Name: "Phone", CredentialID: []byte{2}, CreatedAt: now.Add(time.Hour)}
+	for _, p := range []*model.Passkey{&phone, &laptop} {
+		require.NoError(t, CreatePasskey(db, p))
+	}
+	assert.Error(t, CreatePasskey(db, &model.Passkey{UserID: other.ID, CredentialID: []byte{1}}), "credential IDs are unique")
+
+	passkeys, err := ListPasskeys(db, user.ID)
+	require.NoError(t, err)
+	require.Len(t, passkeys, 2)
+	assert.Equal(t, "Laptop", passkeys[0].Name)
+	assert.Equal(t, "Phone", passkeys[1].Name)
+
+	n, err := CountPasskeys(db, other.ID)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	require.NoError(t, RecordPasskeyUse(db, []byte{2}, 5, 0x05, now))
+	var used model.Passkey
+	require.NoError(t, db.First(&used, phone.ID).Error)
+	assert.Equal(t, uint32(5), used.SignCount)
+	assert.Equal(t, uint8(0x05), used.Flags)
+	require.NotNil(t, used.LastUsedAt)
+
+	deleted, err := DeletePasskey(db, other.ID, laptop.ID)
+	require.NoError(t, err)
+	assert.False(t, deleted, "only the owner can remove a passkey")
+
+	deleted, err = DeletePasskey(db, user.ID, laptop.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	n, err = CountPasskeys(db, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"time"
+
+	"readwillbe/internal/model"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// PasswordResetTTL is how long an emailed reset link stays valid.
+const PasswordResetTTL = time.Hour
+
+// ErrInvalidResetToken is returned for a reset token that is unknown,
+// expired or already used.
+var ErrInvalidResetToken = errors.New("this reset link is invalid or has expired")
+
+// CreatePasswordReset issues a reset token for the user, valid for
+// PasswordResetTTL from now. The returned token is the only copy; just its
+// hash is stored.
+func CreatePasswordReset(tx *gorm.DB, userID uint, now time.Time) (string, error) {
+	now = now.UTC()
+	token, hash, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	reset := model.PasswordReset{
+		UserID:    userID,
+		TokenHash: hash,
+		ExpiresAt: now.Add(PasswordResetTTL),
+		CreatedAt: now,
+	}
+	if err := tx.Create(&reset).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// CountPasswordResets counts the reset tokens issued to the user since the
+// given time.
+func CountPasswordResets(tx *gorm.DB, userID uint, since time.Time) (int64, error) {
+	var n int64
+	err := tx.Model(&model.PasswordReset{}).
+		Where("user_id = ? AND created_at >= ?", userID, since.UTC()).
+		Count(&n).Error
+	return n, err
+}
+
+// GetPasswordReset looks up an unused, unexpired reset token, returning
+// ErrInvalidResetToken if there is none.
+func GetPasswordReset(tx *gorm.DB, token string, now time.Time) (model.PasswordReset, error) {
+	now = now.UTC()
+	var reset model.PasswordReset
+	err := tx.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashToken(token), now).
+		Limit(1).
+		Find(&reset).Error
+	if err != nil {
+		return reset, err
+	}
+	if reset.ID == 0 {
+		return reset, ErrInvalidResetToken
+	}
+	return reset, nil
+}
+
+// ResetPassword sets the password hash of the user a reset token was issued
+// to, marks their email verified, and uses up that token along with any
+// others outstanding for the user. It returns the user's ID.
+func ResetPassword(tx *gorm.DB, token, passwordHash string, now time.Time) (uint, error) {
+	now = now.UTC()
+	var userID uint
+	err := tx.Transaction(func(tx *gorm.DB) error {
+		reset, err := GetPasswordReset(tx, token, now)
+		if err != nil {
+			return err
+		}
+
+		// Guard on used_at so two concurrent requests cannot both use it.
+		result := tx.Model(&reset).Where("used_at IS NULL").Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidResetToken
+		}
+		err = tx.Model(&model.PasswordReset{}).
+			Where("user_id = ? AND used_at IS NULL", reset.UserID).
+			Update("used_at", now).Error
+		if err != nil {
+			return err
+		}
+
+		userID = reset.UserID
+		if err := UpdatePassword(tx, userID, passwordHash); err != nil {
+			return err
+		}
+		// Whoever knew the old password is signed out everywhere.
+		if _, err := RevokeOtherSessions(tx, userID, ""); err != nil {
+			return err
+		}
+		// The link went to the account email, so following it proves the
+		// user owns that address.
+		return tx.Model(&model.User{}).Where("id = ?", userID).Update("email_verified", true).Error
+	})
+	return userID, err
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"readwillbe/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordReset(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "reset@example.com", "password")
+	now := time.Date(2026, time.May, 1, 8, 0, 0, 0, time.UTC)
+
+	token, err := CreatePasswordReset(db, user.ID, now)
+	require.NoError(t, err)
+	other, err := CreatePasswordReset(db, user.ID, now)
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other)
+
+	var stored model.PasswordReset
+	require.NoError(t, db.First(&stored, "user_id = ?", user.ID).Error)
+	assert.NotEqual(t, token, stored.TokenHash, "only a hash of the token is stored")
+
+	n, err := CountPasswordResets(db, user.ID, now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	t.Run("expired", func(t *testing.T) {
+		_, err := GetPasswordReset(db, token, now.Add(PasswordResetTTL))
+		assert.ErrorIs(t, err, ErrInvalidResetToken)
+		_, err = ResetPassword(db, token, "expired-hash", now.Add(PasswordResetTTL+time.Minute))
+		assert.ErrorIs(t, err, ErrInvalidResetToken)
+	})
+
+	t.Run("expiry ignores the server's zone", func(t *testing.T) {
+		issued := now.In(time.FixedZone("east", 10*60*60))
+		zoned, err := CreatePasswordReset(db, user.ID, issued)
+		require.NoError(t, err)
+
+		later := issued.Add(PasswordResetTTL + time.Hour).In(time.FixedZone("west", -10*60*60))
+		_, err = GetPasswordReset(db, zoned, later)
+		assert.ErrorIs(t, err, ErrInvalidResetToken)
+
+		n, err := CountPasswordResets(db, user.ID, later)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), n, "nothing was issued after later")
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		_, err := GetPasswordReset(db, "not-a-token", now)
+		assert.ErrorIs(t, err, ErrInvalidResetToken)
+	})
+
+	t.Run("single use", func(t *testing.T) {
+		reset, err := GetPasswordReset(db, token, now.Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, reset.UserID)
+		_, err = CreateSession(db, &model.Session{UserID: &user.ID, ExpiresAt: now.Add(time.Hour)})
+		require.NoError(t, err)
+
+		userID, err := ResetPassword(db, token, "new-hash", now.Add(time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, userID)
+
+		var updated model.User
+		require.NoError(t, db.First(&updated, user.ID).Error)
+		assert.Equal(t, "new-hash", updated.Password)
+		assert.True(t, updated.EmailVerified, "the reset link proves the address")
+		sessions, err := ListSessions(db, user.ID, now)
+		require.NoError(t, err)
+		assert.Empty(t, sessions, "a reset signs the user out everywhere")
+
+		_, err = ResetPassword(db, token, "again-hash", now.Add(2*time.Minute))
+		assert.ErrorIs(t, err, ErrInvalidResetToken)
+		_, err = ResetPassword(db, other, "other-hash", now.Add(2*time.Minute))
+		assert.ErrorIs(t, err, ErrInvalidResetToken, "a reset uses up the user's other tokens")
+	})
+}
@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"time"
+
+	"readwillbe/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// GetPlanTitles returns the ID and title of each of the user's plans, sorted
+// by title, for filter menus.
+func GetPlanTitles(tx *gorm.DB, userID uint) ([]model.Plan, error) {
+	var plans []model.Plan
+	err := tx.Select("id", "title").Where("user_id = ?", userID).Order("title").Find(&plans).Error
+	return plans, err
+}
+
+// PausePlan marks plan as paused from the calendar day of today, which should
+// be in the user's timezone.
+func PausePlan(tx *gorm.DB, plan *model.Plan, today time.Time) error {
+	pausedAt := model.CivilDate(today)
+	if err := tx.Model(plan).Update("paused_at", pausedAt).Error; err != nil {
+		return err
+	}
+	plan.PausedAt = &pausedAt
+	return nil
+}
+
+// ResumePlan unpauses plan and moves its pending readings forward by the
+// length of the pause, so the schedule picks up where it was left. Readings
+// already overdue on the day the plan was paused keep their dates. It returns
+// the number of readings moved.
+func ResumePlan(tx *gorm.DB, plan *model.Plan, today time.Time) (int, error) {
+	if plan.PausedAt == nil {
+		return 0, nil
+	}
+	pausedAt := *plan.PausedAt
+	days := int(model.CivilDate(today).Sub(model.CivilDate(pausedAt)).Hours() / 24)
+
+	var moved int
+	err := tx.Transaction(func(tx *gorm.DB) error {
+		var err error
+		moved, err = shiftReadings(tx, plan.ID, days, func(r model.Reading) bool {
+			return !r.IsOverdue(pausedAt)
+		})
+		if err != nil {
+			return err
+		}
+		return tx.Model(plan).Update("paused_at", nil).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	plan.PausedAt = nil
+	return moved, nil
+}
+
+// ShiftPendingReadings moves every pending reading of the plan by days, which
+// may be negative, keeping each reading's cadence as [model.Reading.ShiftedDate]
+// does. It returns the number of readings moved.
+func ShiftPendingReadings(tx *gorm.DB, planID uint, days int) (int, error) {
+	var moved int
+	err := tx.Transaction(func(tx *gorm.DB) error {
+		var err error
+		moved, err = shiftReadings(tx, planID, days, func(model.Reading) bool { return true })
+		return err
+	})
+	return moved, err
+}
+
+// shiftReadings moves the pending readings of a plan that match include. A
+// moved reading no longer sits at its offset from the plan start, so its
+// StartOffset is cleared.
+func shiftReadings(tx *gorm.DB, planID uint, days int, include func(model.Reading) bool) (int, error) {
+	var readings []model.Reading
+	err := tx.Where("plan_id = ? AND status = ?", planID, model.StatusPending).Find(&readings).Error
+	if err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for _, r := range readings {
+		if !include(r) {
+			continue
+		}
+		date := r.ShiftedDate(days)
+		if date.Equal(r.Date) {
+			continue
+		}
+		err := tx.Model(&r).Updates(map[string]interface{}{"date": date, "start_offset": nil}).Error
+		if err != nil {
+			return 0, err
+		}
+		moved++
+	}
+	return moved, nil
+}
+
+// CatchUpMode selects how [CatchUp] fits the overdue backlog into the
+// schedule.
+type CatchUpMode string
+
+// Catch-up modes.
+const (
+	// CatchUpSpread adds the backlog on top of the readings already due in
+	// the catch-up window.
+	CatchUpSpread CatchUpMode = "spread"
+	// CatchUpPushBack moves the rest of the schedule back by the length of
+	// the catch-up window, so the window holds only the backlog.
+	CatchUpPushBack CatchUpMode = "push"
+)
+
+// MaxCatchUpDays bounds the catch-up window.
+const MaxCatchUpDays = 60
+
+// CatchUpMove is an overdue reading and the day catch-up assigns it.
+type CatchUpMove struct {
+	Reading model.Reading
+	Date    time.Time
+}
+
+// CatchUpShift is how far CatchUpPushBack moves the upcoming readings of one
+// cadence. Steps counts whole units of DateType, as
+// [model.DateType.Steps] rounds them, and may be zero.
+type CatchUpShift struct {
+	DateType model.DateType
+	Readings int
+	Steps    int
+}
+
+// CatchUpPreview describes what [CatchUp] would do to a plan.
+type CatchUpPreview struct {
+	Days  int
+	Mode  CatchUpMode
+	Moves []CatchUpMove
+	// Remaining is the number of pending readings not yet overdue.
+	Remaining int
+	// Shifts breaks Remaining down by cadence, in day, week, month order,
+	// with how far each moves. It is only set in CatchUpPushBack mode.
+	Shifts []CatchUpShift
+}
+
+// PreviewCatchUp spreads the plan's overdue readings as of today, in
+// schedule order, evenly across the days days starting today, without
+// saving anything. today should be in the user's timezone.
+func PreviewCatchUp(tx *gorm.DB, planID uint, today time.Time, days int, mode CatchUpMode) (CatchUpPreview, error) {
+	var readings []model.Reading
+	err := tx.Where("plan_id = ? AND status = ?", planID, model.StatusPending).
+		Order("date ASC, sort_order ASC, id ASC").
+		Find(&readings).Error
+	if err != nil {
+		return CatchUpPreview{}, err
+	}
+
+	today = model.CivilDate(today)
+	preview := CatchUpPreview{Days: days, Mode: mode}
+	var overdue []model.Reading
+	upcoming := map[model.DateType]int{}
+	for _, r := range readings {
+		if r.IsOverdue(today) {
+			overdue = append(overdue, r)
+		} else {
+			preview.Remaining++
+			switch r.DateType {
+			case model.DateTypeWeek, model.DateTypeMonth:
+				upcoming[r.DateType]++
+			default:
+				upcoming[model.DateTypeDay]++
+			}
+		}
+	}
+
+	if mode == CatchUpPushBack {
+		for _, dt := range []model.DateType{model.DateTypeDay, model.DateTypeWeek, model.DateTypeMonth} {
+			if upcoming[dt] > 0 {
+				preview.Shifts = append(preview.Shifts, CatchUpShift{DateType: dt, Readings: upcoming[dt], Steps: dt.Steps(days)})
+			}
+		}
+	}
+
+	for i, r := range overdue {
+		day := i * days / len(overdue)
+		preview.Moves = append(preview.Moves, CatchUpMove{Reading: r, Date: today.AddDate(0, 0, day)})
+	}
+	return preview, nil
+}
+
+// CatchUp applies [PreviewCatchUp] for the same arguments. Caught-up readings
+// become day readings on their new date; in CatchUpPushBack mode the rest of
+// the schedule then moves back by days, keeping each reading's cadence. It
+// returns what was done.
+func CatchUp(tx *gorm.DB, planID uint, today time.Time, days int, mode CatchUpMode) (CatchUpPreview, error) {
+	var preview CatchUpPreview
+	err := tx.Transaction(func(tx *gorm.DB) error {
+		var err error
+		preview, err = PreviewCatchUp(tx, planID, today, days, mode)
+		if err != nil {
+			return err
+		}
+
+		if mode == CatchUpPushBack {
+			today := model.CivilDate(today)
+			_, err = shiftReadings(tx, planID, days, func(r model.Reading) bool {
+				return !r.IsOverdue(today)
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, m := range preview.Moves {
+			err := tx.Model(&m.Reading).Updates(map[string]interface{}{
+				"date":         m.Date,
+				"date_type":    model.DateTypeDay,
+				"start_offset": nil,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return preview, err
+}
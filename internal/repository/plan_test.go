@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"readwillbe/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPauseAndResumePlan(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "pause@example.com", "password")
+	plan := createTestPlan(t, db, user, "Vacation Plan")
+
+	pausedOn := time.Date(2026, time.March, 4, 0, 0, 0, 0, time.UTC) // Wednesday
+	overdue := createTestReading(t, db, plan, "Overdue", pausedOn.AddDate(0, 0, -2))
+	today := createTestReading(t, db, plan, "Pause day", pausedOn)
+	later := createTestReading(t, db, plan, "Later", pausedOn.AddDate(0, 0, 5))
+
+	week := &model.Reading{PlanID: plan.ID, Content: "Week", Date: time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC), DateType: model.DateTypeWeek, Status: model.StatusPending}
+	month := &model.Reading{PlanID: plan.ID, Content: "Month", Date: time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC), DateType: model.DateTypeMonth, Status: model.StatusPending}
+	offset := 4
+	done := &model.Reading{PlanID: plan.ID, Content: "Done", Date: pausedOn.AddDate(0, 0, 1), DateType: model.DateTypeDay, Status: model.StatusCompleted, StartOffset: &offset}
+	require.NoError(t, db.Create(week).Error)
+	require.NoError(t, db.Create(month).Error)
+	require.NoError(t, db.Create(done).Error)
+
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// Late evening in New York is already the next day in UTC; the pause
+	// should still land on the user's calendar day.
+	require.NoError(t, PausePlan(db, plan, time.Date(2026, time.March, 4, 22, 0, 0, 0, loc)))
+	assert.True(t, plan.IsPaused())
+	assert.True(t, plan.PausedAt.Equal(pausedOn))
+
+	readings, err := GetDashboardReadings(db, user.ID, time.Date(2026, time.March, 10, 9, 0, 0, 0, loc))
+	require.NoError(t, err)
+	assert.Empty(t, readings, "paused plans are left off the dashboard")
+
+	moved, err := ResumePlan(db, plan, time.Date(2026, time.March, 14, 9, 0, 0, 0, loc))
+	require.NoError(t, err)
+	assert.Equal(t, 3, moved)
+	assert.False(t, plan.IsPaused())
+
+	dateOf := func(r *model.Reading) time.Time {
+		var got model.Reading
+		require.NoError(t, db.First(&got, r.ID).Error)
+		return got.Date.UTC()
+	}
+	assert.Equal(t, pausedOn.AddDate(0, 0, -2), dateOf(overdue), "overdue readings stay put")
+	assert.Equal(t, pausedOn.AddDate(0, 0, 10), dateOf(today))
+	assert.Equal(t, pausedOn.AddDate(0, 0, 15), dateOf(later))
+	assert.Equal(t, time.Date(2026, time.March, 9, 0, 0, 0, 0, time.UTC), dateOf(week), "ten days rounds to one week")
+	assert.Equal(t, time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC), dateOf(month), "ten days rounds to no months")
+	assert.Equal(t, pausedOn.AddDate(0, 0, 1), dateOf(done), "completed readings are not moved")
+
+	var stored model.Plan
+	require.NoError(t, db.First(&stored, plan.ID).Error)
+	assert.Nil(t, stored.PausedAt)
+}
+
+func TestShiftPendingReadings(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "shift@example.com", "password")
+	plan := createTestPlan(t, db, user, "Shift Plan")
+
+	day := time.Date(2026, time.March, 4, 0, 0, 0, 0, time.UTC)
+	offset := 2
+	relative := &model.Reading{PlanID: plan.ID, Content: "Day 3", Date: day, DateType: model.DateTypeDay, Status: model.StatusPending, StartOffset: &offset}
+	require.NoError(t, db.Create(relative).Error)
+	week := &model.Reading{PlanID: plan.ID, Content: "Week", Date: time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC), DateType: model.DateTypeWeek, Status: model.StatusPending}
+	require.NoError(t, db.Create(week).Error)
+
+	moved, err := ShiftPendingReadings(db, plan.ID, -14)
+	require.NoError(t, err)
+	assert.Equal(t, 2, moved)
+
+	var got model.Reading
+	require.NoError(t, db.First(&got, relative.ID).Error)
+	assert.Equal(t, day.AddDate(0, 0, -14), got.Date.UTC())
+	assert.Nil(t, got.StartOffset, "a moved reading is pinned to its new date")
+
+	var gotWeek model.Reading
+	require.NoError(t, db.First(&gotWeek, week.ID).Error)
+	assert.Equal(t, time.Date(2026, time.February, 16, 0, 0, 0, 0, time.UTC), gotWeek.Date.UTC())
+	assert.Equal(t, model.DateTypeWeek, gotWeek.DateType)
+}
+
+func TestCatchUp(t *testing.T) {
+	today := time.Date(2026, time.March, 16, 9, 0, 0, 0, time.UTC)
+	day := func(n int) time.Time { return time.Date(2026, time.March, 16, 0, 0, 0, 0, time.UTC).AddDate(0, 0, n) }
+
+	t.Run("preview spreads the backlog without saving", func(t *testing.T) {
+		db := setupTestDB(t)
+		user := createTestUser(t, db, "preview@example.com", "password")
+		plan := createTestPlan(t, db, user, "Behind")
+		for i := -5; i <= 1; i++ {
+			createTestReading(t, db, plan, "Reading", day(i))
+		}
+
+		preview, err := PreviewCatchUp(db, plan.ID, today, 2, CatchUpSpread)
+		require.NoError(t, err)
+		require.Len(t, preview.Moves, 5)
+		assert.Equal(t, 2, preview.Remaining)
+		var got []time.Time
+		for _, m := range preview.Moves {
+			got = append(got, m.Date)
+		}
+		assert.Equal(t, []time.Time{day(0), day(0), day(0), day(1), day(1)}, got)
+
+		var stillOverdue int64
+		db.Model(&model.Reading{}).Where("date < ?", day(0)).Count(&stillOverdue)
+		assert.Equal(t, int64(5), stillOverdue)
+	})
+
+	t.Run("push back moves the remaining schedule", func(t *testing.T) {
+		db := setupTestDB(t)
+		user := createTestUser(t, db, "push@example.com", "password")
+		plan := createTestPlan(t, db, user, "Behind")
+		overdue := createTestReading(t, db, plan, "Overdue", day(-3))
+		upcoming := createTestReading(t, db, plan, "Upcoming", day(0))
+		week := &model.Reading{PlanID: plan.ID, Content: "Week", Date: day(7), DateType: model.DateTypeWeek, Status: model.StatusPending}
+		require.NoError(t, db.Create(week).Error)
+
+		result, err := CatchUp(db, plan.ID, today, 7, CatchUpPushBack)
+		require.NoError(t, err)
+		assert.Len(t, result.Moves, 1)
+
+		dateOf := func(r *model.Reading) time.Time {
+			var got model.Reading
+			require.NoError(t, db.First(&got, r.ID).Error)
+			return got.Date.UTC()
+		}
+		assert.Equal(t, day(0), dateOf(overdue))
+		assert.Equal(t, day(7), dateOf(upcoming))
+		assert.Equal(t, day(14), dateOf(week))
+	})
+
+	t.Run("push back preview reports each cadence's shift", func(t *testing.T) {
+		db := setupTestDB(t)
+		user := createTestUser(t, db, "shifts@example.com", "password")
+		plan := createTestPlan(t, db, user, "Behind")
+		createTestReading(t, db, plan, "Overdue", day(-3))
+		createTestReading(t, db, plan, "Upcoming", day(0))
+		for _, r := range []*model.Reading{
+			{PlanID: plan.ID, Content: "Week", Date: day(7), DateType: model.DateTypeWeek, Status: model.StatusPending},
+			{PlanID: plan.ID, Content: "Month", Date: time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC), DateType: model.DateTypeMonth, Status: model.StatusPending},
+		} {
+			require.NoError(t, db.Create(r).Error)
+		}
+
+		preview, err := PreviewCatchUp(db, plan.ID, today, 10, CatchUpPushBack)
+		require.NoError(t, err)
+		assert.Equal(t, []CatchUpShift{
+			{DateType: model.DateTypeDay, Readings: 1, Steps: 10},
+			{DateType: model.DateTypeWeek, Readings: 1, Steps: 1},
+			{DateType: model.DateTypeMonth, Readings: 1, Steps: 0},
+		}, preview.Shifts)
+
+		spread, err := PreviewCatchUp(db, plan.ID, today, 10, CatchUpSpread)
+		require.NoError(t, err)
+		assert.Empty(t, spread.Shifts)
+	})
+}
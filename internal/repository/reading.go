@@ -10,6 +10,10 @@ import (
 	"gorm.io/gorm"
 )
 
+// doneStatuses are the statuses of readings that need no further action and
+// so are left off the dashboard and out of reminders.
+var doneStatuses = []model.ReadingStatus{model.StatusCompleted, model.StatusSkipped}
+
 func getStartOfDay(t time.Time) time.Time {
 	y, m, d := t.Date()
 	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
@@ -49,30 +53,33 @@ func getEndOfMonth(t time.Time) time.Time {
 }
 
 // GetDashboardReadings fetches relevant readings for the dashboard.
-// It filters out future readings that are not yet active.
-func GetDashboardReadings(tx *gorm.DB, userID uint) ([]model.Reading, error) {
-	now := time.Now() // Uses time.Local if set
+// It filters out future readings that are not yet active and readings of
+// paused plans. now should be in the user's timezone.
+func GetDashboardReadings(tx *gorm.DB, userID uint, now time.Time) ([]model.Reading, error) {
+	now = model.CivilDate(now)
 	endDay := getEndOfDay(now)
 	endWeek := getEndOfWeek(now)
 	endMonth := getEndOfMonth(now)
 
 	var readings []model.Reading
 	err := tx.Preload("Plan").
-		Where("plan_id IN (?)", tx.Model(&model.Plan{}).Select("id").Where("user_id = ?", userID)).
-		Where("status != ?", model.StatusCompleted).
+		Where("plan_id IN (?)", tx.Model(&model.Plan{}).Select("id").Where("user_id = ? AND paused_at IS NULL", userID)).
+		Where("status NOT IN ?", doneStatuses).
 		Where(
-			tx.Where("date_type = ? AND date <= ?", model.DateTypeDay, endDay).
-				Or("date_type = ? AND date <= ?", model.DateTypeWeek, endWeek).
-				Or("date_type = ? AND date <= ?", model.DateTypeMonth, endMonth),
+			tx.Where("readings.date_type = ? AND readings.date <= ?", model.DateTypeDay, endDay).
+				Or("readings.date_type = ? AND readings.date <= ?", model.DateTypeWeek, endWeek).
+				Or("readings.date_type = ? AND readings.date <= ?", model.DateTypeMonth, endMonth),
 		).
 		Find(&readings).Error
 
 	return readings, err
 }
 
-// GetActiveReadingsCount fetches the count of readings active today.
-func GetActiveReadingsCount(tx *gorm.DB, userID uint) (int64, error) {
-	now := time.Now()
+// GetActiveReadingsCount fetches the count of readings active today, where
+// today is the calendar day of now in the user's timezone. Paused plans are
+// skipped.
+func GetActiveReadingsCount(tx *gorm.DB, userID uint, now time.Time) (int64, error) {
+	now = model.CivilDate(now)
 	startDay, endDay := getStartOfDay(now), getEndOfDay(now)
 	startWeek, endWeek := getStartOfWeek(now), getEndOfWeek(now)
 	startMonth, endMonth := getStartOfMonth(now), getEndOfMonth(now)
@@ -80,7 +87,7 @@ func GetActiveReadingsCount(tx *gorm.DB, userID uint) (int64, error) {
 	var count int64
 	err := tx.Model(&model.Reading{}).
 		Joins("JOIN plans ON plans.id = readings.plan_id").
-		Where("plans.user_id = ? AND readings.status != ?", userID, model.StatusCompleted).
+		Where("plans.user_id = ? AND plans.paused_at IS NULL AND readings.status NOT IN ?", userID, doneStatuses).
 		Where(
 			tx.Where("readings.date_type = ? AND readings.date >= ? AND readings.date <= ?", model.DateTypeDay, startDay, endDay).
 				Or("readings.date_type = ? AND readings.date >= ? AND readings.date <= ?", model.DateTypeWeek, startWeek, endWeek).
@@ -91,9 +98,10 @@ func GetActiveReadingsCount(tx *gorm.DB, userID uint) (int64, error) {
 	return count, err
 }
 
-// GetActiveReadings fetches readings active today.
-func GetActiveReadings(tx *gorm.DB, userID uint, limit int) ([]model.Reading, error) {
-	now := time.Now()
+// GetActiveReadings fetches readings active today, where today is the
+// calendar day of now in the user's timezone. Paused plans are skipped.
+func GetActiveReadings(tx *gorm.DB, userID uint, now time.Time, limit int) ([]model.Reading, error) {
+	now = model.CivilDate(now)
 	startDay, endDay := getStartOfDay(now), getEndOfDay(now)
 	startWeek, endWeek := getStartOfWeek(now), getEndOfWeek(now)
 	startMonth, endMonth := getStartOfMonth(now), getEndOfMonth(now)
@@ -101,7 +109,7 @@ func GetActiveReadings(tx *gorm.DB, userID uint, limit int) ([]model.Reading, er
 	var readings []model.Reading
 	q := tx.Preload("Plan").
 		Joins("JOIN plans ON plans.id = readings.plan_id").
-		Where("plans.user_id = ? AND readings.status != ?", userID, model.StatusCompleted).
+		Where("plans.user_id = ? AND plans.paused_at IS NULL AND readings.status NOT IN ?", userID, doneStatuses).
 		Where(
 			tx.Where("readings.date_type = ? AND readings.date >= ? AND readings.date <= ?", model.DateTypeDay, startDay, endDay).
 				Or("readings.date_type = ? AND readings.date >= ? AND readings.date <= ?", model.DateTypeWeek, startWeek, endWeek).
@@ -116,31 +124,52 @@ func GetActiveReadings(tx *gorm.DB, userID uint, limit int) ([]model.Reading, er
 	return readings, err
 }
 
-// GetWeeklyCompletedReadingsCount fetches the count of readings completed this week.
-func GetWeeklyCompletedReadingsCount(tx *gorm.DB, userID uint) (int64, error) {
-	now := time.Now()
-	startWeek, endWeek := getStartOfWeek(now), getEndOfWeek(now)
+// GetWeeklyCompletedReadingsCount fetches the count of readings completed
+// during the week containing now, using now's timezone for the week bounds.
+func GetWeeklyCompletedReadingsCount(tx *gorm.DB, userID uint, now time.Time) (int64, error) {
+	return countReadingsBetween(tx, userID, model.StatusCompleted, "completed_at", getStartOfWeek(now), getEndOfWeek(now))
+}
 
-	var count int64
-	err := tx.Model(&model.Reading{}).
+// GetMonthlyCompletedReadingsCount fetches the count of readings completed
+// during the month containing now, using now's timezone for the month bounds.
+func GetMonthlyCompletedReadingsCount(tx *gorm.DB, userID uint, now time.Time) (int64, error) {
+	return countReadingsBetween(tx, userID, model.StatusCompleted, "completed_at", getStartOfMonth(now), getEndOfMonth(now))
+}
+
+// GetWeeklySkippedReadingsCount fetches the count of readings skipped during
+// the week containing now, using now's timezone for the week bounds.
+func GetWeeklySkippedReadingsCount(tx *gorm.DB, userID uint, now time.Time) (int64, error) {
+	return countReadingsBetween(tx, userID, model.StatusSkipped, "skipped_at", getStartOfWeek(now), getEndOfWeek(now))
+}
+
+// GetMonthlySkippedReadingsCount fetches the count of readings skipped during
+// the month containing now, using now's timezone for the month bounds.
+func GetMonthlySkippedReadingsCount(tx *gorm.DB, userID uint, now time.Time) (int64, error) {
+	return countReadingsBetween(tx, userID, model.StatusSkipped, "skipped_at", getStartOfMonth(now), getEndOfMonth(now))
+}
+
+// GetCompletedTodayReadings fetches the user's readings completed on the
+// day containing now, in now's timezone, with their plan and journal entry.
+func GetCompletedTodayReadings(tx *gorm.DB, userID uint, now time.Time) ([]model.Reading, error) {
+	var readings []model.Reading
+	err := tx.Preload("Plan").Preload("Journal").
 		Joins("JOIN plans ON plans.id = readings.plan_id").
 		Where("plans.user_id = ? AND readings.status = ?", userID, model.StatusCompleted).
-		Where("readings.completed_at >= ? AND readings.completed_at <= ?", startWeek, endWeek).
-		Count(&count).Error
+		Where("readings.completed_at >= ? AND readings.completed_at <= ?", getStartOfDay(now).UTC(), getEndOfDay(now).UTC()).
+		Order("readings.completed_at DESC").
+		Find(&readings).Error
 
-	return count, err
+	return readings, err
 }
 
-// GetMonthlyCompletedReadingsCount fetches the count of readings completed this month.
-func GetMonthlyCompletedReadingsCount(tx *gorm.DB, userID uint) (int64, error) {
-	now := time.Now()
-	startMonth, endMonth := getStartOfMonth(now), getEndOfMonth(now)
-
+// countReadingsBetween counts the user's readings in status whose timestamp
+// column falls between start and end.
+func countReadingsBetween(tx *gorm.DB, userID uint, status model.ReadingStatus, column string, start, end time.Time) (int64, error) {
 	var count int64
 	err := tx.Model(&model.Reading{}).
 		Joins("JOIN plans ON plans.id = readings.plan_id").
-		Where("plans.user_id = ? AND readings.status = ?", userID, model.StatusCompleted).
-		Where("readings.completed_at >= ? AND readings.completed_at <= ?", startMonth, endMonth).
+		Where("plans.user_id = ? AND readings.status = ?", userID, status).
+		Where("readings."+column+" >= ? AND readings."+column+" <= ?", start.UTC(), end.UTC()).
 		Count(&count).Error
 
 	return count, err
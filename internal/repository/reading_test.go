@@ -1,36 +1,23 @@
 package repository
 
 import (
-	"fmt"
-	"strings"
 	"testing"
 	"time"
 
+	"readwillbe/internal/database/dbtest"
 	"readwillbe/internal/model"
 
-	"github.com/ncruces/go-sqlite3/gormlite"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gorm.io/gorm"
 )
 
 func setupTestDB(t *testing.T) *gorm.DB {
-	safeName := strings.ReplaceAll(t.Name(), "/", "_")
-	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", safeName)
-	db, err := gorm.Open(gormlite.Open(dsn), &gorm.Config{})
-	require.NoError(t, err)
-
-	sqlDB, err := db.DB()
-	require.NoError(t, err)
-	sqlDB.SetMaxOpenConns(1)
+	db := dbtest.Open(t)
 
-	err = db.AutoMigrate(&model.User{}, &model.Plan{}, &model.Reading{}, &model.PushSubscription{})
+	err := db.AutoMigrate(&model.User{}, &model.Plan{}, &model.Reading{}, &model.PushSubscription{}, &model.JournalEntry{}, &model.PasswordReset{}, &model.EmailVerification{}, &model.RecoveryCode{}, &model.Passkey{}, &model.Session{})
 	require.NoError(t, err)
 
-	t.Cleanup(func() {
-		_ = sqlDB.Close()
-	})
-
 	return db
 }
 
@@ -86,7 +73,7 @@ func TestGetDashboardReadings(t *testing.T) {
 	completedReading.Status = model.StatusCompleted
 	db.Save(completedReading)
 
-	readings, err := GetDashboardReadings(db, user.ID)
+	readings, err := GetDashboardReadings(db, user.ID, now)
 	require.NoError(t, err)
 
 	assert.Len(t, readings, 2)
@@ -120,7 +107,7 @@ func TestGetActiveReadingsCount(t *testing.T) {
 	// Future (not active today)
 	createTestReading(t, db, plan, "Future", now.AddDate(0, 0, 2))
 
-	count, err := GetActiveReadingsCount(db, user.ID)
+	count, err := GetActiveReadingsCount(db, user.ID, now)
 	require.NoError(t, err)
 	assert.Equal(t, int64(2), count)
 }
@@ -135,12 +122,12 @@ func TestGetActiveReadings(t *testing.T) {
 	createTestReading(t, db, plan, "Active 2", now)
 	createTestReading(t, db, plan, "Past", now.AddDate(0, 0, -2))
 
-	readings, err := GetActiveReadings(db, user.ID, 0)
+	readings, err := GetActiveReadings(db, user.ID, now, 0)
 	require.NoError(t, err)
 	assert.Len(t, readings, 2)
 
 	// Test limit
-	readingsLimit, err := GetActiveReadings(db, user.ID, 1)
+	readingsLimit, err := GetActiveReadings(db, user.ID, now, 1)
 	require.NoError(t, err)
 	assert.Len(t, readingsLimit, 1)
 }
@@ -150,9 +137,16 @@ func TestGetWeeklyCompletedReadingsCount(t *testing.T) {
 	user := createTestUser(t, db, "weekly@example.com", "password")
 	plan := createTestPlan(t, db, user, "Test Plan")
 
-	now := time.Now()
+	// Friday of ISO week 53, whose Monday falls in the previous calendar year.
+	now := time.Date(2021, time.January, 1, 10, 0, 0, 0, time.UTC)
 
 	// Completed this week
+	monday := time.Date(2020, time.December, 28, 9, 0, 0, 0, time.UTC)
+	r0 := createTestReading(t, db, plan, "Completed Monday", monday)
+	r0.Status = model.StatusCompleted
+	r0.CompletedAt = &monday
+	db.Save(r0)
+
 	r1 := createTestReading(t, db, plan, "Completed 1", now)
 	r1.Status = model.StatusCompleted
 	completedAt1 := now
@@ -170,9 +164,9 @@ func TestGetWeeklyCompletedReadingsCount(t *testing.T) {
 	// Pending
 	createTestReading(t, db, plan, "Pending", now)
 
-	count, err := GetWeeklyCompletedReadingsCount(db, user.ID)
+	count, err := GetWeeklyCompletedReadingsCount(db, user.ID, now)
 	require.NoError(t, err)
-	assert.Equal(t, int64(1), count)
+	assert.Equal(t, int64(2), count)
 }
 
 func TestGetMonthlyCompletedReadingsCount(t *testing.T) {
@@ -180,7 +174,8 @@ func TestGetMonthlyCompletedReadingsCount(t *testing.T) {
 	user := createTestUser(t, db, "monthly@example.com", "password")
 	plan := createTestPlan(t, db, user, "Test Plan")
 
-	now := time.Now()
+	// Leap day: the month must run through Feb 29.
+	now := time.Date(2024, time.February, 29, 22, 0, 0, 0, time.UTC)
 
 	// Completed this month
 	r1 := createTestReading(t, db, plan, "Completed 1", now)
@@ -196,10 +191,86 @@ func TestGetMonthlyCompletedReadingsCount(t *testing.T) {
 	r2.CompletedAt = &completedAt2
 	db.Save(r2)
 
+	// Completed early next month
+	march := time.Date(2024, time.March, 1, 0, 30, 0, 0, time.UTC)
+	r3 := createTestReading(t, db, plan, "Completed 3", march)
+	r3.Status = model.StatusCompleted
+	r3.CompletedAt = &march
+	db.Save(r3)
+
 	// Pending
 	createTestReading(t, db, plan, "Pending", now)
 
-	count, err := GetMonthlyCompletedReadingsCount(db, user.ID)
+	count, err := GetMonthlyCompletedReadingsCount(db, user.ID, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestSkippedReadings(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "skipped@example.com", "password")
+	plan := createTestPlan(t, db, user, "Test Plan")
+
+	now := time.Date(2026, time.March, 18, 10, 0, 0, 0, time.UTC)
+
+	skipped := createTestReading(t, db, plan, "Skipped", now)
+	skipped.Status = model.StatusSkipped
+	skipped.SkippedAt = &now
+	db.Save(skipped)
+
+	completed := createTestReading(t, db, plan, "Completed", now)
+	completed.Status = model.StatusCompleted
+	completed.CompletedAt = &now
+	db.Save(completed)
+
+	createTestReading(t, db, plan, "Pending", now)
+
+	readings, err := GetDashboardReadings(db, user.ID, now)
+	require.NoError(t, err)
+	require.Len(t, readings, 1)
+	assert.Equal(t, "Pending", readings[0].Content)
+
+	count, err := GetActiveReadingsCount(db, user.ID, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	weeklyCompleted, err := GetWeeklyCompletedReadingsCount(db, user.ID, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), weeklyCompleted, "skipped readings are not counted as completed")
+
+	weeklySkipped, err := GetWeeklySkippedReadingsCount(db, user.ID, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), weeklySkipped)
+
+	monthlySkipped, err := GetMonthlySkippedReadingsCount(db, user.ID, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), monthlySkipped)
+}
+
+func TestGetDashboardReadings_UserTimezone(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "tz@example.com", "password")
+	plan := createTestPlan(t, db, user, "Test Plan")
+
+	auckland, err := time.LoadLocation("Pacific/Auckland")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	// 10am Jan 16 in Auckland is still Jan 15 in UTC.
+	instant := time.Date(2025, time.January, 15, 21, 0, 0, 0, time.UTC)
+	tomorrowUTC := createTestReading(t, db, plan, "Jan 16", time.Date(2025, time.January, 16, 0, 0, 0, 0, time.UTC))
+
+	readings, err := GetDashboardReadings(db, user.ID, instant)
+	require.NoError(t, err)
+	assert.Empty(t, readings, "Jan 16 reading is still in the future in UTC")
+
+	readings, err = GetDashboardReadings(db, user.ID, instant.In(auckland))
+	require.NoError(t, err)
+	require.Len(t, readings, 1, "Jan 16 reading is today in Auckland")
+	assert.Equal(t, tomorrowUTC.ID, readings[0].ID)
+
+	count, err := GetActiveReadingsCount(db, user.ID, instant.In(auckland))
 	require.NoError(t, err)
 	assert.Equal(t, int64(1), count)
 }
@@ -228,3 +299,21 @@ func TestDateHelpers(t *testing.T) {
 	endMonth := getEndOfMonth(fixedDate)
 	assert.Equal(t, time.Date(2024, 1, 31, 23, 59, 59, 999999999, time.UTC), endMonth)
 }
+
+func TestDateHelpers_DST(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	// Clocks spring forward at 2am on Sunday Mar 9 2025; the day is 23 hours long.
+	now := time.Date(2025, time.March, 9, 12, 0, 0, 0, newYork)
+
+	startDay := getStartOfDay(now)
+	assert.Equal(t, time.Date(2025, time.March, 9, 5, 0, 0, 0, time.UTC), startDay.UTC())
+	assert.Equal(t, 23*time.Hour, getEndOfDay(now).Sub(startDay)+time.Nanosecond)
+
+	startWeek := getStartOfWeek(now)
+	assert.Equal(t, time.Date(2025, time.March, 3, 0, 0, 0, 0, newYork), startWeek)
+	assert.Equal(t, time.Date(2025, time.March, 9, 23, 59, 59, 999999999, newYork), getEndOfWeek(now))
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"readwillbe/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SearchFilter narrows a reading search. Zero-valued fields are ignored.
+type SearchFilter struct {
+	Query  string
+	PlanID uint
+	Status model.ReadingStatus
+	From   *time.Time // earliest reading date, inclusive
+	To     *time.Time // latest reading date, inclusive
+}
+
+// IsZero reports whether the filter matches every reading.
+func (f SearchFilter) IsZero() bool {
+	return strings.TrimSpace(f.Query) == "" && f.PlanID == 0 && f.Status == "" && f.From == nil && f.To == nil
+}
+
+// postgresSearchDocument is the text PostgreSQL matches a query against; it
+// covers the same fields as the SQLite reading_search index.
+const postgresSearchDocument = `to_tsvector('simple', COALESCE(readings.content, '') || ' ' ||
+	COALESCE(readings.notes, '') || ' ' || COALESCE(plans.title, '') || ' ' ||
+	COALESCE(journal_entries.body, ''))`
+
+// SearchReadings returns the user's readings matching filter, with their
+// plan and journal entry loaded. A query matches each of its words, as a
+// prefix, against the reading's content, notes, plan title and journal
+// entry; results are ordered by relevance. Without a query, the filtered
+// readings are returned newest first. Readings of deleted plans are left out.
+func SearchReadings(tx *gorm.DB, userID uint, filter SearchFilter, limit int) ([]model.Reading, error) {
+	q := tx.Preload("Plan").Preload("Journal").
+		Joins("JOIN plans ON plans.id = readings.plan_id AND plans.deleted_at IS NULL").
+		Where("plans.user_id = ?", userID)
+	if filter.PlanID != 0 {
+		q = q.Where("readings.plan_id = ?", filter.PlanID)
+	}
+	if filter.Status != "" {
+		q = q.Where("readings.status = ?", filter.Status)
+	}
+	if filter.From != nil {
+		q = q.Where("readings.date >= ?", model.CivilDate(*filter.From))
+	}
+	if filter.To != nil {
+		q = q.Where("readings.date <= ?", model.CivilDate(*filter.To))
+	}
+
+	if terms := searchTerms(filter.Query); len(terms) > 0 {
+		q = matchSearchTerms(tx, q, terms)
+	}
+	if limit > 0 {
+		q = q.Limit(limit)
+	}
+
+	var readings []model.Reading
+	err := q.Order("readings.date DESC").Order("readings.id").Find(&readings).Error
+	return readings, err
+}
+
+// searchTerms splits a query into lower-case words, dropping punctuation so
+// nothing the user types is read as query syntax.
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// matchSearchTerms restricts q to readings matching every term and orders
+// them by relevance, using FTS5 on SQLite and tsvector on PostgreSQL.
+func matchSearchTerms(tx *gorm.DB, q *gorm.DB, terms []string) *gorm.DB {
+	if tx.Name() == "postgres" {
+		prefixes := make([]string, len(terms))
+		for i, term := range terms {
+			prefixes[i] = term + ":*"
+		}
+		query := strings.Join(prefixes, " & ")
+		return q.Joins("LEFT JOIN journal_entries ON journal_entries.reading_id = readings.id").
+			Where(postgresSearchDocument+" @@ to_tsquery('simple', ?)", query).
+			Order(clause.OrderBy{Expression: clause.Expr{
+				SQL:                "ts_rank(" + postgresSearchDocument + ", to_tsquery('simple', ?)) DESC",
+				Vars:               []interface{}{query},
+				WithoutParentheses: true,
+			}})
+	}
+
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = fmt.Sprintf("%q*", term)
+	}
+	return q.Joins("JOIN reading_search ON reading_search.rowid = readings.id").
+		Where("reading_search MATCH ?", strings.Join(quoted, " ")).
+		Order("bm25(reading_search)")
+}
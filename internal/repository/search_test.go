@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"readwillbe/internal/database/dbtest"
+	"readwillbe/internal/migrate"
+	"readwillbe/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readingIDs(readings []model.Reading) []uint {
+	ids := make([]uint, len(readings))
+	for i, r := range readings {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+func TestSearchReadings(t *testing.T) {
+	// Search relies on the index built by the migrations, not AutoMigrate.
+	db := dbtest.Open(t)
+	_, err := migrate.New(db).Up()
+	require.NoError(t, err)
+
+	user := createTestUser(t, db, "search@example.com", "password")
+	other := createTestUser(t, db, "other@example.com", "password")
+	gospels := createTestPlan(t, db, user, "Gospels")
+	psalms := createTestPlan(t, db, user, "Psalms of Ascent")
+	foreign := createTestPlan(t, db, other, "Gospels")
+
+	day := func(d int) time.Time { return time.Date(2026, time.May, d, 0, 0, 0, 0, time.UTC) }
+	john := createTestReading(t, db, gospels, "John 1", day(1))
+	mark := createTestReading(t, db, gospels, "Mark 1", day(2))
+	psalm := createTestReading(t, db, psalms, "Psalm 121", day(3))
+	createTestReading(t, db, foreign, "John 1", day(1))
+
+	mark.Notes = "Baptism in the Jordan"
+	require.NoError(t, db.Save(mark).Error)
+	_, err = SaveJournalEntry(db, user.ID, psalm.ID, "I lift my eyes to the hills", day(3))
+	require.NoError(t, err)
+	psalm.Status = model.StatusCompleted
+	require.NoError(t, db.Save(psalm).Error)
+
+	search := func(filter SearchFilter) []uint {
+		t.Helper()
+		readings, err := SearchReadings(db, user.ID, filter, 0)
+		require.NoError(t, err)
+		return readingIDs(readings)
+	}
+
+	assert.Equal(t, []uint{john.ID}, search(SearchFilter{Query: "john"}), "content matches, other users' readings do not")
+	assert.ElementsMatch(t, []uint{john.ID, mark.ID}, search(SearchFilter{Query: "gospel"}), "plan titles match by prefix")
+	assert.Equal(t, []uint{mark.ID}, search(SearchFilter{Query: "jordan"}), "reading notes match")
+	assert.Equal(t, []uint{psalm.ID}, search(SearchFilter{Query: "HILLS"}), "journal entries match")
+	assert.Equal(t, []uint{psalm.ID}, search(SearchFilter{Query: "ascent hills"}), "every word must match")
+	assert.Empty(t, search(SearchFilter{Query: "john hills"}))
+	assert.Equal(t, []uint{john.ID}, search(SearchFilter{Query: `"john*(`}), "query syntax is not interpreted")
+
+	assert.Equal(t, []uint{psalm.ID, mark.ID, john.ID}, search(SearchFilter{}), "no query lists newest first")
+	from, to := day(2), day(1)
+	assert.Equal(t, []uint{mark.ID}, search(SearchFilter{Query: "1", PlanID: gospels.ID, From: &from}))
+	assert.Equal(t, []uint{john.ID}, search(SearchFilter{PlanID: gospels.ID, To: &to}))
+	assert.Equal(t, []uint{psalm.ID}, search(SearchFilter{Status: model.StatusCompleted}))
+
+	// The index follows edits to readings, plans and journal entries.
+	john.Content = "Luke 1"
+	require.NoError(t, db.Save(john).Error)
+	assert.Empty(t, search(SearchFilter{Query: "john"}))
+	assert.Equal(t, []uint{john.ID}, search(SearchFilter{Query: "luke"}))
+
+	require.NoError(t, db.Model(psalms).Update("title", "Songs").Error)
+	assert.Equal(t, []uint{psalm.ID}, search(SearchFilter{Query: "songs"}))
+
+	_, err = SaveJournalEntry(db, user.ID, psalm.ID, "", day(4))
+	require.NoError(t, err)
+	assert.Empty(t, search(SearchFilter{Query: "hills"}))
+
+	require.NoError(t, db.Delete(gospels).Error)
+	assert.Equal(t, []uint{psalm.ID}, search(SearchFilter{}), "deleted plans are left out")
+}
+
+func TestSearchFilter_IsZero(t *testing.T) {
+	assert.True(t, SearchFilter{Query: "  "}.IsZero())
+	assert.False(t, SearchFilter{Query: "john"}.IsZero())
+	assert.False(t, SearchFilter{Status: model.StatusSkipped}.IsZero())
+}
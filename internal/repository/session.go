@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"time"
+
+	"readwillbe/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreateSession stores a new session and returns the token for its cookie.
+// The token is the only copy; just its hash is stored.
+func CreateSession(tx *gorm.DB, session *model.Session) (string, error) {
+	token, hash, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	session.TokenHash = hash
+	if err := tx.Create(session).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// GetSession looks up the unexpired session with token, returning
+// gorm.ErrRecordNotFound if it has expired or been revoked.
+func GetSession(tx *gorm.DB, token string, now time.Time) (model.Session, error) {
+	var session model.Session
+	err := tx.Where("token_hash = ? AND expires_at > ?", hashToken(token), now.UTC()).First(&session).Error
+	return session, err
+}
+
+// UpdateSession saves the values, owner and client details of the session
+// with token, returning false if it no longer exists so that a revoked
+// session is not brought back.
+func UpdateSession(tx *gorm.DB, token string, session model.Session) (bool, error) {
+	result := tx.Model(&model.Session{}).Where("token_hash = ?", hashToken(token)).Updates(map[string]interface{}{
+		"user_id":      session.UserID,
+		"data":         session.Data,
+		"user_agent":   session.UserAgent,
+		"ip":           session.IP,
+		"last_seen_at": session.LastSeenAt,
+		"expires_at":   session.ExpiresAt,
+	})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteSession removes the session with token.
+func DeleteSession(tx *gorm.DB, token string) error {
+	return tx.Where("token_hash = ?", hashToken(token)).Delete(&model.Session{}).Error
+}
+
+// ListSessions returns the user's unexpired sessions, most recently used
+// first.
+func ListSessions(tx *gorm.DB, userID uint, now time.Time) ([]model.Session, error) {
+	var sessions []model.Session
+	err := tx.Where("user_id = ? AND expires_at > ?", userID, now.UTC()).
+		Order("last_seen_at DESC, id DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// RevokeSession removes one of the user's sessions, returning false if they
+// have none with that ID.
+func RevokeSession(tx *gorm.DB, userID, id uint) (bool, error) {
+	result := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&model.Session{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// RevokeOtherSessions removes all the user's sessions except the one with
+// token, returning how many were removed. An empty token removes them all.
+func RevokeOtherSessions(tx *gorm.DB, userID uint, token string) (int64, error) {
+	query := tx.Where("user_id = ?", userID)
+	if token != "" {
+		query = query.Where("token_hash <> ?", hashToken(token))
+	}
+	result := query.Delete(&model.Session{})
+	return result.RowsAffected, result.Error
+}
+
+// DeleteExpiredSessions removes sessions that expired before now.
+func DeleteExpiredSessions(tx *gorm.DB, now time.Time) (int64, error) {
+	result := tx.Where("expires_at <= ?", now.UTC()).Delete(&model.Session{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"readwillbe/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestSessions(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "sessions@example.com", "password")
+	other := createTestUser(t, db, "other@example.com", "password")
+	now := time.Date(2026, time.May, 1, 8, 0, 0, 0, time.UTC)
+
+	create := func(userID *uint, lastSeen time.Time) (string, model.Session) {
+		t.Helper()
+		s := model.Session{Name: "session", UserID: userID, CreatedAt: lastSeen, LastSeenAt: lastSeen, ExpiresAt: lastSeen.Add(24 * time.Hour)}
+		token, err := CreateSession(db, &s)
+		require.NoError(t, err)
+		return token, s
+	}
+
+	laptop, laptopRow := create(&user.ID, now.Add(-time.Hour))
+	phone, phoneRow := create(&user.ID, now)
+	old, _ := create(&user.ID, now.Add(-25*time.Hour))
+	anonymous, _ := create(nil, now)
+	otherToken, _ := create(&other.ID, now)
+
+	assert.NotEqual(t, laptop, laptopRow.TokenHash, "only the token's hash is stored")
+
+	got, err := GetSession(db, laptop, now)
+	require.NoError(t, err)
+	assert.Equal(t, laptopRow.ID, got.ID)
+	_, err = GetSession(db, old, now)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound, "expired sessions are not loaded")
+
+	sessions, err := ListSessions(db, user.ID, now)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, phoneRow.ID, sessions[0].ID, "most recently used first")
+	assert.Equal(t, laptopRow.ID, sessions[1].ID)
+
+	found, err := UpdateSession(db, anonymous, model.Session{UserID: &user.ID, Data: "signed in", LastSeenAt: now, ExpiresAt: now.Add(time.Hour)})
+	require.NoError(t, err)
+	assert.True(t, found)
+	got, err = GetSession(db, anonymous, now)
+	require.NoError(t, err)
+	assert.Equal(t, "signed in", got.Data)
+	require.NotNil(t, got.UserID)
+	assert.Equal(t, user.ID, *got.UserID)
+
+	revoked, err := RevokeSession(db, other.ID, laptopRow.ID)
+	require.NoError(t, err)
+	assert.False(t, revoked, "only the owner can revoke a session")
+	revoked, err = RevokeSession(db, user.ID, laptopRow.ID)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	found, err = UpdateSession(db, laptop, model.Session{Data: "too late", ExpiresAt: now.Add(time.Hour)})
+	require.NoError(t, err)
+	assert.False(t, found, "a revoked session is not brought back")
+
+	n, err := RevokeOtherSessions(db, user.ID, phone)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	sessions, err = ListSessions(db, user.ID, now)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, phoneRow.ID, sessions[0].ID)
+
+	_, err = GetSession(db, otherToken, now)
+	assert.NoError(t, err, "other users' sessions are untouched")
+
+	n, err = DeleteExpiredSessions(db, now.Add(23*time.Hour+time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+	n, err = DeleteExpiredSessions(db, now.Add(24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"time"
+
+	"readwillbe/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// EnableTOTP turns on two-factor sign-in for the user with secret, whose
+// code for step has just been confirmed, and replaces their recovery codes.
+func EnableTOTP(tx *gorm.DB, userID uint, secret string, step int64, recoveryCodes []string, now time.Time) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_secret":     secret,
+			"totp_enabled_at": now,
+			"totp_last_step":  step,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return ReplaceRecoveryCodes(tx, userID, recoveryCodes, now)
+	})
+}
+
+// DisableTOTP turns off two-factor sign-in for the user and deletes their
+// secret and recovery codes.
+func DisableTOTP(tx *gorm.DB, userID uint) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_secret":     "",
+			"totp_enabled_at": nil,
+			"totp_last_step":  0,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error
+	})
+}
+
+// UseTOTPStep records that the user's code for step was accepted. It
+// returns false, recording nothing, if a code for that step or a later one
+// was already used, so each code works only once.
+func UseTOTPStep(tx *gorm.DB, userID uint, step int64) (bool, error) {
+	result := tx.Model(&model.User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+// ReplaceRecoveryCodes discards the user's recovery codes and stores hashes
+// of codes in their place.
+func ReplaceRecoveryCodes(tx *gorm.DB, userID uint, codes []string, now time.Time) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		rows := make([]model.RecoveryCode, len(codes))
+		for i, code := range codes {
+			rows[i] = model.RecoveryCode{UserID: userID, CodeHash: hashToken(code), CreatedAt: now}
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+// UseRecoveryCode marks one of the user's unused recovery codes as used,
+// returning false if code is not among them.
+func UseRecoveryCode(tx *gorm.DB, userID uint, code string, now time.Time) (bool, error) {
+	result := tx.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashToken(code)).
+		Update("used_at", now)
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountRecoveryCodes counts the user's unused recovery codes.
+func CountRecoveryCodes(tx *gorm.DB, userID uint) (int64, error) {
+	var n int64
+	err := tx.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&n).Error
+	return n, err
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"readwillbe/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTwoFactor(t *testing.T) {
+	db := setupTestDB(t)
+	user := createTestUser(t, db, "totp@example.com", "password")
+	now := time.Date(2026, time.May, 1, 8, 0, 0, 0, time.UTC)
+
+	require.NoError(t, EnableTOTP(db, user.ID, "SECRET", 100, []string{"aaaaa-aaaaa", "bbbbb-bbbbb"}, now))
+
+	var stored model.User
+	require.NoError(t, db.First(&stored, user.ID).Error)
+	assert.True(t, stored.TwoFactorEnabled())
+	assert.Equal(t, "SECRET", stored.TOTPSecret)
+	assert.Equal(t, int64(100), stored.TOTPLastStep)
+
+	var codes []model.RecoveryCode
+	require.NoError(t, db.Where("user_id = ?", user.ID).Find(&codes).Error)
+	require.Len(t, codes, 2)
+	assert.NotEqual(t, "aaaaa-aaaaa", codes[0].CodeHash, "only hashes of recovery codes are stored")
+
+	t.Run("steps are single use", func(t *testing.T) {
+		ok, err := UseTOTPStep(db, user.ID, 100)
+		require.NoError(t, err)
+		assert.False(t, ok, "the step used to enable cannot be used again")
+
+		ok, err = UseTOTPStep(db, user.ID, 101)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = UseTOTPStep(db, user.ID, 101)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("recovery codes are single use", func(t *testing.T) {
+		ok, err := UseRecoveryCode(db, user.ID, "aaaaa-aaaaa", now)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		ok, err = UseRecoveryCode(db, user.ID, "aaaaa-aaaaa", now)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		ok, err = UseRecoveryCode(db, user.ID, "ccccc-ccccc", now)
+		require.NoError(t, err)
+		assert.False(t, ok)
+
+		left, err := CountRecoveryCodes(db, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), left)
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		require.NoError(t, ReplaceRecoveryCodes(db, user.ID, []string{"ddddd-ddddd", "eeeee-eeeee", "fffff-fffff"}, now))
+		left, err := CountRecoveryCodes(db, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), left)
+
+		ok, err := UseRecoveryCode(db, user.ID, "bbbbb-bbbbb", now)
+		require.NoError(t, err)
+		assert.False(t, ok, "old codes stop working")
+	})
+
+	t.Run("disable", func(t *testing.T) {
+		require.NoError(t, DisableTOTP(db, user.ID))
+
+		var disabled model.User
+		require.NoError(t, db.First(&disabled, user.ID).Error)
+		assert.False(t, disabled.TwoFactorEnabled())
+		assert.Empty(t, disabled.TOTPSecret)
+
+		left, err := CountRecoveryCodes(db, user.ID)
+		require.NoError(t, err)
+		assert.Zero(t, left)
+	})
+}
@@ -35,3 +35,8 @@ func GetUserByEmail(db *gorm.DB, email string) (model.User, error) {
 func CreateUser(db *gorm.DB, user *model.User) error {
 	return db.Create(user).Error
 }
+
+// UpdatePassword replaces the stored password hash of the user.
+func UpdatePassword(db *gorm.DB, userID uint, passwordHash string) error {
+	return db.Model(&model.User{}).Where("id = ?", userID).Update("password", passwordHash).Error
+}
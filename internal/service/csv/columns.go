@@ -0,0 +1,191 @@
+package csvservice
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Canonical column names. Headers are matched against these and the aliases
+// in columnAliases; any other column is ignored.
+const (
+	ColumnDate     = "date"
+	ColumnReading  = "reading"
+	ColumnNotes    = "notes"
+	ColumnLink     = "link"
+	ColumnOrder    = "order"
+	ColumnTags     = "tags"
+	ColumnDuration = "duration_minutes"
+)
+
+// MaxDurationMinutes bounds the duration_minutes column to one day.
+const MaxDurationMinutes = 24 * 60
+
+var columnAliases = map[string]string{
+	"date":             ColumnDate,
+	"day":              ColumnDate,
+	"when":             ColumnDate,
+	"scheduled":        ColumnDate,
+	"due":              ColumnDate,
+	"reading":          ColumnReading,
+	"readings":         ColumnReading,
+	"content":          ColumnReading,
+	"passage":          ColumnReading,
+	"assignment":       ColumnReading,
+	"notes":            ColumnNotes,
+	"note":             ColumnNotes,
+	"comment":          ColumnNotes,
+	"comments":         ColumnNotes,
+	"link":             ColumnLink,
+	"url":              ColumnLink,
+	"order":            ColumnOrder,
+	"sort":             ColumnOrder,
+	"sort_order":       ColumnOrder,
+	"position":         ColumnOrder,
+	"sequence":         ColumnOrder,
+	"tags":             ColumnTags,
+	"tag":              ColumnTags,
+	"labels":           ColumnTags,
+	"duration_minutes": ColumnDuration,
+	"duration":         ColumnDuration,
+	"minutes":          ColumnDuration,
+}
+
+// columnMap records which field index holds each canonical column.
+type columnMap map[string]int
+
+// mapColumns matches header cells to canonical columns. A header naming
+// neither a date nor a reading column falls back to the original layout of
+// date then reading, so files with arbitrary headings keep importing. A
+// header naming only one of them takes the one unrecognised cell for the
+// other, so "Date,Description" and "Day,Text" import too; with more than one
+// to choose from it is an error rather than a guess.
+func mapColumns(header []string) (columnMap, error) {
+	cols := columnMap{}
+	for i, cell := range header {
+		name, ok := columnAliases[normalizeHeader(cell)]
+		if !ok {
+			continue
+		}
+		if _, dup := cols[name]; dup {
+			return nil, fmt.Errorf("CSV header has more than one %s column", name)
+		}
+		cols[name] = i
+	}
+
+	_, hasDate := cols[ColumnDate]
+	_, hasReading := cols[ColumnReading]
+	missing := ColumnReading
+	switch {
+	case hasDate && hasReading:
+		return cols, nil
+	case !hasDate && !hasReading:
+		if len(header) < 2 {
+			return nil, fmt.Errorf("CSV must have at least 2 columns: date and reading")
+		}
+		return columnMap{ColumnDate: 0, ColumnReading: 1}, nil
+	case !hasDate:
+		missing = ColumnDate
+	}
+
+	i, ok := onlyUnmatched(header, cols)
+	if !ok {
+		return nil, fmt.Errorf("CSV header has no %s column; name it one of: %s", missing, strings.Join(aliasesOf(missing), ", "))
+	}
+	cols[missing] = i
+	return cols, nil
+}
+
+// onlyUnmatched returns the index of the header cell not claimed by any
+// column in cols, if there is exactly one.
+func onlyUnmatched(header []string, cols columnMap) (int, bool) {
+	claimed := make(map[int]bool, len(cols))
+	for _, i := range cols {
+		claimed[i] = true
+	}
+	found := -1
+	for i := range header {
+		if claimed[i] {
+			continue
+		}
+		if found >= 0 {
+			return 0, false
+		}
+		found = i
+	}
+	return found, found >= 0
+}
+
+// aliasesOf returns the header names accepted for column, sorted.
+func aliasesOf(column string) []string {
+	var names []string
+	for alias, name := range columnAliases {
+		if name == column {
+			names = append(names, alias)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// normalizeHeader lowercases a header cell and folds spaces and hyphens to
+// underscores, so "Duration Minutes" and "duration-minutes" both match.
+func normalizeHeader(s string) string {
+	s = strings.TrimPrefix(s, "\uFEFF") // byte order mark from spreadsheet exports
+	s = strings.ToLower(strings.TrimSpace(s))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(s)
+}
+
+// cell returns the trimmed value of column name in record, and whether the
+// record has that column at all.
+func (c columnMap) cell(record []string, name string) (string, bool) {
+	i, ok := c[name]
+	if !ok || i >= len(record) {
+		return "", false
+	}
+	return strings.TrimSpace(record[i]), true
+}
+
+func parseLink(s string) (string, error) {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("link must be an http or https URL")
+	}
+	return u.String(), nil
+}
+
+func parseOrder(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("order must be a whole number")
+	}
+	return n, nil
+}
+
+func parseDuration(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 || n > MaxDurationMinutes {
+		return 0, fmt.Errorf("duration_minutes must be a whole number from 0 to %d", MaxDurationMinutes)
+	}
+	return n, nil
+}
+
+// parseTags splits a tags cell on commas or semicolons and rejoins the
+// non-empty, trimmed tags with commas.
+func parseTags(s string) (string, error) {
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' })
+	tags := make([]string, 0, len(parts))
+	for _, p := range parts {
+		tag := strings.TrimSpace(p)
+		if tag == "" {
+			continue
+		}
+		if IsFormulaInjection(tag) {
+			return "", fmt.Errorf("tags cannot start with formula characters (=, +, -, @)")
+		}
+		tags = append(tags, tag)
+	}
+	return strings.Join(tags, ","), nil
+}
@@ -0,0 +1,63 @@
+package csvservice
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxReportedErrors caps how many problems [RowErrors.Error] lists, so a
+// file that is wrong throughout still yields a readable message.
+const MaxReportedErrors = 100
+
+// MaxReportedValueLength caps, in characters, how much of an offending cell
+// a [RowError] keeps, so a runaway cell does not swamp the message.
+const MaxReportedValueLength = 50
+
+// RowError describes one problem with one cell of an uploaded CSV.
+type RowError struct {
+	Row    int    // 1-based line in the file, counting the header
+	Column string // one of the Column constants
+	Value  string // the offending cell, trimmed
+	Reason string
+}
+
+func (e RowError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("row %d, %s: %s", e.Row, e.Column, e.Reason)
+	}
+	return fmt.Sprintf("row %d, %s %q: %s", e.Row, e.Column, e.Value, e.Reason)
+}
+
+// truncateValue shortens s to at most n characters, marking the cut with an
+// ellipsis. It counts runes so multi-byte text is never split mid-character.
+func truncateValue(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "..."
+}
+
+// RowErrors is every problem found in a file. [ParseCSV] returns it instead
+// of stopping at the first bad row so users can fix the whole file at once.
+type RowErrors []RowError
+
+// Error renders a summary line followed by one line per problem.
+func (e RowErrors) Error() string {
+	var b strings.Builder
+	if len(e) == 1 {
+		b.WriteString("1 problem found")
+	} else {
+		fmt.Fprintf(&b, "%d problems found", len(e))
+	}
+
+	for i, re := range e {
+		if i == MaxReportedErrors {
+			fmt.Fprintf(&b, "\n... and %d more", len(e)-MaxReportedErrors)
+			break
+		}
+		b.WriteString("\n")
+		b.WriteString(re.Error())
+	}
+	return b.String()
+}
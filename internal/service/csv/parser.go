@@ -7,6 +7,8 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -38,73 +40,209 @@ func IsFormulaInjection(s string) bool {
 }
 
 // ParseCSV reads a CSV stream from r and converts each data row into a
-// [model.Reading]. The first row is treated as a header.
-func ParseCSV(r io.Reader) ([]model.Reading, error) {
+// [model.Reading]. Columns are matched by header name, so they may appear in
+// any order alongside unrelated columns; see columnAliases. start is the day
+// the plan begins, which "Day n" and "Week n" cells are counted from. Every
+// invalid cell is reported together as [RowErrors]. Readings are returned by
+// date, then by the order column.
+func ParseCSV(r io.Reader, start time.Time) ([]model.Reading, error) {
+	cols, rows, err := readRecords(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var readings []model.Reading
+	var rowErrs RowErrors
+	for i, record := range rows {
+		reading, errs := parseRecord(i+2, record, cols, start)
+		if len(errs) > 0 {
+			rowErrs = append(rowErrs, errs...)
+			continue
+		}
+		readings = append(readings, reading)
+	}
+
+	if len(rowErrs) > 0 {
+		return nil, rowErrs
+	}
+
+	sort.SliceStable(readings, func(i, j int) bool {
+		if !readings[i].Date.Equal(readings[j].Date) {
+			return readings[i].Date.Before(readings[j].Date)
+		}
+		return readings[i].SortOrder < readings[j].SortOrder
+	})
+	return readings, nil
+}
+
+// readRecords reads every record from r, checks the overall shape of the
+// file (a header plus between one and MaxCSVRows data rows) and maps the
+// header to columns. It returns the data rows without the header.
+func readRecords(r io.Reader) (columnMap, [][]string, error) {
 	reader := csv.NewReader(r)
 	reader.LazyQuotes = false
 	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
 	records, err := reader.ReadAll()
 	if err != nil {
-		return nil, errors.Wrap(err, "reading CSV (ensure proper quoting)")
+		return nil, nil, errors.Wrap(err, "reading CSV (ensure proper quoting)")
 	}
 
 	if len(records) < 2 {
-		return nil, fmt.Errorf("CSV must have at least a header and one data row")
+		return nil, nil, fmt.Errorf("CSV must have at least a header and one data row")
 	}
 
 	if len(records) > MaxCSVRows+1 {
-		return nil, fmt.Errorf("CSV exceeds maximum of %d rows", MaxCSVRows)
+		return nil, nil, fmt.Errorf("CSV exceeds maximum of %d rows", MaxCSVRows)
 	}
 
-	header := records[0]
-	if len(header) < 2 {
-		return nil, fmt.Errorf("CSV must have at least 2 columns: date and reading")
+	cols, err := mapColumns(records[0])
+	if err != nil {
+		return nil, nil, err
 	}
 
-	var readings []model.Reading
-	for i, record := range records[1:] {
-		if len(record) < 2 {
-			return nil, fmt.Errorf("row %d: insufficient columns", i+2)
+	return cols, records[1:], nil
+}
+
+// parseRecord converts the data row on line row into a pending
+// [model.Reading], returning every problem with its cells.
+func parseRecord(row int, record []string, cols columnMap, start time.Time) (model.Reading, []RowError) {
+	var errs []RowError
+	fail := func(column, value, reason string) {
+		errs = append(errs, RowError{Row: row, Column: column, Value: truncateValue(value, MaxReportedValueLength), Reason: reason})
+	}
+
+	reading := model.Reading{Status: model.StatusPending}
+
+	dateStr, _ := cols.cell(record, ColumnDate)
+	if dateStr == "" {
+		fail(ColumnDate, dateStr, "date is required")
+	} else {
+		date, dateType, offset, err := ResolveDate(dateStr, start)
+		if err != nil {
+			fail(ColumnDate, dateStr, err.Error())
 		}
+		reading.Date, reading.DateType, reading.StartOffset = date, dateType, offset
+	}
 
-		dateStr := strings.TrimSpace(record[0])
-		content := strings.TrimSpace(record[1])
+	content, ok := cols.cell(record, ColumnReading)
+	switch {
+	case !ok:
+		fail(ColumnReading, "", "missing column")
+	case content == "":
+		fail(ColumnReading, content, "reading content is required")
+	case len(content) > MaxContentLength:
+		fail(ColumnReading, content, fmt.Sprintf("content exceeds maximum length of %d characters", MaxContentLength))
+	case IsFormulaInjection(content):
+		fail(ColumnReading, content, "content cannot start with formula characters (=, +, -, @)")
+	}
+	reading.Content = content
 
-		if dateStr == "" || content == "" {
-			return nil, fmt.Errorf("row %d: date and reading content are required", i+2)
+	if notes, _ := cols.cell(record, ColumnNotes); notes != "" {
+		switch {
+		case len(notes) > MaxContentLength:
+			fail(ColumnNotes, notes, fmt.Sprintf("notes exceed maximum length of %d characters", MaxContentLength))
+		case IsFormulaInjection(notes):
+			fail(ColumnNotes, notes, "notes cannot start with formula characters (=, +, -, @)")
+		default:
+			reading.Notes = notes
 		}
+	}
 
-		if len(content) > MaxContentLength {
-			return nil, fmt.Errorf("row %d: content exceeds maximum length of %d characters", i+2, MaxContentLength)
+	if link, _ := cols.cell(record, ColumnLink); link != "" {
+		if parsed, err := parseLink(link); err != nil {
+			fail(ColumnLink, link, err.Error())
+		} else {
+			reading.Link = parsed
 		}
+	}
 
-		if IsFormulaInjection(content) {
-			return nil, fmt.Errorf("row %d: content cannot start with formula characters (=, +, -, @)", i+2)
+	if order, _ := cols.cell(record, ColumnOrder); order != "" {
+		if n, err := parseOrder(order); err != nil {
+			fail(ColumnOrder, order, err.Error())
+		} else {
+			reading.SortOrder = n
 		}
+	}
 
-		date, dateType, err := ParseDate(dateStr)
-		if err != nil {
-			return nil, fmt.Errorf("row %d: %w", i+2, err)
+	if tags, _ := cols.cell(record, ColumnTags); tags != "" {
+		if parsed, err := parseTags(tags); err != nil {
+			fail(ColumnTags, tags, err.Error())
+		} else {
+			reading.Tags = parsed
 		}
+	}
 
-		reading := model.Reading{
-			Date:     date,
-			DateType: dateType,
-			Content:  content,
-			Status:   model.StatusPending,
+	if duration, _ := cols.cell(record, ColumnDuration); duration != "" {
+		if n, err := parseDuration(duration); err != nil {
+			fail(ColumnDuration, duration, err.Error())
+		} else {
+			reading.DurationMinutes = n
 		}
+	}
 
-		readings = append(readings, reading)
+	if len(errs) > 0 {
+		return model.Reading{}, errs
 	}
+	return reading, nil
+}
 
-	return readings, nil
+// Upper bounds for relative date cells, roughly ten years either way.
+const (
+	MaxRelativeDay  = 3660
+	MaxRelativeWeek = 520
+)
+
+// ParseRelative recognises "Day n" and "Week n" cells, which schedule a
+// reading relative to the plan's start rather than on a calendar date. ok
+// reports whether dateStr is in a relative format at all; if it is, err
+// describes any problem with n. The returned offset is zero-based, so
+// "Day 1" and "Week 1" are both 0.
+func ParseRelative(dateStr string) (offset int, dateType model.DateType, ok bool, err error) {
+	var unit string
+	var limit int
+	switch {
+	case strings.HasPrefix(dateStr, "Day "):
+		unit, limit, dateType = "Day", MaxRelativeDay, model.DateTypeDay
+	case strings.HasPrefix(dateStr, "Week "):
+		unit, limit, dateType = "Week", MaxRelativeWeek, model.DateTypeWeek
+	default:
+		return 0, "", false, nil
+	}
+
+	n, convErr := strconv.Atoi(strings.TrimSpace(dateStr[len(unit)+1:]))
+	if convErr != nil {
+		return 0, dateType, true, fmt.Errorf("invalid %s format: %s (expected '%s n')", strings.ToLower(unit), dateStr, unit)
+	}
+	if n < 1 || n > limit {
+		return 0, dateType, true, fmt.Errorf("%s number must be between 1 and %d, got %d", strings.ToLower(unit), limit, n)
+	}
+	return n - 1, dateType, true, nil
+}
+
+// ResolveDate is [ParseDate] for callers that store readings: alongside the
+// date it returns the offset from start for relative cells, or nil for
+// calendar dates, to be kept in [model.Reading.StartOffset].
+func ResolveDate(dateStr string, start time.Time) (time.Time, model.DateType, *int, error) {
+	if offset, dateType, ok, err := ParseRelative(dateStr); ok {
+		if err != nil {
+			return time.Time{}, "", nil, err
+		}
+		return model.RelativeDate(start, dateType, offset), dateType, &offset, nil
+	}
+	date, dateType, err := ParseDate(dateStr, start)
+	return date, dateType, nil, err
 }
 
-// ParseDate parses a CSV date cell, returning the date and the cadence
-// ([model.DateType]) it represents (day, week, or month).
-func ParseDate(dateStr string) (time.Time, model.DateType, error) {
-	if strings.HasPrefix(dateStr, "Week ") {
-		return parseWeekFormat(dateStr)
+// ParseDate parses a date cell, returning the date and the cadence
+// ([model.DateType]) it represents (day, week, or month). Relative "Day n"
+// and "Week n" cells are resolved against start; see [ParseRelative].
+func ParseDate(dateStr string, start time.Time) (time.Time, model.DateType, error) {
+	if offset, dateType, ok, err := ParseRelative(dateStr); ok {
+		if err != nil {
+			return time.Time{}, "", err
+		}
+		return model.RelativeDate(start, dateType, offset), dateType, nil
 	}
 
 	if len(dateStr) >= 8 && dateStr[4] == '-' && dateStr[5] == 'W' {
@@ -129,7 +267,7 @@ func ParseDate(dateStr string) (time.Time, model.DateType, error) {
 		}
 	}
 
-	return time.Time{}, "", fmt.Errorf("invalid date format: %s (supported: YYYY-MM-DD, MM/DD/YYYY, Month YYYY, YYYY-MM, YYYY-Wnn, Week n)", dateStr)
+	return time.Time{}, "", fmt.Errorf("invalid date format: %s (supported: YYYY-MM-DD, MM/DD/YYYY, Month YYYY, YYYY-MM, YYYY-Wnn, Day n, Week n)", dateStr)
 }
 
 func parseISOWeek(dateStr string) (time.Time, model.DateType, error) {
@@ -151,20 +289,11 @@ func parseISOWeek(dateStr string) (time.Time, model.DateType, error) {
 	mondayOfWeek1 := jan4.AddDate(0, 0, 1-weekday)
 	weekStart := mondayOfWeek1.AddDate(0, 0, 7*(week-1))
 
-	return weekStart, model.DateTypeWeek, nil
-}
-
-func parseWeekFormat(dateStr string) (time.Time, model.DateType, error) {
-	var week int
-	_, err := fmt.Sscanf(dateStr, "Week %d", &week)
-	if err != nil {
-		return time.Time{}, "", fmt.Errorf("invalid week format: %s (expected 'Week n')", dateStr)
+	// Only some years have a week 53; reject it rather than rolling over
+	// into week 1 of the following year.
+	if y, w := weekStart.ISOWeek(); y != year || w != week {
+		return time.Time{}, "", fmt.Errorf("%d has no ISO week %d", year, week)
 	}
 
-	if week < 1 || week > 53 {
-		return time.Time{}, "", fmt.Errorf("week number must be between 1 and 53, got %d", week)
-	}
-
-	currentYear := time.Now().Year()
-	return parseISOWeek(fmt.Sprintf("%d-W%02d", currentYear, week))
+	return weekStart, model.DateTypeWeek, nil
 }
@@ -1,12 +1,18 @@
 package csvservice
 
 import (
+	"errors"
 	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"readwillbe/internal/model"
 )
 
+// testNow pins the reference time for formats that depend on the current year.
+var testNow = time.Date(2025, time.June, 15, 12, 0, 0, 0, time.UTC)
+
 func TestParseCSV(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -55,7 +61,7 @@ invalid-date,Read Chapter 1`,
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			r := strings.NewReader(tt.input)
-			readings, err := ParseCSV(r)
+			readings, err := ParseCSV(r, testNow)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ParseCSV() error = %v, wantErr %v", err, tt.wantErr)
@@ -130,6 +136,17 @@ func TestParseDate(t *testing.T) {
 			wantDateType: model.DateTypeWeek,
 			wantErr:      false,
 		},
+		{
+			name:         "Day format",
+			dateStr:      "Day 12",
+			wantDateType: model.DateTypeDay,
+			wantErr:      false,
+		},
+		{
+			name:    "Day format without a number",
+			dateStr: "Day twelve",
+			wantErr: true,
+		},
 		{
 			name:    "invalid format",
 			dateStr: "not-a-date",
@@ -144,7 +161,7 @@ func TestParseDate(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			date, dateType, err := ParseDate(tt.dateStr)
+			date, dateType, err := ParseDate(tt.dateStr, testNow)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ParseDate() error = %v, wantErr %v", err, tt.wantErr)
@@ -162,3 +179,352 @@ func TestParseDate(t *testing.T) {
 		})
 	}
 }
+
+func TestParseDate_Boundaries(t *testing.T) {
+	tests := []struct {
+		name    string
+		dateStr string
+		start   time.Time
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name:    "ISO week 53 in a long year",
+			dateStr: "2020-W53",
+			want:    time.Date(2020, time.December, 28, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "ISO week 53 in a short year",
+			dateStr: "2025-W53",
+			wantErr: true,
+		},
+		{
+			name:    "ISO week 1 starting in previous year",
+			dateStr: "2025-W01",
+			want:    time.Date(2024, time.December, 30, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "Week 1 starts on the Monday of the start's week",
+			dateStr: "Week 1",
+			start:   time.Date(2026, time.December, 31, 23, 0, 0, 0, time.UTC),
+			want:    time.Date(2026, time.December, 28, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "Week n runs past the end of the year",
+			dateStr: "Week 53",
+			start:   time.Date(2025, time.December, 31, 23, 0, 0, 0, time.UTC),
+			want:    time.Date(2026, time.December, 28, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "Day n counts across a leap day",
+			dateStr: "Day 60",
+			start:   time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+			want:    time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "Day 0 rejected",
+			dateStr: "Day 0",
+			wantErr: true,
+		},
+		{
+			name:    "leap day",
+			dateStr: "2024-02-29",
+			want:    time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "leap day in a common year",
+			dateStr: "2025-02-29",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := tt.start
+			if start.IsZero() {
+				start = testNow
+			}
+
+			got, _, err := ParseDate(tt.dateStr, start)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Errorf("ParseDate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviewCSV_ReportsEveryRow(t *testing.T) {
+	input := `date,reading
+2025-01-15,Read Chapter 1
+not-a-date,Read Chapter 2
+2025-W03,=SUM(A1)
+2025-02
+Week 5,Read Chapter 5`
+
+	preview, err := PreviewCSV(strings.NewReader(input), testNow)
+	if err != nil {
+		t.Fatalf("PreviewCSV() error = %v", err)
+	}
+
+	if len(preview.Rows) != 5 {
+		t.Fatalf("PreviewCSV() returned %d rows, want 5", len(preview.Rows))
+	}
+	if got := preview.ErrorCount(); got != 3 {
+		t.Errorf("ErrorCount() = %d, want 3", got)
+	}
+	if preview.Valid() {
+		t.Error("Valid() = true, want false")
+	}
+
+	wantErrLines := map[int]bool{3: true, 4: true, 5: true}
+	for _, row := range preview.Rows {
+		if (len(row.Errors) > 0) != wantErrLines[row.Line] {
+			t.Errorf("line %d: Errors = %v, want error %v", row.Line, row.Errors, wantErrLines[row.Line])
+		}
+	}
+
+	last := preview.Rows[4]
+	if last.Reading.DateType != model.DateTypeWeek || last.Reading.Date.Year() != 2025 {
+		t.Errorf("line 6: got %v %s, want a 2025 week", last.Reading.Date, last.Reading.DateType)
+	}
+	if preview.Rows[1].Reading.Content != "Read Chapter 2" {
+		t.Errorf("line 3: content = %q, want it kept alongside the error", preview.Rows[1].Reading.Content)
+	}
+}
+
+func TestParseCSV_CollectsAllRowErrors(t *testing.T) {
+	input := `date,reading
+2025-01-15,Read Chapter 1
+2025-13-01,Read Chapter 2
+,=cmd|' /C calc'!A0
+2025-01-18,
+2025-01-19,Read Chapter 5`
+
+	_, err := ParseCSV(strings.NewReader(input), testNow)
+
+	var rowErrs RowErrors
+	if !errors.As(err, &rowErrs) {
+		t.Fatalf("ParseCSV() error = %v, want RowErrors", err)
+	}
+
+	want := []RowError{
+		{Row: 3, Column: ColumnDate, Value: "2025-13-01"},
+		{Row: 4, Column: ColumnDate, Value: ""},
+		{Row: 4, Column: ColumnReading, Value: "=cmd|' /C calc'!A0"},
+		{Row: 5, Column: ColumnReading, Value: ""},
+	}
+	if len(rowErrs) != len(want) {
+		t.Fatalf("got %d errors, want %d: %v", len(rowErrs), len(want), rowErrs)
+	}
+	for i, w := range want {
+		got := rowErrs[i]
+		if got.Row != w.Row || got.Column != w.Column || got.Value != w.Value || got.Reason == "" {
+			t.Errorf("error %d = %+v, want row %d column %s value %q", i, got, w.Row, w.Column, w.Value)
+		}
+	}
+
+	msg := rowErrs.Error()
+	if !strings.HasPrefix(msg, "4 problems found\nrow 3, date \"2025-13-01\": ") {
+		t.Errorf("Error() = %q, want summary line then one line per problem", msg)
+	}
+	if !strings.Contains(msg, "\nrow 4, date: date is required") {
+		t.Errorf("Error() = %q, want empty cells reported without a value", msg)
+	}
+}
+
+func TestParseCSV_TruncatesLongValuesByCharacter(t *testing.T) {
+	content := strings.Repeat("é", MaxContentLength)
+	input := "date,reading\n2025-01-15," + content
+
+	_, err := ParseCSV(strings.NewReader(input), testNow)
+
+	var rowErrs RowErrors
+	if !errors.As(err, &rowErrs) || len(rowErrs) != 1 {
+		t.Fatalf("ParseCSV() error = %v, want one RowError", err)
+	}
+	want := strings.Repeat("é", MaxReportedValueLength) + "..."
+	if rowErrs[0].Value != want {
+		t.Errorf("Value = %q, want %q", rowErrs[0].Value, want)
+	}
+	if !utf8.ValidString(rowErrs[0].Error()) {
+		t.Errorf("Error() = %q, want valid UTF-8", rowErrs[0].Error())
+	}
+}
+
+func TestParseCSV_TruncatesLongNotesByCharacter(t *testing.T) {
+	notes := strings.Repeat("ü", MaxContentLength)
+	input := "date,reading,notes\n2025-01-15,Genesis 1," + notes
+
+	_, err := ParseCSV(strings.NewReader(input), testNow)
+
+	var rowErrs RowErrors
+	if !errors.As(err, &rowErrs) || len(rowErrs) != 1 {
+		t.Fatalf("ParseCSV() error = %v, want one RowError", err)
+	}
+	if got := rowErrs[0]; got.Column != ColumnNotes || !utf8.ValidString(got.Value) {
+		t.Errorf("error = %+v, want a valid UTF-8 notes value", got)
+	}
+}
+
+func TestRowErrors_CapsReportedErrors(t *testing.T) {
+	errs := make(RowErrors, MaxReportedErrors+5)
+	for i := range errs {
+		errs[i] = RowError{Row: i + 2, Column: ColumnDate, Reason: "date is required"}
+	}
+
+	lines := strings.Split(errs.Error(), "\n")
+	if len(lines) != MaxReportedErrors+2 {
+		t.Fatalf("Error() has %d lines, want %d", len(lines), MaxReportedErrors+2)
+	}
+	if lines[len(lines)-1] != "... and 5 more" {
+		t.Errorf("last line = %q, want the overflow count", lines[len(lines)-1])
+	}
+}
+
+func TestParseCSV_MatchesColumnsByHeader(t *testing.T) {
+	input := "\uFEFFExport ID,Tags,Reading,Notes,Duration Minutes,Date,URL,Order\n" +
+		`17,"law; history",Exodus 2,Second,20,2025-01-15,https://example.com/ex2,2
+16,law,Exodus 1,First,15,2025-01-15,,1
+18,,Exodus 3,,,2025-01-14,,`
+
+	readings, err := ParseCSV(strings.NewReader(input), testNow)
+	if err != nil {
+		t.Fatalf("ParseCSV() error = %v", err)
+	}
+	if len(readings) != 3 {
+		t.Fatalf("ParseCSV() returned %d readings, want 3", len(readings))
+	}
+
+	// Sorted by date, then by the order column.
+	gotContent := []string{readings[0].Content, readings[1].Content, readings[2].Content}
+	wantContent := []string{"Exodus 3", "Exodus 1", "Exodus 2"}
+	for i := range wantContent {
+		if gotContent[i] != wantContent[i] {
+			t.Fatalf("content order = %v, want %v", gotContent, wantContent)
+		}
+	}
+
+	r := readings[2]
+	if r.Notes != "Second" || r.Link != "https://example.com/ex2" || r.SortOrder != 2 ||
+		r.Tags != "law,history" || r.DurationMinutes != 20 {
+		t.Errorf("extra columns = %+v", r)
+	}
+	if r.Date != time.Date(2025, time.January, 15, 0, 0, 0, 0, time.UTC) || r.DateType != model.DateTypeDay {
+		t.Errorf("date = %v %s, want 2025-01-15 day", r.Date, r.DateType)
+	}
+}
+
+func TestParseCSV_HeaderErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		wantErr string
+	}{
+		{"missing reading column", "date,notes", "no reading column"},
+		{"missing date column", "passage,notes", "no date column"},
+		{"duplicate column", "date,reading,content", "more than one reading column"},
+		{"leading ID column", "ID,Date,Description", "no reading column; name it one of: assignment, content, passage, reading, readings"},
+		{"ambiguous date column", "Start,Passage,Notes,Source", "no date column; name it one of: date, day, due, scheduled, when"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCSV(strings.NewReader(tt.header+"\n2025-01-15,a,b"), testNow)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseCSV() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseCSV_UnknownHeaderFallsBackToPosition(t *testing.T) {
+	readings, err := ParseCSV(strings.NewReader("When to read,What\n2025-01-15,Genesis 1"), testNow)
+	if err != nil {
+		t.Fatalf("ParseCSV() error = %v", err)
+	}
+	if len(readings) != 1 || readings[0].Content != "Genesis 1" {
+		t.Errorf("ParseCSV() = %+v, want one reading of Genesis 1", readings)
+	}
+}
+
+func TestParseCSV_OneKnownHeaderTakesOnlyUnmatchedColumn(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"date known", "Date,Description\n2025-01-15,Genesis 1"},
+		{"reading known", "Text,Reading\n2025-01-15,Genesis 1"},
+		{"date alias known", "Day,Text,Notes\n2025-01-15,Genesis 1,skim"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readings, err := ParseCSV(strings.NewReader(tt.input), testNow)
+			if err != nil {
+				t.Fatalf("ParseCSV() error = %v", err)
+			}
+			if len(readings) != 1 || readings[0].Content != "Genesis 1" || readings[0].Date.Day() != 15 {
+				t.Errorf("ParseCSV() = %+v, want one reading of Genesis 1 on the 15th", readings)
+			}
+		})
+	}
+}
+
+func TestParseCSV_InvalidExtraColumns(t *testing.T) {
+	input := `date,reading,link,order,duration_minutes,tags
+2025-01-15,Genesis 1,javascript:alert(1),first,-5,=evil`
+
+	_, err := ParseCSV(strings.NewReader(input), testNow)
+
+	var rowErrs RowErrors
+	if !errors.As(err, &rowErrs) {
+		t.Fatalf("ParseCSV() error = %v, want RowErrors", err)
+	}
+
+	var columns []string
+	for _, e := range rowErrs {
+		columns = append(columns, e.Column)
+	}
+	want := []string{ColumnLink, ColumnOrder, ColumnTags, ColumnDuration}
+	if strings.Join(columns, " ") != strings.Join(want, " ") {
+		t.Errorf("error columns = %v, want %v", columns, want)
+	}
+}
+
+func TestParseCSV_RelativeDates(t *testing.T) {
+	start := time.Date(2026, time.March, 4, 0, 0, 0, 0, time.UTC) // a Wednesday
+	input := "date,reading\nDay 1,Genesis 1\nDay 12,Genesis 12\nWeek 3,Exodus\n2026-03-05,Psalm 1"
+
+	readings, err := ParseCSV(strings.NewReader(input), start)
+	if err != nil {
+		t.Fatalf("ParseCSV() error = %v", err)
+	}
+	if len(readings) != 4 {
+		t.Fatalf("ParseCSV() returned %d readings, want 4", len(readings))
+	}
+
+	want := []struct {
+		content string
+		date    time.Time
+		offset  *int
+	}{
+		{"Genesis 1", start, intPtr(0)},
+		{"Psalm 1", time.Date(2026, time.March, 5, 0, 0, 0, 0, time.UTC), nil},
+		{"Genesis 12", time.Date(2026, time.March, 15, 0, 0, 0, 0, time.UTC), intPtr(11)},
+		{"Exodus", time.Date(2026, time.March, 16, 0, 0, 0, 0, time.UTC), intPtr(2)},
+	}
+	for i, w := range want {
+		got := readings[i]
+		if got.Content != w.content || !got.Date.Equal(w.date) {
+			t.Errorf("reading %d = %s on %v, want %s on %v", i, got.Content, got.Date, w.content, w.date)
+		}
+		if (got.StartOffset == nil) != (w.offset == nil) || (w.offset != nil && *got.StartOffset != *w.offset) {
+			t.Errorf("reading %d (%s) StartOffset = %v, want %v", i, got.Content, got.StartOffset, w.offset)
+		}
+	}
+}
+
+func intPtr(n int) *int { return &n }
@@ -0,0 +1,67 @@
+package csvservice
+
+import (
+	"io"
+	"time"
+
+	"readwillbe/internal/model"
+)
+
+// PreviewRow is one data row of an uploaded CSV as it would be imported.
+// Errors is empty when the row is valid, in which case Reading holds the
+// resolved date, date type and content.
+type PreviewRow struct {
+	Line    int // 1-based line in the file, counting the header
+	RawDate string
+	Reading model.Reading
+	Errors  []RowError
+}
+
+// Preview is the result of a dry run of [ParseCSV].
+type Preview struct {
+	Rows []PreviewRow
+}
+
+// Valid reports whether every row parsed, so the file can be imported.
+func (p Preview) Valid() bool {
+	return p.ErrorCount() == 0 && len(p.Rows) > 0
+}
+
+// ErrorCount returns the number of rows with at least one error.
+func (p Preview) ErrorCount() int {
+	n := 0
+	for _, row := range p.Rows {
+		if len(row.Errors) > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// PreviewCSV parses r like [ParseCSV] but returns every data row, valid or
+// not, with either its reading or its errors.
+// The returned error is non-nil only when the file as a whole is unusable,
+// such as malformed quoting or a missing header.
+func PreviewCSV(r io.Reader, start time.Time) (Preview, error) {
+	cols, rows, err := readRecords(r)
+	if err != nil {
+		return Preview{}, err
+	}
+
+	preview := Preview{Rows: make([]PreviewRow, 0, len(rows))}
+	for i, record := range rows {
+		row := PreviewRow{Line: i + 2}
+		row.RawDate, _ = cols.cell(record, ColumnDate)
+
+		reading, errs := parseRecord(row.Line, record, cols, start)
+		if len(errs) > 0 {
+			row.Errors = errs
+			row.Reading.Content, _ = cols.cell(record, ColumnReading)
+		} else {
+			row.Reading = reading
+		}
+		preview.Rows = append(preview.Rows, row)
+	}
+
+	return preview, nil
+}
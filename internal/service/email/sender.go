@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"readwillbe/internal/model"
 
@@ -15,8 +16,10 @@ import (
 
 // Service is implemented by every supported email backend.
 type Service interface {
-	SendDailyDigest(user model.User, readings []model.Reading, hostname string) error
+	SendDailyDigest(user model.User, readings []model.Reading, now time.Time, hostname string) error
 	SendTestEmail(to, hostname string) error
+	SendPasswordReset(user model.User, resetURL string) error
+	SendEmailVerification(user model.User, to, verifyURL string) error
 }
 
 // NewService returns the email service implementation matching cfg.EmailProvider,
@@ -37,9 +40,10 @@ type SMTPService struct {
 	cfg model.Config
 }
 
-// SendDailyDigest renders and sends the daily reading digest for user.
-func (s *SMTPService) SendDailyDigest(user model.User, readings []model.Reading, hostname string) error {
-	html, text := RenderDailyDigestEmail(user, readings, hostname)
+// SendDailyDigest renders and sends the daily reading digest for user. now
+// is the current time in the user's timezone.
+func (s *SMTPService) SendDailyDigest(user model.User, readings []model.Reading, now time.Time, hostname string) error {
+	html, text := RenderDailyDigestEmail(user, readings, now, hostname)
 	return s.send(user.GetNotificationEmail(), "Your readings for today", html, text)
 }
 
@@ -49,6 +53,20 @@ func (s *SMTPService) SendTestEmail(to string, _ string) error {
 	return s.send(to, "Test Email from ReadWillBe", html, text)
 }
 
+// SendPasswordReset sends user a link to choose a new password. It goes to
+// the account email, never the notification address.
+func (s *SMTPService) SendPasswordReset(user model.User, resetURL string) error {
+	html, text := RenderPasswordResetEmail(user, resetURL)
+	return s.send(user.Email, "Reset your ReadWillBe password", html, text)
+}
+
+// SendEmailVerification sends a link confirming that the address to
+// belongs to user.
+func (s *SMTPService) SendEmailVerification(user model.User, to, verifyURL string) error {
+	html, text := RenderEmailVerificationEmail(user, to, verifyURL)
+	return s.send(to, "Confirm your email address for ReadWillBe", html, text)
+}
+
 func (s *SMTPService) send(to, subject, htmlBody, textBody string) error {
 	m := mail.NewMsg()
 	if err := m.From(s.cfg.SMTPFrom); err != nil {
@@ -97,9 +115,10 @@ type ResendService struct {
 	cfg model.Config
 }
 
-// SendDailyDigest renders and sends the daily reading digest for user.
-func (r *ResendService) SendDailyDigest(user model.User, readings []model.Reading, hostname string) error {
-	html, text := RenderDailyDigestEmail(user, readings, hostname)
+// SendDailyDigest renders and sends the daily reading digest for user. now
+// is the current time in the user's timezone.
+func (r *ResendService) SendDailyDigest(user model.User, readings []model.Reading, now time.Time, hostname string) error {
+	html, text := RenderDailyDigestEmail(user, readings, now, hostname)
 	return r.send(user.GetNotificationEmail(), "Your readings for today", html, text)
 }
 
@@ -109,6 +128,20 @@ func (r *ResendService) SendTestEmail(to string, _ string) error {
 	return r.send(to, "Test Email from ReadWillBe", html, text)
 }
 
+// SendPasswordReset sends user a link to choose a new password. It goes to
+// the account email, never the notification address.
+func (r *ResendService) SendPasswordReset(user model.User, resetURL string) error {
+	html, text := RenderPasswordResetEmail(user, resetURL)
+	return r.send(user.Email, "Reset your ReadWillBe password", html, text)
+}
+
+// SendEmailVerification sends a link confirming that the address to
+// belongs to user.
+func (r *ResendService) SendEmailVerification(user model.User, to, verifyURL string) error {
+	html, text := RenderEmailVerificationEmail(user, to, verifyURL)
+	return r.send(to, "Confirm your email address for ReadWillBe", html, text)
+}
+
 func (r *ResendService) send(to, subject, htmlBody, textBody string) error {
 	payload := fmt.Sprintf(`{
 		"from": %q,
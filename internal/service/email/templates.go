@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"html/template"
+	"time"
 
 	"readwillbe/internal/model"
 )
@@ -173,6 +174,97 @@ Email Configuration Working!
 
 Your email notifications are configured correctly.`
 
+const passwordResetHTMLTemplate = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+</head>
+<body style="margin: 0; padding: 40px; background-color: #faf8f5; font-family: Georgia, serif;">
+  <table role="presentation" width="100%" cellspacing="0" cellpadding="0" style="max-width: 600px; margin: 0 auto;">
+    <tr>
+      <td align="center" style="padding-bottom: 24px;">
+        <h1 style="margin: 0; font-size: 32px; color: #3d3730;">ReadWillBe</h1>
+      </td>
+    </tr>
+    <tr>
+      <td style="background-color: #f0ede8; border-radius: 12px; padding: 32px;">
+        <p style="margin: 0 0 16px 0; color: #3d3730; font-size: 16px;">
+          Hi {{.UserName}}, we received a request to reset your password.
+        </p>
+        <p style="margin: 0 0 24px 0; text-align: center;">
+          <a href="{{.ResetURL}}"
+             style="display: inline-block; background-color: #4a8c4a; color: white;
+                    text-decoration: none; padding: 12px 32px; border-radius: 8px;
+                    font-size: 16px; font-weight: bold;">
+            Choose a New Password
+          </a>
+        </p>
+        <p style="margin: 0; color: #6b6560; font-size: 14px;">
+          This link expires in {{.ValidFor}} and can only be used once. If you
+          didn't ask to reset your password, you can ignore this email.
+        </p>
+      </td>
+    </tr>
+  </table>
+</body>
+</html>`
+
+const passwordResetTextTemplate = `ReadWillBe - Reset Your Password
+
+Hi {{.UserName}},
+
+We received a request to reset your password. Choose a new one here:
+
+{{.ResetURL}}
+
+This link expires in {{.ValidFor}} and can only be used once. If you didn't ask to reset your password, you can ignore this email.`
+
+const emailVerificationHTMLTemplate = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+</head>
+<body style="margin: 0; padding: 40px; background-color: #faf8f5; font-family: Georgia, serif;">
+  <table role="presentation" width="100%" cellspacing="0" cellpadding="0" style="max-width: 600px; margin: 0 auto;">
+    <tr>
+      <td align="center" style="padding-bottom: 24px;">
+        <h1 style="margin: 0; font-size: 32px; color: #3d3730;">ReadWillBe</h1>
+      </td>
+    </tr>
+    <tr>
+      <td style="background-color: #f0ede8; border-radius: 12px; padding: 32px;">
+        <p style="margin: 0 0 16px 0; color: #3d3730; font-size: 16px;">
+          Hi {{.UserName}}, please confirm that {{.Address}} is your email address
+          so we can send your ReadWillBe reminders there.
+        </p>
+        <p style="margin: 0 0 24px 0; text-align: center;">
+          <a href="{{.VerifyURL}}"
+             style="display: inline-block; background-color: #4a8c4a; color: white;
+                    text-decoration: none; padding: 12px 32px; border-radius: 8px;
+                    font-size: 16px; font-weight: bold;">
+            Confirm Email Address
+          </a>
+        </p>
+        <p style="margin: 0; color: #6b6560; font-size: 14px;">
+          This link expires in {{.ValidFor}}. If you didn't sign up for
+          ReadWillBe or add this address, you can ignore this email.
+        </p>
+      </td>
+    </tr>
+  </table>
+</body>
+</html>`
+
+const emailVerificationTextTemplate = `ReadWillBe - Confirm Your Email Address
+
+Hi {{.UserName}},
+
+Please confirm that {{.Address}} is your email address so we can send your ReadWillBe reminders there:
+
+{{.VerifyURL}}
+
+This link expires in {{.ValidFor}}. If you didn't sign up for ReadWillBe or add this address, you can ignore this email.`
+
 type emailReading struct {
 	PlanTitle     string
 	Content       string
@@ -190,8 +282,8 @@ type dailyDigestData struct {
 }
 
 // RenderDailyDigestEmail returns the HTML and plain-text bodies for the
-// daily reading digest email.
-func RenderDailyDigestEmail(user model.User, readings []model.Reading, hostname string) (html, text string) {
+// daily reading digest email, judging overdue readings as of now.
+func RenderDailyDigestEmail(user model.User, readings []model.Reading, now time.Time, hostname string) (html, text string) {
 	data := dailyDigestData{
 		UserName:     user.Name,
 		DashboardURL: fmt.Sprintf("https://%s/dashboard", hostname),
@@ -199,7 +291,7 @@ func RenderDailyDigestEmail(user model.User, readings []model.Reading, hostname
 	}
 
 	for _, r := range readings {
-		isOverdue := r.IsOverdue()
+		isOverdue := r.IsOverdue(now)
 		if isOverdue {
 			data.HasOverdue = true
 			data.OverdueCount++
@@ -222,6 +314,49 @@ func RenderDailyDigestEmail(user model.User, readings []model.Reading, hostname
 	return htmlBuf.String(), textBuf.String()
 }
 
+type passwordResetData struct {
+	UserName string
+	ResetURL string
+	ValidFor string
+}
+
+// RenderPasswordResetEmail returns the HTML and plain-text bodies for the
+// password reset email.
+func RenderPasswordResetEmail(user model.User, resetURL string) (html, text string) {
+	data := passwordResetData{UserName: user.Name, ResetURL: resetURL, ValidFor: "1 hour"}
+
+	htmlTmpl := template.Must(template.New("html").Parse(passwordResetHTMLTemplate))
+	textTmpl := template.Must(template.New("text").Parse(passwordResetTextTemplate))
+
+	var htmlBuf, textBuf bytes.Buffer
+	_ = htmlTmpl.Execute(&htmlBuf, data)
+	_ = textTmpl.Execute(&textBuf, data)
+
+	return htmlBuf.String(), textBuf.String()
+}
+
+type emailVerificationData struct {
+	UserName  string
+	Address   string
+	VerifyURL string
+	ValidFor  string
+}
+
+// RenderEmailVerificationEmail returns the HTML and plain-text bodies for the
+// email asking the owner of address to confirm it.
+func RenderEmailVerificationEmail(user model.User, address, verifyURL string) (html, text string) {
+	data := emailVerificationData{UserName: user.Name, Address: address, VerifyURL: verifyURL, ValidFor: "2 days"}
+
+	htmlTmpl := template.Must(template.New("html").Parse(emailVerificationHTMLTemplate))
+	textTmpl := template.Must(template.New("text").Parse(emailVerificationTextTemplate))
+
+	var htmlBuf, textBuf bytes.Buffer
+	_ = htmlTmpl.Execute(&htmlBuf, data)
+	_ = textTmpl.Execute(&textBuf, data)
+
+	return htmlBuf.String(), textBuf.String()
+}
+
 // RenderTestEmail returns the HTML and plain-text bodies for the test email.
 func RenderTestEmail() (html, text string) {
 	return testEmailHTMLTemplate, testEmailTextTemplate
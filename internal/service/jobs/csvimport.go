@@ -0,0 +1,113 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"readwillbe/internal/model"
+	csvservice "readwillbe/internal/service/csv"
+)
+
+// TypeCSVImport parses an uploaded CSV into readings for a plan. The raw CSV
+// is the job payload, so an import survives a restart without relying on a
+// temp file.
+const TypeCSVImport = "csv_import"
+
+// PlanInterruptedMessage is shown on plans whose import was lost.
+const PlanInterruptedMessage = "Import was interrupted; please upload the CSV again"
+
+// EnqueueCSVImport schedules plan, which must be in the processing state, to
+// be filled from data. The job is written through tx; call [Queue.Notify]
+// once it commits.
+func EnqueueCSVImport(tx *gorm.DB, q *Queue, plan model.Plan, data []byte) (model.Job, error) {
+	return q.EnqueueTx(tx, model.Job{
+		Type:    TypeCSVImport,
+		UserID:  plan.UserID,
+		PlanID:  plan.ID,
+		Payload: data,
+	})
+}
+
+// CSVImportHandler returns the handler for [TypeCSVImport] jobs. Parse errors
+// fail the job immediately; database errors are retried.
+func CSVImportHandler() Handler {
+	return Handler{
+		Run:    runCSVImport,
+		Failed: failCSVImport,
+	}
+}
+
+func runCSVImport(_ context.Context, db *gorm.DB, job model.Job) error {
+	var plan model.Plan
+	err := db.Where("id = ? AND user_id = ?", job.PlanID, job.UserID).Limit(1).Find(&plan).Error
+	if err != nil {
+		return errors.Wrap(err, "loading plan")
+	}
+	if plan.ID == 0 || plan.Status != "processing" {
+		// Deleted or already handled while the job was queued.
+		return nil
+	}
+
+	var user model.User
+	if err := db.First(&user, job.UserID).Error; err != nil {
+		return errors.Wrap(err, "loading user")
+	}
+
+	// Relative dates count from the start chosen at upload. Plans queued
+	// before start dates existed fall back to the upload day, so retries
+	// produce the same dates either way.
+	start := job.CreatedAt.In(user.Location())
+	if plan.StartDate != nil {
+		start = *plan.StartDate
+	}
+	readings, err := csvservice.ParseCSV(bytes.NewReader(job.Payload), start)
+	if err != nil {
+		return Permanent(errors.Wrap(err, "Failed to parse CSV"))
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for i := range readings {
+			readings[i].PlanID = plan.ID
+		}
+		if len(readings) > 0 {
+			if err := tx.Create(&readings).Error; err != nil {
+				return errors.Wrap(err, "saving readings")
+			}
+		}
+		return tx.Model(&plan).Update("status", "active").Error
+	})
+}
+
+func failCSVImport(db *gorm.DB, job model.Job, err error) {
+	res := db.Model(&model.Plan{}).
+		Where("id = ? AND status = ?", job.PlanID, "processing").
+		Updates(map[string]interface{}{
+			"status":        "failed",
+			"error_message": err.Error(),
+		})
+	if res.Error != nil {
+		logrus.Errorf("Error marking plan %d failed: %v", job.PlanID, res.Error)
+	}
+}
+
+// RecoverPlans fails plans left in the processing state with no pending or
+// running job behind them, such as imports started by the old in-process
+// goroutine or whose job record was lost. It returns the number of plans
+// updated. Call it after [Queue.Start] has reclaimed interrupted jobs.
+func RecoverPlans(db *gorm.DB) (int64, error) {
+	active := db.Model(&model.Job{}).
+		Select("plan_id").
+		Where("status IN ?", []model.JobStatus{model.JobPending, model.JobRunning})
+
+	res := db.Model(&model.Plan{}).
+		Where("status = ? AND id NOT IN (?)", "processing", active).
+		Updates(map[string]interface{}{
+			"status":        "failed",
+			"error_message": PlanInterruptedMessage,
+		})
+	return res.RowsAffected, errors.Wrap(res.Error, "recovering stuck plans")
+}
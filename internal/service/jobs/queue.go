@@ -0,0 +1,364 @@
+// Package jobs runs persisted background jobs. Jobs are stored in the jobs
+// table, claimed by a pool of workers, retried with backoff on failure, and
+// reclaimed if the process running them dies.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"readwillbe/internal/clock"
+	"readwillbe/internal/model"
+)
+
+// Queue defaults.
+const (
+	// DefaultMaxAttempts is how many times a job runs before it is marked failed.
+	DefaultMaxAttempts = 3
+	// PollInterval is how often idle workers check for due jobs.
+	PollInterval = 2 * time.Second
+	// Lease is how long a job may stay running before another worker may
+	// assume its owner died and reclaim it.
+	Lease = 10 * time.Minute
+)
+
+// Handler processes jobs of one type.
+type Handler struct {
+	// Run performs the job. Returning an error schedules a retry unless the
+	// error is wrapped with [Permanent] or the job is out of attempts.
+	Run func(ctx context.Context, db *gorm.DB, job model.Job) error
+	// Failed, if set, is called once when the job is given up on.
+	Failed func(db *gorm.DB, job model.Job, err error)
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying.
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// Queue stores and dispatches jobs.
+type Queue struct {
+	db       *gorm.DB
+	clk      clock.Clock
+	id       string
+	handlers map[string]Handler
+	wake     chan struct{}
+	mu       sync.RWMutex
+}
+
+// NewQueue returns a Queue backed by db. Call [Queue.Register] for each job
+// type before [Queue.Start].
+func NewQueue(db *gorm.DB, clk clock.Clock) *Queue {
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	host, _ := os.Hostname()
+
+	return &Queue{
+		db:       db,
+		clk:      clk,
+		id:       fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(suffix)),
+		handlers: map[string]Handler{},
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Register installs the handler for jobType.
+func (q *Queue) Register(jobType string, h Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = h
+}
+
+// Enqueue persists a new pending job and wakes an idle worker.
+func (q *Queue) Enqueue(ctx context.Context, job model.Job) (model.Job, error) {
+	job, err := q.EnqueueTx(q.db.WithContext(ctx), job)
+	if err != nil {
+		return job, err
+	}
+	q.Notify()
+	return job, nil
+}
+
+// EnqueueTx persists a new pending job through tx so it commits or rolls
+// back with the caller's other writes. Call [Queue.Notify] after the
+// transaction commits.
+func (q *Queue) EnqueueTx(tx *gorm.DB, job model.Job) (model.Job, error) {
+	job.Status = model.JobPending
+	if job.MaxAttempts == 0 {
+		job.MaxAttempts = DefaultMaxAttempts
+	}
+	now := q.clk.Now().UTC()
+	if job.CreatedAt.IsZero() {
+		job.CreatedAt = now
+	}
+	if job.RunAfter.IsZero() {
+		job.RunAfter = now
+	}
+
+	if err := tx.Create(&job).Error; err != nil {
+		return job, errors.Wrap(err, "enqueueing job")
+	}
+	return job, nil
+}
+
+// Notify wakes an idle worker to look for due jobs.
+func (q *Queue) Notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start launches workers goroutines that process jobs until the returned
+// cancel function is called. Jobs left running by a previous process are
+// reclaimed first; reclaimAll should only be true when no other process can
+// be working the same queue, such as with SQLite.
+func (q *Queue) Start(workers int, reclaimAll bool) context.CancelFunc {
+	lease := Lease
+	if reclaimAll {
+		lease = 0
+	}
+	if n, err := q.RecoverStale(lease); err != nil {
+		logrus.Errorf("Error recovering interrupted jobs: %v", err)
+	} else if n > 0 {
+		logrus.Infof("Recovered %d interrupted job(s)", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+
+	logrus.Infof("Job queue started with %d worker(s)", workers)
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+func (q *Queue) work(ctx context.Context) {
+	ticker := time.NewTicker(PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			ran, err := q.RunNext(ctx)
+			if err != nil {
+				logrus.Errorf("Job queue error: %v", err)
+				break
+			}
+			if !ran || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-ticker.C:
+			if _, err := q.RecoverStale(Lease); err != nil {
+				logrus.Errorf("Error recovering stale jobs: %v", err)
+			}
+		}
+	}
+}
+
+// RunNext claims and runs the oldest due job, reporting whether there was
+// one.
+func (q *Queue) RunNext(ctx context.Context) (bool, error) {
+	job, ok, err := q.claim(ctx)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	q.mu.RLock()
+	h, found := q.handlers[job.Type]
+	q.mu.RUnlock()
+
+	var runErr error
+	if !found {
+		runErr = Permanent(errors.Errorf("no handler registered for job type %q", job.Type))
+	} else {
+		runErr = q.run(ctx, h, job)
+	}
+
+	return true, q.finish(h, job, runErr)
+}
+
+func (q *Queue) run(ctx context.Context, h Handler, job model.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = Permanent(errors.Errorf("panic: %v", r))
+		}
+	}()
+	return h.Run(ctx, q.db.WithContext(ctx), job)
+}
+
+// claim atomically moves the oldest due pending job to running. The status
+// guard on the UPDATE means concurrent workers, in this process or another
+// replica, cannot claim the same row.
+func (q *Queue) claim(ctx context.Context) (model.Job, bool, error) {
+	db := q.db.WithContext(ctx)
+	now := q.clk.Now().UTC()
+
+	for {
+		var job model.Job
+		err := db.Where("status = ? AND run_after <= ?", model.JobPending, now).
+			Order("run_after ASC, id ASC").
+			Limit(1).
+			Find(&job).Error
+		if err != nil {
+			return job, false, errors.Wrap(err, "finding due job")
+		}
+		if job.ID == 0 {
+			return job, false, nil
+		}
+
+		res := db.Model(&model.Job{}).
+			Where("id = ? AND status = ?", job.ID, model.JobPending).
+			Updates(map[string]interface{}{
+				"status":    model.JobRunning,
+				"attempts":  gorm.Expr("attempts + 1"),
+				"locked_at": now,
+				"locked_by": q.id,
+			})
+		if res.Error != nil {
+			return job, false, errors.Wrap(res.Error, "claiming job")
+		}
+		if res.RowsAffected == 0 {
+			// Another worker got there first.
+			continue
+		}
+
+		job.Status = model.JobRunning
+		job.Attempts++
+		job.LockedAt = &now
+		job.LockedBy = q.id
+		return job, true, nil
+	}
+}
+
+func (q *Queue) finish(h Handler, job model.Job, runErr error) error {
+	now := q.clk.Now().UTC()
+	updates := map[string]interface{}{
+		"locked_at": nil,
+		"locked_by": "",
+	}
+
+	var permanent permanentError
+	switch {
+	case runErr == nil:
+		updates["status"] = model.JobSucceeded
+		updates["finished_at"] = now
+		updates["last_error"] = ""
+		updates["payload"] = nil
+	case errors.As(runErr, &permanent) || job.Attempts >= job.MaxAttempts:
+		updates["status"] = model.JobFailed
+		updates["finished_at"] = now
+		updates["last_error"] = runErr.Error()
+		updates["payload"] = nil
+		logrus.Errorf("Job %d (%s) failed after %d attempt(s): %v", job.ID, job.Type, job.Attempts, runErr)
+	default:
+		updates["status"] = model.JobPending
+		updates["run_after"] = now.Add(backoff(job.Attempts))
+		updates["last_error"] = runErr.Error()
+		logrus.Warnf("Job %d (%s) attempt %d failed, will retry: %v", job.ID, job.Type, job.Attempts, runErr)
+	}
+
+	err := q.db.Model(&model.Job{}).
+		Where("id = ? AND locked_by = ?", job.ID, q.id).
+		Updates(updates).Error
+	if err != nil {
+		return errors.Wrapf(err, "recording result of job %d", job.ID)
+	}
+
+	if updates["status"] == model.JobFailed && h.Failed != nil {
+		h.Failed(q.db, job, runErr)
+	}
+	return nil
+}
+
+// RecoverStale returns running jobs whose lease started more than lease ago
+// to pending so they run again, or marks them failed if they have no
+// attempts left. It returns the number of jobs recovered.
+func (q *Queue) RecoverStale(lease time.Duration) (int64, error) {
+	now := q.clk.Now().UTC()
+	cutoff := now.Add(-lease)
+
+	var exhausted []model.Job
+	err := q.db.Where("status = ? AND locked_at <= ? AND attempts >= max_attempts", model.JobRunning, cutoff).
+		Find(&exhausted).Error
+	if err != nil {
+		return 0, errors.Wrap(err, "finding exhausted jobs")
+	}
+
+	interrupted := errors.New("interrupted on final attempt")
+	for _, job := range exhausted {
+		res := q.db.Model(&model.Job{}).
+			Where("id = ? AND status = ?", job.ID, model.JobRunning).
+			Updates(map[string]interface{}{
+				"status":      model.JobFailed,
+				"finished_at": now,
+				"last_error":  interrupted.Error(),
+				"payload":     nil,
+				"locked_at":   nil,
+				"locked_by":   "",
+			})
+		if res.Error != nil {
+			return 0, errors.Wrapf(res.Error, "failing exhausted job %d", job.ID)
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+
+		q.mu.RLock()
+		h := q.handlers[job.Type]
+		q.mu.RUnlock()
+		if h.Failed != nil {
+			h.Failed(q.db, job, interrupted)
+		}
+	}
+
+	res := q.db.Model(&model.Job{}).
+		Where("status = ? AND locked_at <= ?", model.JobRunning, cutoff).
+		Updates(map[string]interface{}{
+			"status":    model.JobPending,
+			"run_after": now,
+			"locked_at": nil,
+			"locked_by": "",
+		})
+	return res.RowsAffected, errors.Wrap(res.Error, "recovering stale jobs")
+}
+
+// backoff returns the delay before retrying a job that has failed attempts
+// times: 30s, 2m, 4m30s, ... capped at one hour.
+func backoff(attempts int) time.Duration {
+	d := time.Duration(attempts*attempts) * 30 * time.Second
+	if d > time.Hour {
+		return time.Hour
+	}
+	return d
+}
@@ -0,0 +1,189 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"readwillbe/internal/database/dbtest"
+	"readwillbe/internal/model"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type stepClock struct {
+	now time.Time
+}
+
+func (c *stepClock) Now() time.Time {
+	return c.now
+}
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db := dbtest.Open(t)
+
+	err := db.AutoMigrate(&model.User{}, &model.Plan{}, &model.Reading{}, &model.Job{})
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestQueue_RetriesUntilMaxAttempts(t *testing.T) {
+	db := setupTestDB(t)
+	clk := &stepClock{now: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	q := NewQueue(db, clk)
+
+	var failedCalls int
+	q.Register("flaky", Handler{
+		Run: func(context.Context, *gorm.DB, model.Job) error {
+			return errors.New("boom")
+		},
+		Failed: func(*gorm.DB, model.Job, error) { failedCalls++ },
+	})
+
+	job, err := q.Enqueue(context.Background(), model.Job{Type: "flaky", MaxAttempts: 2})
+	require.NoError(t, err)
+
+	ran, err := q.RunNext(context.Background())
+	require.NoError(t, err)
+	assert.True(t, ran)
+
+	require.NoError(t, db.First(&job, job.ID).Error)
+	assert.Equal(t, model.JobPending, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Equal(t, "boom", job.LastError)
+
+	// The retry is not due until the backoff has elapsed.
+	ran, err = q.RunNext(context.Background())
+	require.NoError(t, err)
+	assert.False(t, ran)
+
+	clk.now = clk.now.Add(backoff(1))
+	ran, err = q.RunNext(context.Background())
+	require.NoError(t, err)
+	assert.True(t, ran)
+
+	require.NoError(t, db.First(&job, job.ID).Error)
+	assert.Equal(t, model.JobFailed, job.Status)
+	assert.Equal(t, 2, job.Attempts)
+	assert.NotNil(t, job.FinishedAt)
+	assert.Equal(t, 1, failedCalls)
+}
+
+func TestQueue_PermanentErrorSkipsRetry(t *testing.T) {
+	db := setupTestDB(t)
+	q := NewQueue(db, &stepClock{now: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)})
+	q.Register("bad", Handler{
+		Run: func(context.Context, *gorm.DB, model.Job) error {
+			return Permanent(errors.New("bad input"))
+		},
+	})
+
+	job, err := q.Enqueue(context.Background(), model.Job{Type: "bad", Payload: []byte("data")})
+	require.NoError(t, err)
+
+	_, err = q.RunNext(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, db.First(&job, job.ID).Error)
+	assert.Equal(t, model.JobFailed, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Empty(t, job.Payload)
+}
+
+func TestQueue_RecoverStale(t *testing.T) {
+	db := setupTestDB(t)
+	clk := &stepClock{now: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	q := NewQueue(db, clk)
+
+	var failed []uint
+	q.Register("work", Handler{
+		Run:    func(context.Context, *gorm.DB, model.Job) error { return nil },
+		Failed: func(_ *gorm.DB, job model.Job, _ error) { failed = append(failed, job.ID) },
+	})
+
+	lockedAt := clk.now.Add(-time.Hour)
+	retryable := model.Job{Type: "work", Status: model.JobRunning, Attempts: 1, MaxAttempts: 3, LockedAt: &lockedAt, LockedBy: "dead"}
+	exhausted := model.Job{Type: "work", Status: model.JobRunning, Attempts: 3, MaxAttempts: 3, LockedAt: &lockedAt, LockedBy: "dead"}
+	fresh := model.Job{Type: "work", Status: model.JobRunning, Attempts: 1, MaxAttempts: 3, LockedAt: &clk.now, LockedBy: "alive"}
+	require.NoError(t, db.Create(&retryable).Error)
+	require.NoError(t, db.Create(&exhausted).Error)
+	require.NoError(t, db.Create(&fresh).Error)
+
+	n, err := q.RecoverStale(Lease)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	require.NoError(t, db.First(&retryable, retryable.ID).Error)
+	assert.Equal(t, model.JobPending, retryable.Status)
+	assert.Empty(t, retryable.LockedBy)
+
+	require.NoError(t, db.First(&exhausted, exhausted.ID).Error)
+	assert.Equal(t, model.JobFailed, exhausted.Status)
+	assert.Equal(t, []uint{exhausted.ID}, failed)
+
+	require.NoError(t, db.First(&fresh, fresh.ID).Error)
+	assert.Equal(t, model.JobRunning, fresh.Status)
+}
+
+func TestCSVImport_ResumesAfterRestart(t *testing.T) {
+	db := setupTestDB(t)
+	clk := &stepClock{now: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+
+	user := model.User{Email: "import@example.com", Name: "Importer"}
+	require.NoError(t, db.Create(&user).Error)
+	plan := model.Plan{Title: "Imported", UserID: user.ID, Status: "processing"}
+	require.NoError(t, db.Create(&plan).Error)
+
+	first := NewQueue(db, clk)
+	_, err := EnqueueCSVImport(db, first, plan, []byte("date,reading\n2026-03-01,Genesis 1\n2026-03-02,Genesis 2\n"))
+	require.NoError(t, err)
+
+	// Simulate the process dying after claiming the job.
+	_, ok, err := first.claim(context.Background())
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	second := NewQueue(db, clk)
+	second.Register(TypeCSVImport, CSVImportHandler())
+	cancel := second.Start(0, true)
+	defer cancel()
+
+	n, err := RecoverPlans(db)
+	require.NoError(t, err)
+	assert.Zero(t, n, "plan with a recovered job should be left to run")
+
+	ran, err := second.RunNext(context.Background())
+	require.NoError(t, err)
+	assert.True(t, ran)
+
+	require.NoError(t, db.Preload("Readings").First(&plan, plan.ID).Error)
+	assert.Equal(t, "active", plan.Status)
+	assert.Len(t, plan.Readings, 2)
+}
+
+func TestRecoverPlans_FailsOrphanedPlans(t *testing.T) {
+	db := setupTestDB(t)
+
+	user := model.User{Email: "orphan@example.com", Name: "Orphan"}
+	require.NoError(t, db.Create(&user).Error)
+
+	orphan := model.Plan{Title: "Orphan", UserID: user.ID, Status: "processing"}
+	active := model.Plan{Title: "Active", UserID: user.ID, Status: "active"}
+	require.NoError(t, db.Create(&orphan).Error)
+	require.NoError(t, db.Create(&active).Error)
+
+	n, err := RecoverPlans(db)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	require.NoError(t, db.First(&orphan, orphan.ID).Error)
+	assert.Equal(t, "failed", orphan.Status)
+	assert.Equal(t, PlanInterruptedMessage, orphan.ErrorMessage)
+
+	require.NoError(t, db.First(&active, active.ID).Error)
+	assert.Equal(t, "active", active.Status)
+}
@@ -0,0 +1,137 @@
+// Package passkey adapts users and their stored passkeys to the WebAuthn
+// library that runs the registration and sign-in ceremonies.
+package passkey
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/pkg/errors"
+
+	"readwillbe/internal/model"
+)
+
+// DisplayName names the site in the browser's passkey prompts.
+const DisplayName = "ReadWillBe"
+
+// ErrInvalidUserHandle is returned for a user handle this site did not issue.
+var ErrInvalidUserHandle = errors.New("invalid user handle")
+
+// New configures WebAuthn for cfg.Hostname. Outside production, plain HTTP
+// origins are allowed too so passkeys can be tried on localhost.
+func New(cfg model.Config) (*webauthn.WebAuthn, error) {
+	rpID := cfg.Hostname
+	if host, _, err := net.SplitHostPort(cfg.Hostname); err == nil {
+		rpID = host
+	}
+	origins := []string{"https://" + cfg.Hostname}
+	if !cfg.IsProduction() {
+		origins = append(origins, "http://"+cfg.Hostname)
+	}
+
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          rpID,
+		RPDisplayName: DisplayName,
+		RPOrigins:     origins,
+	})
+	return wa, errors.Wrap(err, "configuring WebAuthn")
+}
+
+// User is a model.User with its Passkeys loaded, as the WebAuthn library
+// sees it.
+type User struct {
+	model.User
+}
+
+// WebAuthnID implements webauthn.User.
+func (u User) WebAuthnID() []byte {
+	return UserHandle(u.ID)
+}
+
+// WebAuthnName implements webauthn.User.
+func (u User) WebAuthnName() string {
+	return u.Email
+}
+
+// WebAuthnDisplayName implements webauthn.User.
+func (u User) WebAuthnDisplayName() string {
+	if u.Name != "" {
+		return u.Name
+	}
+	return u.Email
+}
+
+// WebAuthnCredentials implements webauthn.User.
+func (u User) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, len(u.Passkeys))
+	for i, p := range u.Passkeys {
+		credentials[i] = Credential(p)
+	}
+	return credentials
+}
+
+// UserHandle is the opaque WebAuthn user handle for a user ID. Authenticators
+// hand it back on passwordless sign-in to say whose passkey was used.
+func UserHandle(userID uint) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(userID))
+}
+
+// UserIDFromHandle reverses UserHandle.
+func UserIDFromHandle(handle []byte) (uint, error) {
+	if len(handle) != 8 {
+		return 0, ErrInvalidUserHandle
+	}
+	id := binary.BigEndian.Uint64(handle)
+	if id == 0 {
+		return 0, ErrInvalidUserHandle
+	}
+	return uint(id), nil
+}
+
+// Credential converts a stored passkey for the WebAuthn library.
+func Credential(p model.Passkey) webauthn.Credential {
+	var transports []protocol.AuthenticatorTransport
+	for _, t := range strings.Split(p.Transports, ",") {
+		if t != "" {
+			transports = append(transports, protocol.AuthenticatorTransport(t))
+		}
+	}
+	return webauthn.Credential{
+		ID:                p.CredentialID,
+		PublicKey:         p.PublicKey,
+		AttestationType:   p.AttestationType,
+		AttestationFormat: p.AttestationFormat,
+		Transport:         transports,
+		Flags:             webauthn.NewCredentialFlags(protocol.AuthenticatorFlags(p.Flags)),
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    p.AAGUID,
+			SignCount: p.SignCount,
+		},
+	}
+}
+
+// FromCredential makes a passkey to store from a newly registered
+// credential.
+func FromCredential(userID uint, name string, c *webauthn.Credential, now time.Time) model.Passkey {
+	transports := make([]string, len(c.Transport))
+	for i, t := range c.Transport {
+		transports[i] = string(t)
+	}
+	return model.Passkey{
+		UserID:            userID,
+		Name:              name,
+		CredentialID:      c.ID,
+		PublicKey:         c.PublicKey,
+		AttestationType:   c.AttestationType,
+		AttestationFormat: c.AttestationFormat,
+		Transports:        strings.Join(transports, ","),
+		AAGUID:            c.Authenticator.AAGUID,
+		SignCount:         c.Authenticator.SignCount,
+		Flags:             uint8(c.Flags.ProtocolValue()),
+		CreatedAt:         now,
+	}
+}
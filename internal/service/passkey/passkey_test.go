@@ -0,0 +1,65 @@
+package passkey
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"readwillbe/internal/model"
+)
+
+func TestNew(t *testing.T) {
+	wa, err := New(model.Config{Hostname: "readwillbe.example:8443"})
+	require.NoError(t, err)
+	assert.Equal(t, "readwillbe.example", wa.Config.RPID)
+	assert.Contains(t, wa.Config.RPOrigins, "https://readwillbe.example:8443")
+}
+
+func TestUserHandle(t *testing.T) {
+	handle := UserHandle(42)
+	assert.Len(t, handle, 8)
+
+	id, err := UserIDFromHandle(handle)
+	require.NoError(t, err)
+	assert.Equal(t, uint(42), id)
+
+	for _, bad := range [][]byte{nil, []byte("short"), make([]byte, 8), make([]byte, 64)} {
+		_, err := UserIDFromHandle(bad)
+		assert.ErrorIs(t, err, ErrInvalidUserHandle)
+	}
+}
+
+func TestCredentialRoundTrip(t *testing.T) {
+	now := time.Date(2026, time.May, 1, 8, 0, 0, 0, time.UTC)
+	flags := protocol.FlagUserPresent | protocol.FlagUserVerified | protocol.FlagBackupEligible
+	credential := &webauthn.Credential{
+		ID:                []byte{1, 2, 3},
+		PublicKey:         []byte{4, 5, 6},
+		AttestationType:   "none",
+		AttestationFormat: "none",
+		Transport:         []protocol.AuthenticatorTransport{protocol.Internal, protocol.Hybrid},
+		Flags:             webauthn.NewCredentialFlags(flags),
+		Authenticator:     webauthn.Authenticator{AAGUID: make([]byte, 16), SignCount: 7},
+	}
+
+	p := FromCredential(3, "Laptop", credential, now)
+	assert.Equal(t, uint(3), p.UserID)
+	assert.Equal(t, "Laptop", p.Name)
+	assert.Equal(t, "internal,hybrid", p.Transports)
+	assert.Equal(t, now, p.CreatedAt)
+
+	back := Credential(p)
+	assert.Equal(t, credential.ID, back.ID)
+	assert.Equal(t, credential.PublicKey, back.PublicKey)
+	assert.Equal(t, credential.Transport, back.Transport)
+	assert.Equal(t, credential.Flags, back.Flags)
+	assert.Equal(t, credential.Authenticator, back.Authenticator)
+
+	user := User{model.User{Email: "reader@example.com", Passkeys: []model.Passkey{p}}}
+	assert.Equal(t, "reader@example.com", user.WebAuthnDisplayName())
+	assert.Len(t, user.WebAuthnCredentials(), 1)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 
+	"readwillbe/internal/clock"
 	"readwillbe/internal/model"
 	"readwillbe/internal/service/email"
 )
@@ -21,8 +22,9 @@ import (
 const NotificationCheckInterval = 1 * time.Minute
 
 // StartNotificationWorker starts the background notification loop and returns
-// a cancel function that stops it.
-func StartNotificationWorker(cfg model.Config, db *gorm.DB) context.CancelFunc {
+// a cancel function that stops it. clk decides when each user's notification
+// time has been reached.
+func StartNotificationWorker(cfg model.Config, db *gorm.DB, clk clock.Clock) context.CancelFunc {
 	pushEnabled := cfg.VAPIDPublicKey != "" && cfg.VAPIDPrivateKey != ""
 	emailEnabled := cfg.EmailEnabled()
 
@@ -53,7 +55,7 @@ func StartNotificationWorker(cfg model.Config, db *gorm.DB) context.CancelFunc {
 				logrus.Info("Notification worker stopped")
 				return
 			case <-ticker.C:
-				processNotifications(cfg, db, emailService, pushEnabled)
+				processNotifications(cfg, db, clk, emailService, pushEnabled)
 			}
 		}
 	}()
@@ -62,56 +64,80 @@ func StartNotificationWorker(cfg model.Config, db *gorm.DB) context.CancelFunc {
 	return cancel
 }
 
-func processNotifications(cfg model.Config, db *gorm.DB, emailService email.Service, pushEnabled bool) {
-	now := time.Now()
-	currentTime := now.Format("15:04")
+func processNotifications(cfg model.Config, db *gorm.DB, clk clock.Clock, emailService email.Service, pushEnabled bool) {
+	now := clk.Now()
 
-	var users []model.User
-	err := db.Preload("PushSubscriptions").
-		Where("notification_time = ?", currentTime).
+	// Notification times are wall-clock times in each user's own zone, so
+	// resolve "now" once per distinct timezone and match against that.
+	var zones []string
+	err := db.Model(&model.User{}).
 		Where("notifications_enabled = ? OR email_notifications_enabled = ?", true, true).
-		Find(&users).Error
-
+		Distinct("timezone").
+		Pluck("timezone", &zones).Error
 	if err != nil {
-		logrus.Errorf("Error fetching users for notifications: %v", err)
+		logrus.Errorf("Error fetching timezones for notifications: %v", err)
 		return
 	}
 
-	for _, user := range users {
-		var readings []model.Reading
-		err := db.Preload("Plan").
-			Where("plan_id IN (?)",
-				db.Table("plans").Select("id").Where("user_id = ?", user.ID),
-			).
-			Where("status != ?", model.StatusCompleted).
-			Find(&readings).Error
+	for _, zone := range zones {
+		localNow := now.In(model.ResolveLocation(zone))
+
+		var users []model.User
+		err := db.Preload("PushSubscriptions").
+			Where("timezone = ?", zone).
+			Where("notification_time = ?", localNow.Format("15:04")).
+			Where("notifications_enabled = ? OR email_notifications_enabled = ?", true, true).
+			Find(&users).Error
 
 		if err != nil {
-			logrus.Errorf("Error fetching readings for user %d: %v", user.ID, err)
+			logrus.Errorf("Error fetching users for notifications: %v", err)
 			continue
 		}
 
-		var activeReadings []model.Reading
-		for _, r := range readings {
-			if r.IsActiveToday() || r.IsOverdue() {
-				activeReadings = append(activeReadings, r)
-			}
+		for _, user := range users {
+			notifyUser(cfg, db, emailService, pushEnabled, user, localNow)
 		}
+	}
+}
 
-		if len(activeReadings) == 0 {
-			continue
-		}
+func notifyUser(cfg model.Config, db *gorm.DB, emailService email.Service, pushEnabled bool, user model.User, now time.Time) {
+	var readings []model.Reading
+	err := db.Preload("Plan").
+		Where("plan_id IN (?)",
+			db.Table("plans").Select("id").Where("user_id = ? AND paused_at IS NULL", user.ID),
+		).
+		Where("status NOT IN ?", []model.ReadingStatus{model.StatusCompleted, model.StatusSkipped}).
+		Find(&readings).Error
+
+	if err != nil {
+		logrus.Errorf("Error fetching readings for user %d: %v", user.ID, err)
+		return
+	}
 
-		if pushEnabled && user.NotificationsEnabled && len(user.PushSubscriptions) > 0 {
-			SendPushNotification(cfg, db, user)
+	var activeReadings []model.Reading
+	for _, r := range readings {
+		if r.IsActiveToday(now) || r.IsOverdue(now) {
+			activeReadings = append(activeReadings, r)
 		}
+	}
 
-		if emailService != nil && user.EmailNotificationsEnabled {
-			if err := emailService.SendDailyDigest(user, activeReadings, cfg.Hostname); err != nil {
-				logrus.Errorf("Error sending email to user %d: %v", user.ID, err)
-			} else {
-				logrus.Infof("Sent daily digest email to user %d", user.ID)
-			}
+	if len(activeReadings) == 0 {
+		return
+	}
+
+	if pushEnabled && user.NotificationsEnabled && len(user.PushSubscriptions) > 0 {
+		SendPushNotification(cfg, db, user)
+	}
+
+	if emailService != nil && user.EmailNotificationsEnabled {
+		if !user.NotificationEmailIsVerified() {
+			logrus.Infof("Not emailing user %d: %s is not verified", user.ID, user.GetNotificationEmail())
+			return
+		}
+		if err := emailService.SendDailyDigest(user, activeReadings, now, cfg.Hostname); err != nil {
+			logrus.Errorf("Error sending email to user %d: %v", user.ID, err)
+		} else {
+			logrus.Infof("Sent daily digest email to user %d", user.ID)
 		}
 	}
 }
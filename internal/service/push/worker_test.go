@@ -0,0 +1,127 @@
+package push
+
+import (
+	"testing"
+	"time"
+
+	"readwillbe/internal/clock"
+	"readwillbe/internal/database/dbtest"
+	"readwillbe/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db := dbtest.Open(t)
+
+	err := db.AutoMigrate(&model.User{}, &model.Plan{}, &model.Reading{}, &model.PushSubscription{})
+	require.NoError(t, err)
+
+	return db
+}
+
+type digest struct {
+	email string
+	now   time.Time
+	count int
+}
+
+type fakeEmailService struct {
+	sent []digest
+}
+
+func (f *fakeEmailService) SendDailyDigest(user model.User, readings []model.Reading, now time.Time, _ string) error {
+	f.sent = append(f.sent, digest{email: user.Email, now: now, count: len(readings)})
+	return nil
+}
+
+func (f *fakeEmailService) SendTestEmail(_, _ string) error {
+	return nil
+}
+
+func (f *fakeEmailService) SendPasswordReset(_ model.User, _ string) error {
+	return nil
+}
+
+func (f *fakeEmailService) SendEmailVerification(_ model.User, _, _ string) error {
+	return nil
+}
+
+func createNotifiedUser(t *testing.T, db *gorm.DB, email, timezone, notificationTime string, readingDate time.Time) *model.User {
+	user := &model.User{
+		Email:                     email,
+		EmailVerified:             true,
+		Name:                      "Test User",
+		Timezone:                  timezone,
+		NotificationTime:          notificationTime,
+		EmailNotificationsEnabled: true,
+	}
+	require.NoError(t, db.Create(user).Error)
+
+	plan := &model.Plan{Title: "Plan", UserID: user.ID, Status: "active"}
+	require.NoError(t, db.Create(plan).Error)
+
+	reading := &model.Reading{
+		PlanID:   plan.ID,
+		Date:     readingDate,
+		DateType: model.DateTypeDay,
+		Content:  "Reading",
+		Status:   model.StatusPending,
+	}
+	require.NoError(t, db.Create(reading).Error)
+	return user
+}
+
+func TestProcessNotifications_UserTimezone(t *testing.T) {
+	db := setupTestDB(t)
+
+	if _, err := time.LoadLocation("America/New_York"); err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	// 03:30 UTC on Mar 10 is still 23:30 on Mar 9 in New York.
+	now := time.Date(2025, time.March, 10, 3, 30, 0, 0, time.UTC)
+	mar9 := time.Date(2025, time.March, 9, 0, 0, 0, 0, time.UTC)
+	mar10 := time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC)
+
+	createNotifiedUser(t, db, "ny@example.com", "America/New_York", "23:30", mar9)
+	createNotifiedUser(t, db, "utc@example.com", "UTC", "03:30", mar10)
+	createNotifiedUser(t, db, "early@example.com", "UTC", "23:30", mar10)
+	createNotifiedUser(t, db, "future@example.com", "America/New_York", "23:30", mar10)
+
+	emailService := &fakeEmailService{}
+	processNotifications(model.Config{Hostname: "example.com"}, db, clock.Fixed(now), emailService, false)
+
+	require.Len(t, emailService.sent, 2)
+
+	byEmail := map[string]digest{}
+	for _, d := range emailService.sent {
+		byEmail[d.email] = d
+	}
+
+	ny, ok := byEmail["ny@example.com"]
+	require.True(t, ok, "New York user is due at 23:30 local time")
+	assert.Equal(t, 9, ny.now.Day())
+	assert.Equal(t, "America/New_York", ny.now.Location().String())
+	assert.Equal(t, 1, ny.count)
+
+	_, ok = byEmail["utc@example.com"]
+	assert.True(t, ok, "UTC user is due at 03:30")
+}
+
+func TestProcessNotifications_UnverifiedEmail(t *testing.T) {
+	db := setupTestDB(t)
+	now := time.Date(2025, time.March, 10, 7, 0, 0, 0, time.UTC)
+	user := createNotifiedUser(t, db, "verified@example.com", "UTC", "07:00", now)
+	require.NoError(t, db.Model(user).Update("notification_email", "inbox@example.com").Error)
+
+	emailService := &fakeEmailService{}
+	processNotifications(model.Config{}, db, clock.Fixed(now), emailService, false)
+	assert.Empty(t, emailService.sent, "the notification address has not been verified")
+
+	require.NoError(t, db.Model(user).Update("notification_email_verified", true).Error)
+	processNotifications(model.Config{}, db, clock.Fixed(now), emailService, false)
+	assert.Len(t, emailService.sent, 1)
+}
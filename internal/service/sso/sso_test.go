@@ -0,0 +1,84 @@
+package sso
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"readwillbe/internal/model"
+	"readwillbe/internal/service/sso/ssotest"
+)
+
+func newTestProvider(t *testing.T) (*ssotest.Provider, *Provider) {
+	idp := ssotest.NewProvider(t)
+	provider, err := New(context.Background(), model.Config{
+		OIDCIssuer:       idp.URL,
+		OIDCClientID:     ssotest.ClientID,
+		OIDCClientSecret: ssotest.ClientSecret,
+		OIDCRedirectURL:  "https://readwillbe.example/auth/oidc/callback",
+	})
+	require.NoError(t, err)
+	return idp, provider
+}
+
+func TestExchange(t *testing.T) {
+	ctx := context.Background()
+	idp, provider := newTestProvider(t)
+
+	authorize := func(t *testing.T, nonce, verifier string) string {
+		callback := idp.Authorize(t, provider.AuthCodeURL("state", nonce, verifier))
+		assert.Equal(t, "readwillbe.example", callback.Host)
+		assert.Equal(t, "state", callback.Query().Get("state"))
+		return callback.Query().Get("code")
+	}
+
+	t.Run("returns the signed-in identity", func(t *testing.T) {
+		idp.SignIn(map[string]any{"sub": "u-1", "email": "reader@example.com", "email_verified": true, "name": "Reader"})
+		verifier := oauth2.GenerateVerifier()
+
+		identity, err := provider.Exchange(ctx, authorize(t, "nonce", verifier), "nonce", verifier)
+		require.NoError(t, err)
+		assert.Equal(t, Identity{Subject: "u-1", Email: "reader@example.com", Name: "Reader"}, identity)
+	})
+
+	t.Run("needs the PKCE verifier", func(t *testing.T) {
+		idp.SignIn(map[string]any{"email": "reader@example.com"})
+		code := authorize(t, "nonce", oauth2.GenerateVerifier())
+
+		_, err := provider.Exchange(ctx, code, "nonce", oauth2.GenerateVerifier())
+		assert.Error(t, err)
+	})
+
+	t.Run("codes are single use", func(t *testing.T) {
+		idp.SignIn(map[string]any{"email": "reader@example.com"})
+		verifier := oauth2.GenerateVerifier()
+		code := authorize(t, "nonce", verifier)
+
+		_, err := provider.Exchange(ctx, code, "nonce", verifier)
+		require.NoError(t, err)
+		_, err = provider.Exchange(ctx, code, "nonce", verifier)
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects another sign-in's token", func(t *testing.T) {
+		idp.SignIn(map[string]any{"email": "reader@example.com"})
+		verifier := oauth2.GenerateVerifier()
+
+		_, err := provider.Exchange(ctx, authorize(t, "other", verifier), "nonce", verifier)
+		assert.ErrorIs(t, err, ErrNonceMismatch)
+	})
+
+	t.Run("needs a verified email", func(t *testing.T) {
+		verifier := oauth2.GenerateVerifier()
+		idp.SignIn(map[string]any{"sub": "u-2"})
+		_, err := provider.Exchange(ctx, authorize(t, "nonce", verifier), "nonce", verifier)
+		assert.ErrorIs(t, err, ErrNoEmail)
+
+		idp.SignIn(map[string]any{"email": "reader@example.com", "email_verified": false})
+		_, err = provider.Exchange(ctx, authorize(t, "nonce", verifier), "nonce", verifier)
+		assert.ErrorIs(t, err, ErrEmailNotVerified)
+	})
+}
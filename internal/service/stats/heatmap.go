@@ -0,0 +1,68 @@
+package stats
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"readwillbe/internal/model"
+)
+
+// MaxHeatmapDays bounds the range of a heatmap request.
+const MaxHeatmapDays = 2 * 366
+
+// HeatmapDay is how many readings were completed on one calendar day. Each
+// reading counts once, on the day it was completed, whatever its cadence;
+// the cadence breakdown lets a week or month reading be told apart from a
+// day's.
+type HeatmapDay struct {
+	Date    string `json:"date"` // YYYY-MM-DD
+	Count   int    `json:"count"`
+	Daily   int    `json:"daily"`
+	Weekly  int    `json:"weekly"`
+	Monthly int    `json:"monthly"`
+}
+
+// Heatmap returns one entry per calendar day from from through to, in loc,
+// with the user's completed readings counted on the day they were completed.
+// Readings of deleted plans are left out.
+func Heatmap(tx *gorm.DB, userID uint, from, to time.Time, loc *time.Location) ([]HeatmapDay, error) {
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	end := time.Date(to.Year(), to.Month(), to.Day()+1, 0, 0, 0, 0, loc)
+
+	var readings []model.Reading
+	err := tx.Select("readings.id", "readings.date_type", "readings.completed_at").
+		Joins("JOIN plans ON plans.id = readings.plan_id AND plans.deleted_at IS NULL").
+		Where("plans.user_id = ? AND readings.status = ?", userID, model.StatusCompleted).
+		Where("readings.completed_at >= ? AND readings.completed_at < ?", start.UTC(), end.UTC()).
+		Find(&readings).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var days []HeatmapDay
+	index := make(map[string]int)
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		key := d.Format("2006-01-02")
+		index[key] = len(days)
+		days = append(days, HeatmapDay{Date: key})
+	}
+
+	for _, r := range readings {
+		i, ok := index[r.CompletedAt.In(loc).Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		day := &days[i]
+		day.Count++
+		switch r.DateType {
+		case model.DateTypeWeek:
+			day.Weekly++
+		case model.DateTypeMonth:
+			day.Monthly++
+		default:
+			day.Daily++
+		}
+	}
+	return days, nil
+}
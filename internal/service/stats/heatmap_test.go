@@ -0,0 +1,46 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"readwillbe/internal/database/dbtest"
+	"readwillbe/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeatmap(t *testing.T) {
+	db := dbtest.Open(t)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Plan{}, &model.Reading{}))
+
+	user := &model.User{Email: "heatmap@example.com"}
+	require.NoError(t, db.Create(user).Error)
+	plan := &model.Plan{Title: "Plan", UserID: user.ID, Status: "active"}
+	require.NoError(t, db.Create(plan).Error)
+
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	complete := func(dateType model.DateType, at time.Time) {
+		r := &model.Reading{PlanID: plan.ID, Date: at, DateType: dateType, Status: model.StatusCompleted, CompletedAt: &at}
+		require.NoError(t, db.Create(r).Error)
+	}
+	complete(model.DateTypeDay, time.Date(2026, time.March, 2, 9, 0, 0, 0, loc))
+	complete(model.DateTypeWeek, time.Date(2026, time.March, 2, 22, 0, 0, 0, loc)) // March 3 in UTC
+	complete(model.DateTypeMonth, time.Date(2026, time.March, 4, 8, 0, 0, 0, loc))
+	complete(model.DateTypeDay, time.Date(2026, time.March, 9, 8, 0, 0, 0, loc)) // outside the range
+	require.NoError(t, db.Create(&model.Reading{PlanID: plan.ID, Date: time.Now(), DateType: model.DateTypeDay, Status: model.StatusPending}).Error)
+
+	from := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, time.March, 7, 0, 0, 0, 0, time.UTC)
+	days, err := Heatmap(db, user.ID, from, to, loc)
+	require.NoError(t, err)
+
+	require.Len(t, days, 7)
+	assert.Equal(t, "2026-03-01", days[0].Date)
+	assert.Equal(t, "2026-03-07", days[6].Date)
+	assert.Equal(t, HeatmapDay{Date: "2026-03-02", Count: 2, Daily: 1, Weekly: 1}, days[1], "completion days follow the user's timezone")
+	assert.Equal(t, HeatmapDay{Date: "2026-03-04", Count: 1, Monthly: 1}, days[3])
+	assert.Zero(t, days[2].Count)
+}
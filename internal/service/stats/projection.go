@@ -0,0 +1,141 @@
+package stats
+
+import (
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+
+	"readwillbe/internal/model"
+)
+
+// ProjectionStatus says how a plan's projected finish compares with its
+// schedule.
+type ProjectionStatus string
+
+// Projection statuses.
+const (
+	ProjectionComplete ProjectionStatus = "complete"
+	ProjectionAhead    ProjectionStatus = "ahead"
+	ProjectionOnTrack  ProjectionStatus = "on_track"
+	ProjectionBehind   ProjectionStatus = "behind"
+	// ProjectionUnknown is used when nothing was completed recently, or the
+	// plan is paused, so there is no rate to project from.
+	ProjectionUnknown ProjectionStatus = "unknown"
+)
+
+// RecentDays is how many full days before today the completion rate is
+// measured over.
+const RecentDays = 28
+
+// OnTrackDays is how many days early a plan may be projected to finish and
+// still count as on track rather than ahead.
+const OnTrackDays = 7
+
+// Projection estimates when a plan will be finished at the user's recent
+// pace. Dates are calendar days as UTC midnight.
+type Projection struct {
+	PlanID    uint   `json:"planId"`
+	Title     string `json:"title"`
+	Remaining int    `json:"remaining"`
+	// RatePerDay is readings completed per day over the last RecentDays
+	// full days, or since the plan started if that is more recent.
+	RatePerDay      float64          `json:"ratePerDay"`
+	ScheduledFinish time.Time        `json:"scheduledFinish"`
+	ProjectedFinish *time.Time       `json:"projectedFinish,omitempty"`
+	Status          ProjectionStatus `json:"status"`
+	// DaysAhead is how many days before the scheduled finish the plan is
+	// projected to end; negative when behind.
+	DaysAhead int `json:"daysAhead"`
+}
+
+// Project estimates the finish of plan, whose Readings must be loaded. now
+// should be in the user's timezone.
+func Project(plan model.Plan, now time.Time) Projection {
+	p := Projection{PlanID: plan.ID, Title: plan.Title, Status: ProjectionUnknown}
+	today := model.CivilDate(now)
+
+	yesterday := today.AddDate(0, 0, -1)
+	windowStart := today.AddDate(0, 0, -RecentDays)
+	started := model.CivilDate(plan.CreatedAt.In(now.Location()))
+	if plan.StartDate != nil {
+		started = model.CivilDate(*plan.StartDate)
+	}
+	if started.After(windowStart) {
+		windowStart = started
+	}
+
+	var recent, doneToday int
+	for _, r := range plan.Readings {
+		if last := r.LastDay(); last.After(p.ScheduledFinish) {
+			p.ScheduledFinish = last
+		}
+		if !r.IsDone() {
+			p.Remaining++
+		}
+		if r.Status != model.StatusCompleted || r.CompletedAt == nil {
+			continue
+		}
+		switch day := model.CivilDate(r.CompletedAt.In(now.Location())); {
+		case day.Equal(today):
+			doneToday++
+		case !day.Before(windowStart) && !day.After(yesterday):
+			recent++
+		}
+	}
+
+	if p.Remaining == 0 {
+		p.Status = ProjectionComplete
+		return p
+	}
+	if windowDays := int(yesterday.Sub(windowStart).Hours()/24) + 1; windowDays > 0 {
+		p.RatePerDay = float64(recent) / float64(windowDays)
+	} else {
+		// The plan started today, so today is all there is to go on.
+		p.RatePerDay = float64(doneToday)
+	}
+	if p.RatePerDay == 0 || plan.IsPaused() {
+		return p
+	}
+
+	// Reading resumes today, or tomorrow if today's reading is done, and
+	// finishing n readings at one a day takes n days counting the first.
+	first := today
+	if doneToday > 0 {
+		first = today.AddDate(0, 0, 1)
+	}
+	days := int(math.Ceil(float64(p.Remaining) / p.RatePerDay))
+	finish := first.AddDate(0, 0, days-1)
+	p.ProjectedFinish = &finish
+	p.DaysAhead = int(p.ScheduledFinish.Sub(finish).Hours() / 24)
+	switch {
+	case p.DaysAhead < 0:
+		p.Status = ProjectionBehind
+	case p.DaysAhead > OnTrackDays:
+		p.Status = ProjectionAhead
+	default:
+		p.Status = ProjectionOnTrack
+	}
+	return p
+}
+
+// ProjectionsForUser projects every active, unfinished plan of the user, in
+// title order.
+func ProjectionsForUser(tx *gorm.DB, userID uint, now time.Time) ([]Projection, error) {
+	var plans []model.Plan
+	err := tx.Preload("Readings").
+		Where("user_id = ? AND status = ?", userID, "active").
+		Order("title").
+		Find(&plans).Error
+	if err != nil {
+		return nil, err
+	}
+
+	projections := make([]Projection, 0, len(plans))
+	for _, plan := range plans {
+		if p := Project(plan, now); p.Status != ProjectionComplete {
+			projections = append(projections, p)
+		}
+	}
+	return projections, nil
+}
@@ -0,0 +1,131 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"readwillbe/internal/database/dbtest"
+	"readwillbe/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dailyPlan builds a plan of daily readings from start, with the first done
+// of them completed one a day from start, each on its own date.
+func dailyPlan(start time.Time, total, done int) model.Plan {
+	plan := model.Plan{Title: "Plan", Status: "active", StartDate: &start}
+	for i := 0; i < total; i++ {
+		r := model.Reading{Date: start.AddDate(0, 0, i), DateType: model.DateTypeDay, Status: model.StatusPending}
+		if i < done {
+			completed := start.AddDate(0, 0, i).Add(9 * time.Hour)
+			r.Status, r.CompletedAt = model.StatusCompleted, &completed
+		}
+		plan.Readings = append(plan.Readings, r)
+	}
+	return plan
+}
+
+func TestProject(t *testing.T) {
+	start := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return start.AddDate(0, 0, d-1) }
+
+	t.Run("on schedule", func(t *testing.T) {
+		// Ten readings done on the first ten days, twenty to go.
+		p := Project(dailyPlan(start, 30, 10), day(11).Add(8*time.Hour))
+		assert.Equal(t, 20, p.Remaining)
+		assert.InDelta(t, 1.0, p.RatePerDay, 0.001, "the window starts with the plan")
+		assert.Equal(t, day(30), p.ScheduledFinish)
+		require.NotNil(t, p.ProjectedFinish)
+		assert.Equal(t, day(30), *p.ProjectedFinish)
+		assert.Equal(t, 0, p.DaysAhead)
+		assert.Equal(t, ProjectionOnTrack, p.Status)
+
+		p = Project(dailyPlan(start, 30, 10), day(10).Add(20*time.Hour))
+		require.NotNil(t, p.ProjectedFinish)
+		assert.Equal(t, day(30), *p.ProjectedFinish, "today's reading is done, so the rest start tomorrow")
+	})
+
+	t.Run("behind", func(t *testing.T) {
+		plan := dailyPlan(start, 30, 0)
+		for i := 0; i < 5; i++ {
+			completed := day(1 + 2*i).Add(9 * time.Hour) // every other day
+			plan.Readings[i].Status, plan.Readings[i].CompletedAt = model.StatusCompleted, &completed
+		}
+		p := Project(plan, day(11).Add(8*time.Hour))
+		assert.InDelta(t, 0.5, p.RatePerDay, 0.001)
+		assert.Equal(t, day(60), *p.ProjectedFinish)
+		assert.Equal(t, -30, p.DaysAhead)
+		assert.Equal(t, ProjectionBehind, p.Status)
+	})
+
+	t.Run("ahead", func(t *testing.T) {
+		plan := dailyPlan(start, 30, 0)
+		for i := 0; i < 20; i++ {
+			completed := day(1 + i/2).Add(9 * time.Hour) // two a day
+			plan.Readings[i].Status, plan.Readings[i].CompletedAt = model.StatusCompleted, &completed
+		}
+		p := Project(plan, day(10).Add(20*time.Hour))
+		assert.Equal(t, ProjectionAhead, p.Status)
+		assert.Equal(t, day(15), *p.ProjectedFinish)
+		assert.Equal(t, 15, p.DaysAhead)
+	})
+
+	t.Run("recent window", func(t *testing.T) {
+		// Everything was done long ago, nothing lately.
+		p := Project(dailyPlan(start, 60, 10), day(60))
+		assert.Equal(t, ProjectionUnknown, p.Status)
+		assert.Nil(t, p.ProjectedFinish)
+		assert.Zero(t, p.RatePerDay)
+	})
+
+	t.Run("skipped readings are not remaining", func(t *testing.T) {
+		plan := dailyPlan(start, 2, 1)
+		plan.Readings[1].Status = model.StatusSkipped
+		assert.Equal(t, ProjectionComplete, Project(plan, day(2)).Status)
+	})
+
+	t.Run("paused", func(t *testing.T) {
+		plan := dailyPlan(start, 30, 10)
+		paused := day(11)
+		plan.PausedAt = &paused
+		p := Project(plan, day(11))
+		assert.Equal(t, ProjectionUnknown, p.Status)
+		assert.Nil(t, p.ProjectedFinish)
+	})
+
+	t.Run("week readings finish at the end of their week", func(t *testing.T) {
+		plan := model.Plan{Readings: []model.Reading{{Date: day(2), DateType: model.DateTypeWeek, Status: model.StatusPending}}}
+		assert.Equal(t, day(8), Project(plan, day(2)).ScheduledFinish)
+	})
+}
+
+func TestProjectionsForUser(t *testing.T) {
+	db := dbtest.Open(t)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Plan{}, &model.Reading{}))
+	user := &model.User{Email: "projection@example.com"}
+	require.NoError(t, db.Create(user).Error)
+
+	start := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	for _, p := range []struct {
+		title       string
+		total, done int
+		status      string
+	}{
+		{"B running", 30, 10, "active"},
+		{"A finished", 5, 5, "active"},
+		{"C failed", 5, 0, "failed"},
+		{"A running", 30, 10, "active"},
+	} {
+		plan := dailyPlan(start, p.total, p.done)
+		plan.Title, plan.Status, plan.UserID = p.title, p.status, user.ID
+		require.NoError(t, db.Create(&plan).Error)
+	}
+
+	projections, err := ProjectionsForUser(db, user.ID, start.AddDate(0, 0, 10))
+	require.NoError(t, err)
+	require.Len(t, projections, 2)
+	assert.Equal(t, "A running", projections[0].Title)
+	assert.Equal(t, "B running", projections[1].Title)
+	assert.Equal(t, 20, projections[0].Remaining)
+}
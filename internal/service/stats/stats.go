@@ -0,0 +1,200 @@
+// Package stats computes reading streaks and how consistently readings are
+// completed on schedule, for a user overall and for each of their plans.
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"readwillbe/internal/model"
+)
+
+// Stats summarises a set of completed readings.
+type Stats struct {
+	// CurrentStreak is the number of consecutive periods of StreakUnit, up
+	// to the current one, in which at least one reading was completed. A
+	// streak that reached the previous period still counts until the
+	// current one is over.
+	CurrentStreak int `json:"currentStreak"`
+	// LongestStreak is the longest such run ever.
+	LongestStreak int `json:"longestStreak"`
+	// StreakUnit is the period streaks are counted in: days overall, and the
+	// cadence most of a plan's readings follow for that plan.
+	StreakUnit model.DateType `json:"streakUnit"`
+	Completed  int            `json:"completed"`
+	// OnTime counts readings completed by the last day of their scheduled
+	// day, week or month; Late counts the rest.
+	OnTime int `json:"onTime"`
+	Late   int `json:"late"`
+	// AverageDelayDays is how many days after the end of their window late
+	// readings were completed, on average.
+	AverageDelayDays float64 `json:"averageDelayDays"`
+}
+
+// OnTimePercent is the share of completed readings that were on time, from 0
+// to 100. It is 0 when nothing has been completed.
+func (s Stats) OnTimePercent() int {
+	if s.Completed == 0 {
+		return 0
+	}
+	return s.OnTime * 100 / s.Completed
+}
+
+// PlanStats are the [Stats] for one plan.
+type PlanStats struct {
+	PlanID uint   `json:"planId"`
+	Title  string `json:"title"`
+	Stats
+}
+
+// Report holds a user's stats overall and per plan, with plans in title
+// order.
+type Report struct {
+	Overall Stats       `json:"overall"`
+	Plans   []PlanStats `json:"plans"`
+}
+
+// ForUser computes the report for the user's completed readings on plans that
+// have not been deleted. now should be in the user's timezone, which decides
+// the calendar day each reading was completed on.
+func ForUser(tx *gorm.DB, userID uint, now time.Time) (Report, error) {
+	var readings []model.Reading
+	err := tx.Preload("Plan").
+		Joins("JOIN plans ON plans.id = readings.plan_id AND plans.deleted_at IS NULL").
+		Where("plans.user_id = ? AND readings.status = ? AND readings.completed_at IS NOT NULL", userID, model.StatusCompleted).
+		Find(&readings).Error
+	if err != nil {
+		return Report{}, err
+	}
+	return Compute(readings, now), nil
+}
+
+// Compute builds a report from completed readings, which must have their
+// Plan loaded. Readings without a CompletedAt are ignored.
+func Compute(readings []model.Reading, now time.Time) Report {
+	byPlan := make(map[uint][]model.Reading)
+	titles := make(map[uint]string)
+	for _, r := range readings {
+		if r.CompletedAt == nil {
+			continue
+		}
+		byPlan[r.PlanID] = append(byPlan[r.PlanID], r)
+		titles[r.PlanID] = r.Plan.Title
+	}
+
+	report := Report{Overall: compute(readings, model.DateTypeDay, now), Plans: make([]PlanStats, 0, len(byPlan))}
+	for planID, planReadings := range byPlan {
+		report.Plans = append(report.Plans, PlanStats{
+			PlanID: planID,
+			Title:  titles[planID],
+			Stats:  compute(planReadings, cadence(planReadings), now),
+		})
+	}
+	sort.Slice(report.Plans, func(i, j int) bool {
+		if report.Plans[i].Title != report.Plans[j].Title {
+			return report.Plans[i].Title < report.Plans[j].Title
+		}
+		return report.Plans[i].PlanID < report.Plans[j].PlanID
+	})
+	return report
+}
+
+func compute(readings []model.Reading, unit model.DateType, now time.Time) Stats {
+	s := Stats{StreakUnit: unit}
+	var delayDays int
+	periods := make(map[time.Time]bool)
+	for _, r := range readings {
+		if r.CompletedAt == nil {
+			continue
+		}
+		completed := model.CivilDate(r.CompletedAt.In(now.Location()))
+		periods[periodStart(completed, unit)] = true
+
+		s.Completed++
+		if late := int(completed.Sub(r.LastDay()).Hours() / 24); late > 0 {
+			s.Late++
+			delayDays += late
+		} else {
+			s.OnTime++
+		}
+	}
+	if s.Late > 0 {
+		s.AverageDelayDays = float64(delayDays) / float64(s.Late)
+	}
+	s.CurrentStreak, s.LongestStreak = streaks(periods, unit, periodStart(model.CivilDate(now), unit))
+	return s
+}
+
+// cadence is the unit a plan's streaks are counted in: the date type most of
+// its readings use, preferring the shorter on a tie.
+func cadence(readings []model.Reading) model.DateType {
+	counts := make(map[model.DateType]int)
+	for _, r := range readings {
+		counts[r.DateType]++
+	}
+	unit := model.DateTypeDay
+	for _, dt := range []model.DateType{model.DateTypeWeek, model.DateTypeMonth} {
+		if counts[dt] > counts[unit] {
+			unit = dt
+		}
+	}
+	return unit
+}
+
+// periodStart is the first day of the day, week or month, by unit, holding
+// day. Weeks start on Monday, as week readings do.
+func periodStart(day time.Time, unit model.DateType) time.Time {
+	switch unit {
+	case model.DateTypeWeek:
+		return model.RelativeDate(day, model.DateTypeWeek, 0)
+	case model.DateTypeMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// nextPeriod is the start of the period n units after the one starting at p.
+func nextPeriod(p time.Time, unit model.DateType, n int) time.Time {
+	switch unit {
+	case model.DateTypeWeek:
+		return p.AddDate(0, 0, 7*n)
+	case model.DateTypeMonth:
+		return p.AddDate(0, n, 0)
+	default:
+		return p.AddDate(0, 0, n)
+	}
+}
+
+// streaks returns the current and longest runs of consecutive periods in
+// periods, each keyed by its start. today is the start of the period holding
+// today.
+func streaks(periods map[time.Time]bool, unit model.DateType, today time.Time) (current, longest int) {
+	sorted := make([]time.Time, 0, len(periods))
+	for p := range periods {
+		sorted = append(sorted, p)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+
+	run := 0
+	for i, p := range sorted {
+		if i > 0 && nextPeriod(sorted[i-1], unit, 1).Equal(p) {
+			run++
+		} else {
+			run = 1
+		}
+		longest = max(longest, run)
+	}
+
+	p := today
+	if !periods[p] {
+		p = nextPeriod(p, unit, -1)
+	}
+	for periods[p] {
+		current++
+		p = nextPeriod(p, unit, -1)
+	}
+	return current, longest
+}
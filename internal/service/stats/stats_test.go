@@ -0,0 +1,153 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"readwillbe/internal/database/dbtest"
+	"readwillbe/internal/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func completedReading(plan model.Plan, dateType model.DateType, date, completed time.Time) model.Reading {
+	return model.Reading{
+		PlanID:      plan.ID,
+		Plan:        plan,
+		Date:        date,
+		DateType:    dateType,
+		Status:      model.StatusCompleted,
+		CompletedAt: &completed,
+	}
+}
+
+func TestCompute(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	day := func(d int) time.Time { return time.Date(2026, time.March, d, 0, 0, 0, 0, time.UTC) }
+	at := func(d, hour int) time.Time { return time.Date(2026, time.March, d, hour, 0, 0, 0, loc) }
+
+	daily := model.Plan{Title: "Daily"}
+	daily.ID = 1
+	weekly := model.Plan{Title: "A weekly"}
+	weekly.ID = 2
+
+	readings := []model.Reading{
+		// A three-day run, March 1-3.
+		completedReading(daily, model.DateTypeDay, day(1), at(1, 9)),
+		completedReading(daily, model.DateTypeDay, day(2), at(2, 9)),
+		completedReading(daily, model.DateTypeDay, day(3), at(3, 9)),
+		// Late by four days, then by two.
+		completedReading(daily, model.DateTypeDay, day(4), at(8, 9)),
+		completedReading(daily, model.DateTypeDay, day(5), at(7, 9)),
+		// Completed late at night in New York, which is the next day in UTC;
+		// it still counts as on time and extends the current run.
+		completedReading(daily, model.DateTypeDay, day(9), at(9, 23)),
+		// A week reading finished on the week's last day is on time.
+		completedReading(weekly, model.DateTypeWeek, day(2), at(8, 9)),
+		{PlanID: daily.ID, Plan: daily, Status: model.StatusCompleted, Date: day(9)},
+	}
+
+	report := Compute(readings, at(10, 8))
+
+	overall := report.Overall
+	assert.Equal(t, 7, overall.Completed)
+	assert.Equal(t, 5, overall.OnTime)
+	assert.Equal(t, 2, overall.Late)
+	assert.InDelta(t, 3.0, overall.AverageDelayDays, 0.001)
+	assert.Equal(t, 71, overall.OnTimePercent())
+	assert.Equal(t, 3, overall.LongestStreak)
+	assert.Equal(t, 3, overall.CurrentStreak, "March 7-9, still running on the 10th")
+
+	require.Len(t, report.Plans, 2)
+	assert.Equal(t, "A weekly", report.Plans[0].Title)
+	assert.Equal(t, 1, report.Plans[0].OnTime)
+	assert.Equal(t, model.DateTypeWeek, report.Plans[0].StreakUnit)
+	assert.Equal(t, 1, report.Plans[0].CurrentStreak, "completed last week, still running this week")
+	assert.Equal(t, "Daily", report.Plans[1].Title)
+	assert.Equal(t, model.DateTypeDay, report.Plans[1].StreakUnit)
+	assert.Equal(t, 6, report.Plans[1].Completed)
+	assert.Equal(t, 3, report.Plans[1].CurrentStreak)
+
+	assert.Equal(t, 0, Compute(readings, at(11, 8)).Overall.CurrentStreak, "a missed day ends the streak")
+}
+
+func TestCompute_Empty(t *testing.T) {
+	report := Compute(nil, time.Now())
+	assert.Equal(t, Stats{StreakUnit: model.DateTypeDay}, report.Overall)
+	assert.Empty(t, report.Plans)
+	assert.Equal(t, 0, report.Overall.OnTimePercent())
+}
+
+func TestCompute_StreaksFollowPlanCadence(t *testing.T) {
+	day := func(m time.Month, d int) time.Time { return time.Date(2026, m, d, 0, 0, 0, 0, time.UTC) }
+	at := func(m time.Month, d int) time.Time { return time.Date(2026, m, d, 12, 0, 0, 0, time.UTC) }
+
+	weekly := model.Plan{Title: "Weekly"}
+	weekly.ID = 1
+	monthly := model.Plan{Title: "Monthly"}
+	monthly.ID = 2
+
+	readings := []model.Reading{
+		// Weeks of February 23, March 2 and March 9, on different weekdays;
+		// then a gap week before March 23.
+		completedReading(weekly, model.DateTypeWeek, day(time.February, 23), at(time.February, 23)),
+		completedReading(weekly, model.DateTypeWeek, day(time.March, 2), at(time.March, 8)),
+		completedReading(weekly, model.DateTypeWeek, day(time.March, 9), at(time.March, 11)),
+		completedReading(weekly, model.DateTypeWeek, day(time.March, 23), at(time.March, 24)),
+		// January and February, then March at its very end.
+		completedReading(monthly, model.DateTypeMonth, day(time.January, 1), at(time.January, 5)),
+		completedReading(monthly, model.DateTypeMonth, day(time.February, 1), at(time.February, 27)),
+		completedReading(monthly, model.DateTypeMonth, day(time.March, 1), at(time.March, 31)),
+	}
+
+	report := Compute(readings, at(time.April, 8))
+	require.Len(t, report.Plans, 2)
+
+	monthlyStats, weeklyStats := report.Plans[0], report.Plans[1]
+	assert.Equal(t, model.DateTypeMonth, monthlyStats.StreakUnit)
+	assert.Equal(t, 3, monthlyStats.LongestStreak)
+	assert.Equal(t, 3, monthlyStats.CurrentStreak, "March still counts during April")
+
+	assert.Equal(t, model.DateTypeWeek, weeklyStats.StreakUnit)
+	assert.Equal(t, 3, weeklyStats.LongestStreak)
+	assert.Equal(t, 0, weeklyStats.CurrentStreak, "nothing in the week of April 6 or the one before")
+
+	assert.Equal(t, 1, Compute(readings, at(time.April, 2)).Plans[1].CurrentStreak, "the week of March 23 counts during the next")
+}
+
+func TestForUser(t *testing.T) {
+	db := dbtest.Open(t)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Plan{}, &model.Reading{}))
+
+	user := &model.User{Email: "stats@example.com"}
+	other := &model.User{Email: "other@example.com"}
+	require.NoError(t, db.Create(user).Error)
+	require.NoError(t, db.Create(other).Error)
+
+	now := time.Date(2026, time.March, 10, 9, 0, 0, 0, time.UTC)
+	addPlan := func(owner *model.User, title string) *model.Plan {
+		plan := &model.Plan{Title: title, UserID: owner.ID, Status: "active"}
+		require.NoError(t, db.Create(plan).Error)
+		for _, status := range []model.ReadingStatus{model.StatusCompleted, model.StatusPending} {
+			r := &model.Reading{PlanID: plan.ID, Date: now, DateType: model.DateTypeDay, Status: status}
+			if status == model.StatusCompleted {
+				r.CompletedAt = &now
+			}
+			require.NoError(t, db.Create(r).Error)
+		}
+		return plan
+	}
+	addPlan(user, "Kept")
+	deleted := addPlan(user, "Deleted")
+	addPlan(other, "Other")
+	require.NoError(t, db.Delete(deleted).Error)
+
+	report, err := ForUser(db, user.ID, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Overall.Completed)
+	assert.Equal(t, 1, report.Overall.CurrentStreak)
+	require.Len(t, report.Plans, 1)
+	assert.Equal(t, "Kept", report.Plans[0].Title)
+}
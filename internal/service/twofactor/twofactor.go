@@ -0,0 +1,111 @@
+// Package twofactor generates and checks the time-based one-time passwords
+// (TOTP, RFC 6238) and recovery codes used as a second sign-in factor.
+package twofactor
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"image/png"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+// Issuer names the app in authenticator apps.
+const Issuer = "ReadWillBe"
+
+// Period is how many seconds each code is valid for.
+const Period = 30
+
+// Skew is how many periods either side of now a code is still accepted, to
+// allow for clock drift between the server and the authenticator.
+const Skew = 1
+
+// RecoveryCodeCount is how many recovery codes are issued at once.
+const RecoveryCodeCount = 10
+
+var validateOpts = totp.ValidateOpts{
+	Period:    Period,
+	Digits:    otp.DigitsSix,
+	Algorithm: otp.AlgorithmSHA1,
+}
+
+// NewKey generates a new TOTP secret for accountName, usually the user's
+// email address.
+func NewKey(accountName string) (*otp.Key, error) {
+	return totp.Generate(totp.GenerateOpts{
+		Issuer:      Issuer,
+		AccountName: accountName,
+		Period:      Period,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+}
+
+// QRCode renders the key's otpauth URI as a PNG data URI for an <img>.
+func QRCode(key *otp.Key) (string, error) {
+	img, err := key.Image(200, 200)
+	if err != nil {
+		return "", errors.Wrap(err, "rendering QR code")
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", errors.Wrap(err, "encoding QR code")
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// Validate checks code against secret at now, returning the time step it
+// matched. Callers record the step and reject codes for a step at or before
+// the last one used, so an intercepted code cannot be replayed.
+func Validate(secret, code string, now time.Time) (step int64, ok bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != otp.DigitsSix.Length() {
+		return 0, false
+	}
+	for offset := -Skew; offset <= Skew; offset++ {
+		t := now.Add(time.Duration(offset*Period) * time.Second)
+		want, err := totp.GenerateCodeCustom(secret, t, validateOpts)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			return t.Unix() / Period, true
+		}
+	}
+	return 0, false
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewRecoveryCodes returns RecoveryCodeCount random single-use codes in the
+// form xxxxx-xxxxx.
+func NewRecoveryCodes() ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, errors.Wrap(err, "generating recovery code")
+		}
+		code := strings.ToLower(recoveryEncoding.EncodeToString(raw))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode puts a recovery code as typed by the user into the
+// form it was issued in.
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.Join(strings.Fields(code), ""))
+	code = strings.ReplaceAll(code, "-", "")
+	if len(code) != 10 {
+		return code
+	}
+	return code[:5] + "-" + code[5:]
+}
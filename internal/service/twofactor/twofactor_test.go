@@ -0,0 +1,71 @@
+package twofactor
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewKey(t *testing.T) {
+	key, err := NewKey("reader@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, Issuer, key.Issuer())
+	assert.Equal(t, "reader@example.com", key.AccountName())
+	assert.True(t, strings.HasPrefix(key.URL(), "otpauth://totp/"), key.URL())
+
+	parsed, err := otp.NewKeyFromURL(key.URL())
+	require.NoError(t, err)
+	assert.Equal(t, key.Secret(), parsed.Secret())
+
+	qr, err := QRCode(key)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(qr, "data:image/png;base64,"))
+}
+
+func TestValidate(t *testing.T) {
+	key, err := NewKey("reader@example.com")
+	require.NoError(t, err)
+	now := time.Date(2026, time.May, 1, 8, 0, 15, 0, time.UTC)
+
+	code, err := totp.GenerateCode(key.Secret(), now)
+	require.NoError(t, err)
+
+	step, ok := Validate(key.Secret(), code, now)
+	assert.True(t, ok)
+	assert.Equal(t, now.Unix()/Period, step)
+
+	step, ok = Validate(key.Secret(), " "+code+" ", now.Add(Period*time.Second))
+	assert.True(t, ok, "a code from the previous period is still accepted")
+	assert.Equal(t, now.Unix()/Period, step, "the step is the one the code was generated for")
+
+	_, ok = Validate(key.Secret(), code, now.Add(2*Period*time.Second))
+	assert.False(t, ok, "codes expire after the skew window")
+
+	_, ok = Validate(key.Secret(), "12345", now)
+	assert.False(t, ok)
+	_, ok = Validate(key.Secret(), "", now)
+	assert.False(t, ok)
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes()
+	require.NoError(t, err)
+	assert.Len(t, codes, RecoveryCodeCount)
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, code)
+		assert.False(t, seen[code], "codes are unique")
+		seen[code] = true
+		assert.Equal(t, code, NormalizeRecoveryCode(code))
+	}
+
+	assert.Equal(t, "abcde-fghij", NormalizeRecoveryCode(" ABCDE FGHIJ "))
+	assert.Equal(t, "abcde-fghij", NormalizeRecoveryCode("abcdefghij"))
+	assert.Equal(t, "abc", NormalizeRecoveryCode("ABC"))
+}
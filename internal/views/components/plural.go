@@ -0,0 +1,12 @@
+package components
+
+import "fmt"
+
+// Pluralize formats a count with its noun, adding an s unless n is 1:
+// "1 day", "3 days".
+func Pluralize(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
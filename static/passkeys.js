@@ -0,0 +1,173 @@
+// Passkey (WebAuthn) registration and sign-in. The server sends ceremony
+// options with binary fields base64url-encoded; these helpers convert them
+// for navigator.credentials and convert the browser's response back.
+
+function base64urlToBuffer(value) {
+  const padding = '='.repeat((4 - value.length % 4) % 4);
+  const base64 = (value + padding)
+    .replace(/\-/g, '+')
+    .replace(/_/g, '/');
+
+  const rawData = window.atob(base64);
+  const outputArray = new Uint8Array(rawData.length);
+
+  for (let i = 0; i < rawData.length; ++i) {
+    outputArray[i] = rawData.charCodeAt(i);
+  }
+  return outputArray.buffer;
+}
+
+function bufferToBase64url(buffer) {
+  const bytes = new Uint8Array(buffer);
+  let binary = '';
+  for (let i = 0; i < bytes.length; ++i) {
+    binary += String.fromCharCode(bytes[i]);
+  }
+  return window.btoa(binary)
+    .replace(/\+/g, '-')
+    .replace(/\//g, '_')
+    .replace(/=+$/, '');
+}
+
+function passkeyCsrfToken() {
+  const match = document.cookie.match(/(?:^|; )_csrf=([^;]*)/);
+  return match ? decodeURIComponent(match[1]) : '';
+}
+
+async function postPasskeyJSON(url, body) {
+  const response = await fetch(url, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      'X-CSRF-Token': passkeyCsrfToken(),
+    },
+    body: body === undefined ? undefined : JSON.stringify(body)
+  });
+
+  const data = await response.json().catch(() => ({}));
+  if (!response.ok) {
+    throw new Error(data.error || 'Something went wrong, please try again');
+  }
+  return data;
+}
+
+function passkeyCreationOptions(options) {
+  const publicKey = options.publicKey;
+  return {
+    ...publicKey,
+    challenge: base64urlToBuffer(publicKey.challenge),
+    user: {
+      ...publicKey.user,
+      id: base64urlToBuffer(publicKey.user.id)
+    },
+    excludeCredentials: (publicKey.excludeCredentials || []).map(credential => ({
+      ...credential,
+      id: base64urlToBuffer(credential.id)
+    }))
+  };
+}
+
+function passkeyRequestOptions(options) {
+  const publicKey = options.publicKey;
+  return {
+    ...publicKey,
+    challenge: base64urlToBuffer(publicKey.challenge),
+    allowCredentials: (publicKey.allowCredentials || []).map(credential => ({
+      ...credential,
+      id: base64urlToBuffer(credential.id)
+    }))
+  };
+}
+
+function passkeyCredentialJSON(credential) {
+  const response = credential.response;
+  const json = {
+    id: credential.id,
+    rawId: bufferToBase64url(credential.rawId),
+    type: credential.type,
+    clientExtensionResults: credential.getClientExtensionResults(),
+    response: {
+      clientDataJSON: bufferToBase64url(response.clientDataJSON)
+    }
+  };
+
+  if (response.attestationObject) {
+    json.response.attestationObject = bufferToBase64url(response.attestationObject);
+    json.response.transports = response.getTransports ? response.getTransports() : [];
+  } else {
+    json.response.authenticatorData = bufferToBase64url(response.authenticatorData);
+    json.response.signature = bufferToBase64url(response.signature);
+    if (response.userHandle) {
+      json.response.userHandle = bufferToBase64url(response.userHandle);
+    }
+  }
+  return json;
+}
+
+function showPasskeyError(error) {
+  console.error('Passkey error:', error);
+  // Cancelling the browser prompt is not worth an error message.
+  if (error.name === 'NotAllowedError' || error.name === 'AbortError') {
+    return;
+  }
+
+  const element = document.getElementById('passkey-error');
+  if (!element) {
+    alert(error.message);
+    return;
+  }
+  element.textContent = error.message;
+  element.classList.remove('hidden');
+}
+
+function passkeysSupported() {
+  if (window.PublicKeyCredential) {
+    return true;
+  }
+  showPasskeyError(new Error('This browser does not support passkeys'));
+  return false;
+}
+
+async function passkeyAssertion(optionsURL, finishURL) {
+  if (!passkeysSupported()) {
+    return;
+  }
+  try {
+    const options = await postPasskeyJSON(optionsURL);
+    const credential = await navigator.credentials.get({
+      publicKey: passkeyRequestOptions(options)
+    });
+    const result = await postPasskeyJSON(finishURL, passkeyCredentialJSON(credential));
+    window.location.href = result.redirect;
+  } catch (error) {
+    showPasskeyError(error);
+  }
+}
+
+async function signInWithPasskey() {
+  await passkeyAssertion('/auth/passkey/options', '/auth/passkey');
+}
+
+async function verifyWithPasskey() {
+  await passkeyAssertion('/auth/two-factor/passkey/options', '/auth/two-factor/passkey');
+}
+
+async function registerPasskey() {
+  if (!passkeysSupported()) {
+    return;
+  }
+  try {
+    const name = document.getElementById('passkey-name')?.value || '';
+    const options = await postPasskeyJSON('/account/passkeys/options');
+    const credential = await navigator.credentials.create({
+      publicKey: passkeyCreationOptions(options)
+    });
+    const result = await postPasskeyJSON(
+      '/account/passkeys?name=' + encodeURIComponent(name),
+      passkeyCredentialJSON(credential)
+    );
+    window.location.href = result.redirect;
+  } catch (error) {
+    showPasskeyError(error);
+  }
+}